@@ -2,28 +2,60 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	cudosapp "github.com/CudoVentures/cudos-node/app"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/email"
 	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/grpc"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/handlers"
 	key "github.com/CudoVentures/cudos-ondemand-minting-service/internal/key"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/logger"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/marshal"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
 	relayminter "github.com/CudoVentures/cudos-ondemand-minting-service/internal/relay_minter"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/rpc"
 	state "github.com/CudoVentures/cudos-ondemand-minting-service/internal/state"
 	infraclient "github.com/CudoVentures/cudos-ondemand-minting-service/internal/tokenised_infra/client"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/tx"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 // The one and only entrypoint of the program.
+// Without a command the service is run, the rebuild command reconstructs the state and the ledger from the chain history
+// and the reprocess command runs payments through the pipeline again, the verify command checks the chain of the decision log, all exit once done.
 func main() {
-	runService(context.Background())
+	ctx := context.Background()
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case commandRebuild:
+			runRebuild(ctx, os.Args[2:])
+			return
+		case commandReprocess:
+			runReprocess(ctx, os.Args[2:])
+			return
+		case commandVerify:
+			runVerify(os.Args[2:])
+			return
+		}
+	}
+
+	runService(ctx)
 }
 
 // This function does initial params processing and stars the relayer thread at the end.
@@ -50,68 +82,393 @@ func runService(ctx context.Context) {
 		return
 	}
 
-	var rmLogger zerolog.Logger
-	if cfg.HasPrettyLogging() {
-		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
-		rmLogger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr})
-	} else {
-		rmLogger = zerolog.New(os.Stderr)
+	// A shadow instance is only compared with the live one through its decision log
+	if cfg.HasShadowMode() && !cfg.HasDecisionLog() {
+		log.Fatal().Msg("shadow mode needs a decision log, set DECISION_LOG_FILE")
+		return
 	}
 
+	rmLogger := newRelayerLogger(cfg)
+
 	log.Info().Msgf("starting on-demand-minting-service using config %s", cfg.String())
 
+	lock, err := state.LockFile(state.DefaultLockFilePath)
+	if err != nil {
+		log.Fatal().Msgf("locking state failed: %s", err)
+		return
+	}
+	defer lock.Unlock()
+
+	rm, err := newRelayer(cfg, rmLogger)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return
+	}
+
+	go rm.Start(ctx)
+
+	log.Info().Msg("registering http handlers")
+
+	r := mux.NewRouter()
+	r.HandleFunc("/health", handlers.GetHealth(rm)).Methods(http.MethodGet)
+	if cfg.HasQuotes() {
+		quoteAuthToken, err := newQuoteAuthToken(cfg)
+		if err != nil {
+			log.Error().Msg(err.Error())
+			return
+		}
+
+		quoteLimiter := handlers.NewQuoteLimiter(cfg.QuoteRateLimit, cfg.QuoteClientRateLimit, time.Minute)
+		r.HandleFunc("/api/v1/quote/{uid}/{recipient}", handlers.GetQuote(rm, quoteLimiter, quoteAuthToken)).Methods(http.MethodGet)
+	}
+
+	log.Info().Msg(fmt.Sprintf("listening on port %d", cfg.Port))
+	srv := &http.Server{
+		Handler:      r,
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  15 * time.Second,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Msgf("error while listening: %s", err)
+		}
+	}()
+	defer srv.Shutdown(context.Background())
+
+	<-ctx.Done()
+
+	log.Info().Msg("stopping on-demand-minting-service")
+}
+
+// Rebuilding the state and the ledger of the payments from the chain history and printing the report as JSON.
+// The blocks after the --from height up to the --to height are rebuilt, by default from the configured starting height up to the confirmed height.
+// The state is set to the resume height of the report only with --write-state, otherwise the report is just the plan.
+// The lock of the service is taken, so it fails while the service runs from the same working directory.
+func runRebuild(ctx context.Context, args []string) {
+	cfg, err := config.NewConfig(envPath)
+	if err != nil {
+		log.Fatal().Msgf("creating config failed: %s", err)
+		return
+	}
+
+	flags := flag.NewFlagSet(commandRebuild, flag.ExitOnError)
+	fromHeight := flags.Int64("from", cfg.StartingHeight, "height after which the payments are rebuilt")
+	toHeight := flags.Int64("to", 0, "height up to which the payments are rebuilt, the confirmed height if zero")
+	writeState := flags.Bool("write-state", false, "set the state to the resume height of the report")
+	flags.Parse(args)
+
+	lock, err := state.LockFile(state.DefaultLockFilePath)
+	if err != nil {
+		log.Fatal().Msgf("locking state failed: %s", err)
+		return
+	}
+	defer lock.Unlock()
+
+	rm, err := newRelayer(cfg, newRelayerLogger(cfg))
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+		return
+	}
+
+	report, err := rm.Rebuild(ctx, *fromHeight, *toHeight, *writeState)
+	if err != nil {
+		log.Fatal().Msgf("rebuilding failed: %s", err)
+		return
+	}
+
+	printReport(report)
+}
+
+// Reprocessing the payments of the incoming transaction given by --tx or the payments after the --from height up to the --to height
+// and printing the report as JSON. The block scanner needs the --height of the transaction. With --dry-run nothing is sent.
+// Unless it is a dry run the lock of the service is taken, so it fails while the service runs from the same working directory.
+// The report lists the transactions sent before a failure as well.
+func runReprocess(ctx context.Context, args []string) {
+	cfg, err := config.NewConfig(envPath)
+	if err != nil {
+		log.Fatal().Msgf("creating config failed: %s", err)
+		return
+	}
+
+	flags := flag.NewFlagSet(commandReprocess, flag.ExitOnError)
+	txHash := flags.String("tx", "", "hash of the incoming transaction to reprocess")
+	height := flags.Int64("height", 0, "height of the incoming transaction, required by the block scanner")
+	fromHeight := flags.Int64("from", 0, "height after which the payments are reprocessed")
+	toHeight := flags.Int64("to", 0, "height up to which the payments are reprocessed, the confirmed height if zero")
+	dryRun := flags.Bool("dry-run", false, "build the transactions without sending them")
+	flags.Parse(args)
+
+	if *txHash == "" && *fromHeight == 0 && *toHeight == 0 {
+		log.Fatal().Msg("either --tx or --from and --to are required")
+		return
+	}
+
+	// A dry run sends nothing, so it may run next to the service
+	if !*dryRun {
+		lock, err := state.LockFile(state.DefaultLockFilePath)
+		if err != nil {
+			log.Fatal().Msgf("locking state failed: %s", err)
+			return
+		}
+		defer lock.Unlock()
+	}
+
+	rm, err := newRelayer(cfg, newRelayerLogger(cfg))
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+		return
+	}
+
+	var report model.ReprocessReport
+	if *txHash != "" {
+		report, err = rm.ReprocessTx(ctx, *txHash, *height, *dryRun)
+	} else {
+		report, err = rm.ReprocessRange(ctx, *fromHeight, *toHeight, *dryRun)
+	}
+
+	printReport(report)
+	if err != nil {
+		log.Fatal().Msgf("reprocessing failed: %s", err)
+	}
+}
+
+// Verifying the hash chain of the decision log given by --file, by default the configured one, and printing the report as JSON.
+// With --head the log must contain the head hash of an earlier verification, so records removed from its end are detected.
+// It fails if the log has any problem.
+func runVerify(args []string) {
+	cfg, err := config.NewConfig(envPath)
+	if err != nil {
+		log.Fatal().Msgf("creating config failed: %s", err)
+		return
+	}
+
+	flags := flag.NewFlagSet(commandVerify, flag.ExitOnError)
+	filePath := flags.String("file", cfg.DecisionLogFile, "decision log to verify")
+	head := flags.String("head", "", "head hash of an earlier verification the log must contain")
+	flags.Parse(args)
+
+	if *filePath == "" {
+		log.Fatal().Msg("no decision log is configured, --file is required")
+		return
+	}
+
+	report, err := state.VerifyDecisionLog(*filePath, *head)
+	if err != nil {
+		log.Fatal().Msgf("verifying decision log failed: %s", err)
+		return
+	}
+
+	printReport(report)
+	if !report.Valid {
+		log.Fatal().Msgf("decision log (%s) has %d problems", report.File, len(report.Problems))
+	}
+}
+
+func printReport(report interface{}) {
+	output, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatal().Msgf("marshaling report failed: %s", err)
+		return
+	}
+
+	fmt.Println(string(output))
+}
+
+// Creating the logger of the relayer. With pretty logging the global logger prints to the console as well.
+func newRelayerLogger(cfg config.Config) zerolog.Logger {
+	if !cfg.HasPrettyLogging() {
+		return zerolog.New(os.Stderr)
+	}
+
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	return zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr})
+}
+
+// Creating the relayer with the given logger and its keys, AuraPool client and stores from the config.
+func newRelayer(cfg config.Config, rmLogger zerolog.Logger) (relayer, error) {
 	cudosapp.SetConfig()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
 
+	quoteKey, err := newQuoteKey(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quote key: %s", err)
+	}
+
+	quoteStore, err := state.NewFileQuoteStore(cfg.QuoteStoreFile, cfg.QuoteRetention)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load quotes: %s", err)
+	}
+
 	state := state.NewFileState()
 	state.CreateStateFileIfNotExists(cfg.StartingHeight)
 
-	infraClient := infraclient.NewTokenisedInfraClient(cfg.AuraPoolBackend, marshal.NewJsonMarshaler())
+	auraPoolPubKeys, err := key.Ed25519PubKeysFromBase64(cfg.AuraPoolPublicKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AuraPool public keys: %s", err)
+	}
+
+	if !cfg.HasSignedQuotes() {
+		log.Warn().Msg("no AuraPool public keys are configured, the quotes of the AuraPool are not verified")
+	}
+
+	infraClient := infraclient.NewTokenisedInfraClient(cfg.AuraPoolBackend, marshal.NewJsonMarshaler(), auraPoolPubKeys)
 
-	privKey, err := key.PrivKeyFromMnemonic(cfg.WalletMnemonic)
+	hdParams, err := key.WalletHDParams(cfg.WalletKeyConfig())
 	if err != nil {
-		log.Error().Msg("failed to create private key from wallet mnemonic")
-		return
+		return nil, fmt.Errorf("failed to create derivation parameters: %s", err)
 	}
 
-	rm := relayminter.NewRelayMinter(
+	walletKey, err := newWalletKey(cfg, hdParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet key: %s", err)
+	}
+
+	minterPrivKeys, err := minterPrivKeys(cfg, hdParams, sdk.AccAddress(walletKey.PubKey().Address()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create private keys of the minter wallets: %s", err)
+	}
+
+	retiredPrivKeys, err := key.SourcePrivKeys(cfg.WalletKeyConfig(), hdParams, cfg.RetiredWalletKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create private keys of the retired wallets: %s", err)
+	}
+
+	// Printing the addresses before anything is sent, so the operators can verify the intended wallets are loaded
+	log.Info().Msgf("loaded payment wallet %s", sdk.AccAddress(walletKey.PubKey().Address()).String())
+	for _, minterPrivKey := range minterPrivKeys {
+		log.Info().Msgf("loaded minter wallet %s", sdk.AccAddress(minterPrivKey.PubKey().Address()).String())
+	}
+	for _, retiredPrivKey := range retiredPrivKeys {
+		log.Info().Msgf("loaded retired wallet %s", sdk.AccAddress(retiredPrivKey.PubKey().Address()).String())
+	}
+
+	return relayminter.NewRelayMinter(
 		logger.NewLogger(rmLogger.With().Str("module", "relayer").Timestamp().Logger()),
 		&encodingConfig,
 		cfg,
 		state,
 		infraClient,
-		privKey,
+		walletKey,
+		minterPrivKeys,
+		retiredPrivKeys,
 		grpc.GRPCConnector{},
 		rpc.RPCConnector{},
 		tx.NewTxCoder(&encodingConfig),
 		email.NewSendgridEmailService(cfg),
-	)
+		quoteKey,
+		quoteStore,
+	), nil
+}
 
-	go rm.Start(ctx)
+// Creating the key the quotes are signed with. It is read from a file, so it is never passed through the environment.
+// Without a configured key file no quotes are issued and no key is returned.
+func newQuoteKey(cfg config.Config) (ed25519.PrivateKey, error) {
+	if !cfg.HasQuotes() {
+		return nil, nil
+	}
 
-	// log.Info().Msg("registering http handlers")
+	seed, err := key.ReadSecretFile(cfg.QuoteKeyFile)
+	if err != nil {
+		return nil, err
+	}
 
-	// r := mux.NewRouter()
-	// r.HandleFunc("/simulate/mint", handlers.GetMintTxFee(cfg, sdk.AccAddress(privKey.PubKey().Address()).String(), rm, rm))
+	return key.Ed25519PrivKeyFromBase64Seed(seed)
+}
 
-	// log.Info().Msg(fmt.Sprintf("listening on port %d", cfg.Port))
-	// srv := &http.Server{
-	// 	Handler:      r,
-	// 	Addr:         fmt.Sprintf(":%d", cfg.Port),
-	// 	WriteTimeout: 15 * time.Second,
-	// 	ReadTimeout:  15 * time.Second,
-	// }
+// Reading the token the quote endpoint requires, empty for a public endpoint.
+func newQuoteAuthToken(cfg config.Config) (string, error) {
+	if cfg.QuoteAuthTokenFile == "" {
+		return "", nil
+	}
 
-	// go func() {
-	// 	if err := srv.ListenAndServe(); err != nil {
-	// 		log.Fatal().Msgf("error while listening: %s", err)
-	// 	}
-	// }()
+	return key.ReadSecretFile(cfg.QuoteAuthTokenFile)
+}
 
-	<-ctx.Done()
+// Creating the key of the payment wallet.
+// With a configured signing daemon only the public key is known to the service and every transaction is signed remotely,
+// otherwise the private key is loaded from the configured key source.
+func newWalletKey(cfg config.Config, hdParams key.HDParams) (walletKey, error) {
+	if !cfg.HasRemoteSigner() {
+		return key.WalletPrivKey(cfg.WalletKeyConfig(), hdParams)
+	}
 
-	// srv.Shutdown(context.Background())
-	log.Info().Msg("stopping on-demand-minting-service")
+	pubKey, err := key.PubKeyFromBase64(cfg.SignerPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	authToken, err := key.ReadSecretFile(cfg.SignerAuthTokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newSignerClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.NewRemoteSigner(cfg.SignerURL, authToken, pubKey, client), nil
+}
+
+// Creating the HTTP client of the signing daemon. With a configured CA file only the certificates it holds are trusted,
+// e.g. the self-signed certificate of a daemon on the private network.
+func newSignerClient(cfg config.Config) (*http.Client, error) {
+	client := &http.Client{Timeout: signerTimeout}
+	if cfg.SignerCAFile == "" {
+		return client, nil
+	}
+
+	caPEM, err := os.ReadFile(cfg.SignerCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading signer CA file failed: %s", err)
+	}
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("signer CA file (%s) holds no certificate", cfg.SignerCAFile)
+	}
+
+	client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}}
+	return client, nil
+}
+
+// Loading the keys of the minter wallets. The sub-accounts are derived from the wallet mnemonic, so they require the mnemonic source,
+// the separate minter wallets are loaded from the key source of the payment wallet.
+func minterPrivKeys(cfg config.Config, hdParams key.HDParams, walletAddress sdk.AccAddress) ([]*secp256k1.PrivKey, error) {
+	if len(cfg.MinterAccountIndices) > 0 && cfg.WalletKeySource != config.KeySourceMnemonic {
+		return nil, fmt.Errorf("minter account indices are sub-accounts of the wallet mnemonic and require the %s key source", config.KeySourceMnemonic)
+	}
+
+	keys, err := key.SourcePrivKeys(cfg.WalletKeyConfig(), hdParams, cfg.MinterKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.MinterPrivKeys(cfg.WalletMnemonic, hdParams, cfg.MinterAccountIndices, keys, walletAddress)
+}
+
+type relayer interface {
+	Start(ctx context.Context)
+	Health() model.Health
+	IssueQuote(ctx context.Context, uid, recipient string) (model.Quote, error)
+	Rebuild(ctx context.Context, fromHeight, toHeight int64, writeState bool) (model.RebuildReport, error)
+	ReprocessTx(ctx context.Context, txHash string, height int64, dryRun bool) (model.ReprocessReport, error)
+	ReprocessRange(ctx context.Context, fromHeight, toHeight int64, dryRun bool) (model.ReprocessReport, error)
+}
+
+type walletKey interface {
+	PubKey() cryptotypes.PubKey
+	Sign(msg []byte) ([]byte, error)
 }
 
 var envPath = ".env"
+
+const (
+	commandRebuild   = "rebuild"
+	commandReprocess = "reprocess"
+	commandVerify    = "verify"
+)
+
+const signerTimeout = 15 * time.Second
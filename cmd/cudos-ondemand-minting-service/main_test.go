@@ -4,7 +4,16 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/key"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRun(t *testing.T) {
@@ -29,6 +38,52 @@ func TestShouldExitIfConfigContainsInvalidMnemonic(t *testing.T) {
 	main()
 }
 
+func TestShouldLoadMinterKeysFromWalletKeySource(t *testing.T) {
+	mnemonic := "rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu"
+	expectedPrivKey, err := key.PrivKeyFromMnemonic(mnemonic)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	kr, err := keyring.New(sdk.KeyringServiceName(), keyring.BackendTest, dir, nil)
+	require.NoError(t, err)
+	_, err = kr.NewAccount("minter", mnemonic, "", sdk.FullFundraiserPath, hd.Secp256k1)
+	require.NoError(t, err)
+
+	cfg := config.Config{WalletKeySource: config.KeySourceKeyring, KeyringBackend: keyring.BackendTest, KeyringDir: dir, MinterKeys: []string{"minter"}}
+	privKeys, err := minterPrivKeys(cfg, key.DefaultHDParams(), nil)
+	require.NoError(t, err)
+	require.Len(t, privKeys, 1)
+	require.True(t, expectedPrivKey.Equals(privKeys[0]))
+
+	mnemonicFile := filepath.Join(dir, "minter_mnemonic")
+	require.NoError(t, os.WriteFile(mnemonicFile, []byte(mnemonic+"\n"), 0600))
+	privKeys, err = minterPrivKeys(config.Config{WalletKeySource: config.KeySourceMnemonic, MinterKeys: []string{mnemonicFile}}, key.DefaultHDParams(), nil)
+	require.NoError(t, err)
+	require.True(t, expectedPrivKey.Equals(privKeys[0]))
+
+	// The sub-accounts can only be derived from a mnemonic
+	cfg.MinterAccountIndices = []uint32{1}
+	_, err = minterPrivKeys(cfg, key.DefaultHDParams(), nil)
+	require.Error(t, err)
+
+	_, err = minterPrivKeys(config.Config{WalletKeySource: config.KeySourceKeyring, KeyringBackend: keyring.BackendTest, KeyringDir: dir, MinterKeys: []string{"missing"}}, key.DefaultHDParams(), nil)
+	require.Error(t, err)
+}
+
+func TestShouldTrustOnlySignerCAFile(t *testing.T) {
+	client, err := newSignerClient(config.Config{})
+	require.NoError(t, err)
+	require.Nil(t, client.Transport)
+
+	_, err = newSignerClient(config.Config{SignerCAFile: "missing"})
+	require.Error(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "signer_ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("no certificate"), 0600))
+	_, err = newSignerClient(config.Config{SignerCAFile: caFile})
+	require.Error(t, err)
+}
+
 func TestPrettyLogging(t *testing.T) {
 	envPath = "./testdata/pretty_logging.env"
 	main()
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -18,6 +20,7 @@ func main() {
 	r.HandleFunc("/nft/minted/check-status", getNFTMintedHandler())
 	r.HandleFunc("/api/v1/nft/on-demand-minting-nft/{uid}/{recipient}/{amount}", getNFTHandler())
 
+	log.Info().Msg(fmt.Sprintf("Signing quotes with public key: %s", base64.StdEncoding.EncodeToString(quoteKey.Public().(ed25519.PublicKey))))
 	log.Info().Msg(fmt.Sprintf("Listening on port: %d", listeningPort))
 	srv := &http.Server{
 		Handler: r,
@@ -70,6 +73,14 @@ func getNFTHandler() func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
+		signBytes, err := nft.SignBytes(mux.Vars(r)["recipient"])
+		if err != nil {
+			log.Error().Err(fmt.Errorf("error while encoding sign bytes: %s", err)).Send()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		nft.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(quoteKey, signBytes))
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
@@ -82,6 +93,7 @@ func getNFTHandler() func(http.ResponseWriter, *http.Request) {
 
 var nfts = map[string]model.NFTData{
 	"nftuid1": {
+		Id:              "nftuid1",
 		Price:           sdk.NewIntFromUint64(8000000000000000000),
 		Name:            "test nft name",
 		Uri:             "test nft uri",
@@ -91,6 +103,7 @@ var nfts = map[string]model.NFTData{
 		PriceValidUntil: tomorrow,
 	},
 	"nftuid2": {
+		Id:              "nftuid2",
 		Price:           sdk.NewIntFromUint64(8000000000000000000),
 		Name:            "test nft name",
 		Uri:             "test nft uri",
@@ -103,6 +116,9 @@ var nfts = map[string]model.NFTData{
 
 const listeningPort = 8080
 
+// Fixed key, so the minting service can be configured with its public key
+var quoteKey = ed25519.NewKeyFromSeed([]byte("mock-aura-pool-quote-signing-key"))
+
 var tomorrow = time.Now().Add(time.Hour * 24).UnixMilli()
 
 type mintTx struct {
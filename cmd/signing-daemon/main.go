@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	cudosapp "github.com/CudoVentures/cudos-node/app"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	key "github.com/CudoVentures/cudos-ondemand-minting-service/internal/key"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/signing"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/tx"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// The reference signing daemon. It holds the key of the payment wallet so the minting host never does,
+// and signs only the transactions of the relayer that pass its policy.
+func main() {
+	cfg, err := config.NewSigningDaemonConfig(envPath)
+	if err != nil {
+		log.Fatal().Msgf("creating config failed: %s", err)
+		return
+	}
+
+	if cfg.HasPrettyLogging() {
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	}
+
+	log.Info().Msgf("starting signing daemon using config %s", cfg.String())
+
+	cudosapp.SetConfig()
+
+	if !cfg.HasTLS() && (cfg.TLSCertFile != "" || cfg.TLSKeyFile != "") {
+		log.Fatal().Msg("TLS needs both SIGNER_TLS_CERT_FILE and SIGNER_TLS_KEY_FILE")
+		return
+	}
+
+	// The sign requests carry the auth token, so they must not travel over the network in plain HTTP
+	if !cfg.HasTLS() && !cfg.HasLoopbackHost() {
+		log.Fatal().Msgf("refusing to listen on %s without TLS, set SIGNER_TLS_CERT_FILE and SIGNER_TLS_KEY_FILE or a loopback SIGNER_HOST", cfg.ListenAddress())
+		return
+	}
+
+	hdParams, err := key.WalletHDParams(cfg.WalletKeyConfig())
+	if err != nil {
+		log.Fatal().Msgf("failed to create derivation parameters: %s", err)
+		return
+	}
+
+	privKey, err := key.WalletPrivKey(cfg.WalletKeyConfig(), hdParams)
+	if err != nil {
+		log.Fatal().Msgf("failed to create wallet key: %s", err)
+		return
+	}
+
+	authToken, err := key.ReadSecretFile(cfg.AuthTokenFile)
+	if err != nil || authToken == "" {
+		log.Fatal().Msgf("reading auth token file failed: %v", err)
+		return
+	}
+
+	maxRefundAmount, ok := sdk.NewIntFromString(cfg.MaxRefundAmount)
+	if !ok {
+		log.Fatal().Msgf("invalid max refund amount (%s)", cfg.MaxRefundAmount)
+		return
+	}
+
+	maxFeeAmount, ok := sdk.NewIntFromString(cfg.MaxFeeAmount)
+	if !ok {
+		log.Fatal().Msgf("invalid max fee amount (%s)", cfg.MaxFeeAmount)
+		return
+	}
+
+	if cfg.MaxGas <= 0 {
+		log.Fatal().Msgf("invalid max gas (%d)", cfg.MaxGas)
+		return
+	}
+
+	walletAddress := sdk.AccAddress(privKey.PubKey().Address()).String()
+	policy := signing.NewSigningPolicy(cfg.ChainID, cfg.AllowedMsgTypes, maxRefundAmount, cfg.PaymentDenom, maxFeeAmount, uint64(cfg.MaxGas),
+		walletAddress, cfg.FeeGranter, cfg.TreasuryAddress)
+
+	log.Info().Msgf("signing for wallet %s", walletAddress)
+
+	r := mux.NewRouter()
+	r.HandleFunc(tx.SignUri, signing.NewSignHandler(privKey, policy, authToken)).Methods(http.MethodPost)
+
+	log.Info().Msg(fmt.Sprintf("listening on %s", cfg.ListenAddress()))
+	srv := &http.Server{
+		Handler:      r,
+		Addr:         cfg.ListenAddress(),
+		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  15 * time.Second,
+		TLSConfig:    &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+
+	if cfg.HasTLS() {
+		err = srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+
+	if err != nil {
+		log.Fatal().Err(fmt.Errorf("error while listening: %s", err)).Send()
+	}
+}
+
+var envPath = ".env.signing-daemon"
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -14,24 +15,80 @@ func NewConfig(envPath string) (Config, error) {
 		return Config{}, err
 	}
 
+	minterAccountIndices, err := getEnvAsUint32List("MINTER_ACCOUNT_INDICES")
+	if err != nil {
+		return Config{}, err
+	}
+
 	return Config{
-		WalletMnemonic:    getEnv("WALLET_MNEMONIC", ""),
-		ChainID:           getEnv("CHAIN_ID", ""),
-		ChainRPC:          getEnv("CHAIN_RPC", ""),
-		ChainGRPC:         getEnv("CHAIN_GRPC", ""),
-		AuraPoolBackend:   getEnv("AURA_POOL_BACKEND", ""),
-		StartingHeight:    getEnvAsInt64("STARTING_HEIGHT", 1),
-		MaxRetries:        getEnvAsInt("MAX_RETRIES", 10),
-		RetryInterval:     getEnvAsDuration("RETRY_INTERVAL", time.Second*30),
-		RelayInterval:     getEnvAsDuration("RELAY_INTERVAL", time.Second*5),
-		PaymentDenom:      getEnv("PAYMENT_DENOM", "acudos"),
-		Port:              getEnvAsInt("PORT", 3000),
-		PrettyLogging:     getEnvAsInt("PRETTY_LOGGING", 0),
-		EmailFrom:         getEnv("EMAIL_FROM", ""),
-		ServiceEmail:      getEnv("SERVICE_EMAIL", ""),
-		SendgridApiKey:    getEnv("SENDGRID_API_KEY", ""),
-		AuraPoolApiKey:    getEnv("AURA_POOL_API_KEY", ""),
-		EmailSendInterval: getEnvAsDuration("EMAIL_SEND_INTERVAL", time.Minute*30),
+		WalletMnemonic:           getEnv("WALLET_MNEMONIC", ""),
+		WalletKeySource:          getEnv("WALLET_KEY_SOURCE", KeySourceMnemonic),
+		KeyringBackend:           getEnv("KEYRING_BACKEND", "file"),
+		KeyringDir:               getEnv("KEYRING_DIR", ""),
+		WalletKeyName:            getEnv("WALLET_KEY_NAME", ""),
+		WalletKeyFile:            getEnv("WALLET_KEY_FILE", ""),
+		KeyPassphraseFile:        getEnv("KEY_PASSPHRASE_FILE", ""),
+		HDCoinType:               getEnvAsUint32("HD_COIN_TYPE", 118),
+		HDAccount:                getEnvAsUint32("HD_ACCOUNT", 0),
+		HDAddressIndex:           getEnvAsUint32("HD_ADDRESS_INDEX", 0),
+		Bip39PassphraseFile:      getEnv("BIP39_PASSPHRASE_FILE", ""),
+		ChainID:                  getEnv("CHAIN_ID", ""),
+		ChainRPC:                 getEnv("CHAIN_RPC", ""),
+		ChainGRPC:                getEnv("CHAIN_GRPC", ""),
+		PaymentSource:            getEnv("PAYMENT_SOURCE", PaymentSourceTxSearch),
+		QueryWindow:              getEnvAsInt64("QUERY_WINDOW", 10000),
+		QueryPageRetries:         getEnvAsInt("QUERY_PAGE_RETRIES", 3),
+		TxIndexFile:              getEnv("TX_INDEX_FILE", ""),
+		LedgerFile:               getEnv("LEDGER_FILE", "ledger.jsonl"),
+		DecisionLogFile:          getEnv("DECISION_LOG_FILE", ""),
+		ShadowMode:               getEnvAsInt("SHADOW_MODE", 0),
+		AuraPoolBackend:          getEnv("AURA_POOL_BACKEND", ""),
+		StartingHeight:           getEnvAsInt64("STARTING_HEIGHT", 1),
+		MaxRetries:               getEnvAsInt("MAX_RETRIES", 10),
+		RetryInterval:            getEnvAsDuration("RETRY_INTERVAL", time.Second*30),
+		RelayInterval:            getEnvAsDuration("RELAY_INTERVAL", time.Second*5),
+		ConfirmationDepth:        getEnvAsInt64("CONFIRMATION_DEPTH", 0),
+		MaxBlockAge:              getEnvAsDuration("MAX_BLOCK_AGE", time.Minute),
+		PaymentDenom:             getEnv("PAYMENT_DENOM", "acudos"),
+		Port:                     getEnvAsInt("PORT", 3000),
+		PrettyLogging:            getEnvAsInt("PRETTY_LOGGING", 0),
+		EmailFrom:                getEnv("EMAIL_FROM", ""),
+		ServiceEmail:             getEnv("SERVICE_EMAIL", ""),
+		SendgridApiKey:           getEnv("SENDGRID_API_KEY", ""),
+		AuraPoolApiKey:           getEnv("AURA_POOL_API_KEY", ""),
+		AuraPoolPublicKeys:       getEnvAsList("AURA_POOL_PUBLIC_KEYS"),
+		PriceValidityGrace:       getEnvAsDuration("PRICE_VALIDITY_GRACE", time.Minute),
+		EmailSendInterval:        getEnvAsDuration("EMAIL_SEND_INTERVAL", time.Minute*30),
+		CartFailurePolicy:        getEnv("CART_FAILURE_POLICY", CartPolicyRefundUnavailable),
+		MaxCartItems:             getEnvAsInt("MAX_CART_ITEMS", 10),
+		BatchTxs:                 getEnvAsInt("BATCH_TXS", 0),
+		BatchGasLimit:            getEnvAsInt64("BATCH_GAS_LIMIT", 2000000),
+		Workers:                  getEnvAsInt("WORKERS", 1),
+		MinterAccountIndices:     minterAccountIndices,
+		MinterKeys:               getEnvAsList("MINTER_KEYS"),
+		RetiredWalletKeys:        getEnvAsList("RETIRED_WALLET_KEYS"),
+		RetiredWalletAddresses:   getEnvAsList("RETIRED_WALLET_ADDRESSES"),
+		RetiredPaymentPolicy:     getEnv("RETIRED_PAYMENT_POLICY", RetiredPaymentPolicyRefund),
+		BalanceWarningThreshold:  getEnv("BALANCE_WARNING_THRESHOLD", ""),
+		BalanceCriticalThreshold: getEnv("BALANCE_CRITICAL_THRESHOLD", ""),
+		TreasuryAddress:          getEnv("TREASURY_ADDRESS", ""),
+		SweepFloat:               getEnv("SWEEP_FLOAT", "0"),
+		SweepInterval:            getEnvAsDuration("SWEEP_INTERVAL", time.Hour*24),
+		SweepLogFile:             getEnv("SWEEP_LOG_FILE", "sweeps.jsonl"),
+		FeeGranter:               getEnv("FEE_GRANTER", ""),
+		AuthzMinter:              getEnv("AUTHZ_MINTER", ""),
+		FeeGrantMinAllowance:     getEnv("FEE_GRANT_MIN_ALLOWANCE", ""),
+		QuoteKeyFile:             getEnv("QUOTE_KEY_FILE", ""),
+		QuoteStoreFile:           getEnv("QUOTE_STORE_FILE", "quotes.jsonl"),
+		QuoteValidity:            getEnvAsDuration("QUOTE_VALIDITY", time.Minute*15),
+		QuoteRetention:           getEnvAsDuration("QUOTE_RETENTION", time.Hour*24),
+		QuoteRateLimit:           getEnvAsInt("QUOTE_RATE_LIMIT", 60),
+		QuoteClientRateLimit:     getEnvAsInt("QUOTE_CLIENT_RATE_LIMIT", 10),
+		QuoteAuthTokenFile:       getEnv("QUOTE_AUTH_TOKEN_FILE", ""),
+		SignerURL:                getEnv("SIGNER_URL", ""),
+		SignerPubKey:             getEnv("SIGNER_PUBKEY", ""),
+		SignerAuthTokenFile:      getEnv("SIGNER_AUTH_TOKEN_FILE", ""),
+		SignerCAFile:             getEnv("SIGNER_CA_FILE", ""),
 	}, nil
 }
 
@@ -61,6 +118,41 @@ func getEnvAsInt64(name string, defaultVal int64) int64 {
 	return defaultVal
 }
 
+func getEnvAsUint32(name string, defaultVal uint32) uint32 {
+	valueStr := getEnv(name, "")
+	if value, err := strconv.ParseUint(valueStr, 10, 32); err == nil {
+		return uint32(value)
+	}
+
+	return defaultVal
+}
+
+// Getting a comma separated list. Empty entries are skipped.
+func getEnvAsList(name string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(name, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// Getting a comma separated list of numbers. An invalid entry is an error, so no configured wallet is silently left out.
+func getEnvAsUint32List(name string) ([]uint32, error) {
+	var values []uint32
+	for _, valueStr := range getEnvAsList(name) {
+		value, err := strconv.ParseUint(valueStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry (%s) of %s: %s", valueStr, name, err)
+		}
+		values = append(values, uint32(value))
+	}
+
+	return values, nil
+}
+
 func getEnvAsDuration(name string, defaultVal time.Duration) time.Duration {
 	valStr := getEnv(name, "")
 	if valStr == "" {
@@ -73,25 +165,153 @@ func getEnvAsDuration(name string, defaultVal time.Duration) time.Duration {
 }
 
 type Config struct {
-	WalletMnemonic    string
-	ChainID           string
-	ChainRPC          string
-	ChainGRPC         string
-	AuraPoolBackend   string
-	StartingHeight    int64
-	MaxRetries        int
-	RetryInterval     time.Duration
-	RelayInterval     time.Duration
-	PaymentDenom      string
-	Port              int
-	PrettyLogging     int
-	EmailFrom         string
-	ServiceEmail      string
-	SendgridApiKey    string
-	AuraPoolApiKey    string
-	EmailSendInterval time.Duration
+	WalletMnemonic string
+	// Where the key of the payment wallet is loaded from, see the key sources
+	WalletKeySource string
+	// Backend of the keyring, either file or test
+	KeyringBackend string
+	// Home directory of the keyring
+	KeyringDir string
+	// Name of the wallet key in the keyring
+	WalletKeyName string
+	// Armored encrypted private key of the wallet
+	WalletKeyFile string
+	// File with the passphrase of the keyring or the key file
+	KeyPassphraseFile string
+	// Derivation path m/44'/<coin type>'/<account>'/0/<address index> of the wallet mnemonic
+	HDCoinType     uint32
+	HDAccount      uint32
+	HDAddressIndex uint32
+	// File with the BIP39 passphrase of the wallet mnemonic
+	Bip39PassphraseFile string
+	ChainID             string
+	ChainRPC            string
+	ChainGRPC           string
+	// Where the payments and the transactions of the idempotency checks are found, see the payment sources
+	PaymentSource string
+	// Number of blocks the payments are queried and processed in at once, 0 to query all blocks at once
+	QueryWindow int64
+	// Number of retries of a failed page of a query
+	QueryPageRetries int
+	// File the outgoing transactions of the service are indexed in, empty to search the chain in every idempotency check
+	TxIndexFile string
+	// File the ledger of the payments is written to by the rebuild command
+	LedgerFile string
+	// File the decisions of the relayer are logged to, empty to disable
+	DecisionLogFile string
+	// With 1 nothing is broadcasted, the decisions are logged only
+	ShadowMode      int
+	AuraPoolBackend string
+	StartingHeight  int64
+	MaxRetries      int
+	RetryInterval   time.Duration
+	RelayInterval   time.Duration
+	// Number of blocks a payment must be behind the latest block before it is processed
+	ConfirmationDepth int64
+	// Age of the latest block of the node above which the chain is considered halted, 0 to disable
+	MaxBlockAge    time.Duration
+	PaymentDenom   string
+	Port           int
+	PrettyLogging  int
+	EmailFrom      string
+	ServiceEmail   string
+	SendgridApiKey string
+	AuraPoolApiKey string
+	// Base64 encoded ed25519 public keys the AuraPool signs its quotes with, empty to accept unsigned quotes
+	AuraPoolPublicKeys []string
+	// Time a price stays valid after its expiry, so a clock skew between the chain and the AuraPool does not refund a payment
+	PriceValidityGrace time.Duration
+	EmailSendInterval  time.Duration
+	CartFailurePolicy  string
+	MaxCartItems       int
+	BatchTxs           int
+	BatchGasLimit      int64
+	Workers            int
+	// Indices of the sub-accounts of the wallet mnemonic used as minter wallets
+	MinterAccountIndices []uint32
+	// Keys of separate minter wallets in the wallet key source: names of keyring keys, key files or files with a mnemonic
+	MinterKeys []string
+	// Keys of former payment wallets that are still held, in the wallet key source like the minter keys
+	RetiredWalletKeys []string
+	// Addresses of former payment wallets whose keys are no longer held
+	RetiredWalletAddresses []string
+	// What to do with payments received by a retired wallet
+	RetiredPaymentPolicy string
+	// Balance in the payment denom below which an alert is sent, empty to disable
+	BalanceWarningThreshold string
+	// Balance in the payment denom below which minting and refunding are paused, empty to disable
+	BalanceCriticalThreshold string
+	// Address the balance of the payment wallet above the float is swept to, empty to disable
+	TreasuryAddress string
+	// Balance in the payment denom kept in the payment wallet by the sweep
+	SweepFloat    string
+	SweepInterval time.Duration
+	// File every sweep is recorded in
+	SweepLogFile string
+	// Address paying the fees of all transactions through fee grants, empty to pay them by the signing wallets
+	FeeGranter string
+	// Spend limit in the payment denom the fee allowance of every wallet must have at startup, empty to accept any
+	FeeGrantMinAllowance string
+	// Cold minter account that granted MsgMintNft to the wallets, empty if the wallets are the minters themselves
+	AuthzMinter string
+	// File with the base64 encoded ed25519 seed the quotes are signed with, empty to disable the quote endpoint
+	QuoteKeyFile string
+	// File every issued quote is stored in
+	QuoteStoreFile string
+	QuoteValidity  time.Duration
+	// How long a quote is kept after it expires, a payment processed after its quote is dropped is refunded
+	QuoteRetention time.Duration
+	// Quotes issued per minute in total and per client address, 0 for no limit
+	QuoteRateLimit       int
+	QuoteClientRateLimit int
+	// File with the bearer token the quote endpoint requires, empty for a public endpoint
+	QuoteAuthTokenFile string
+	// Url of the signing daemon holding the key of the payment wallet
+	SignerURL string
+	// Base64 encoded compressed secp256k1 public key of the payment wallet
+	SignerPubKey string
+	// File with the auth token of the signing daemon
+	SignerAuthTokenFile string
+	// PEM file with the certificates the TLS certificate of the signing daemon is verified against, the system roots if empty
+	SignerCAFile string
 }
 
+// Sources of the key of the payment wallet.
+const (
+	// Deriving the key from the wallet mnemonic.
+	KeySourceMnemonic = "mnemonic"
+	// Loading the key by its name from a keyring.
+	KeySourceKeyring = "keyring"
+	// Decrypting an armored private key file.
+	KeySourceKeyFile = "keyfile"
+)
+
+// Sources of the payments and of the transactions of the idempotency checks.
+const (
+	// Querying the tx indexer of the node by tx_search.
+	PaymentSourceTxSearch = "tx_search"
+	// Scanning the blocks and their results, for nodes without a tx indexer.
+	PaymentSourceBlockScan = "block_scan"
+	// Querying the tx service of the Cosmos SDK by GetTxsEvent, the RPC of the node is not used.
+	PaymentSourceGRPC = "grpc"
+)
+
+// Policies for cart payments with some of the items not being available for minting.
+const (
+	// Minting the available items and refunding only the unavailable ones.
+	CartPolicyRefundUnavailable = "refund_unavailable"
+	// Refunding the whole cart if any of its items is not available.
+	CartPolicyAllOrNothing = "all_or_nothing"
+)
+
+// Policies for payments received by a retired wallet.
+const (
+	// Refunding the payments from the retired wallet.
+	RetiredPaymentPolicyRefund = "refund"
+	// Processing the payments as the ones of the payment wallet, the NFTs are minted by the wallet pool.
+	RetiredPaymentPolicyMint = "mint"
+)
+
 func (cfg *Config) HasPrettyLogging() bool {
 	return cfg.PrettyLogging == 1
 }
@@ -100,6 +320,62 @@ func (cfg *Config) HasValidEmailConfig() bool {
 	return cfg.SendgridApiKey != "" && cfg.EmailFrom != "" && cfg.ServiceEmail != ""
 }
 
+func (cfg *Config) IsAllOrNothingCart() bool {
+	return cfg.CartFailurePolicy == CartPolicyAllOrNothing
+}
+
+func (cfg *Config) ScansBlocks() bool {
+	return cfg.PaymentSource == PaymentSourceBlockScan
+}
+
+func (cfg *Config) QueriesGRPC() bool {
+	return cfg.PaymentSource == PaymentSourceGRPC
+}
+
+func (cfg *Config) HasTxIndex() bool {
+	return cfg.TxIndexFile != ""
+}
+
+func (cfg *Config) HasDecisionLog() bool {
+	return cfg.DecisionLogFile != ""
+}
+
+func (cfg *Config) HasShadowMode() bool {
+	return cfg.ShadowMode == 1
+}
+
+func (cfg *Config) HasBatchTxs() bool {
+	return cfg.BatchTxs == 1
+}
+
+func (cfg *Config) MintsRetiredPayments() bool {
+	return cfg.RetiredPaymentPolicy == RetiredPaymentPolicyMint
+}
+
+func (cfg *Config) HasTreasurySweep() bool {
+	return cfg.TreasuryAddress != ""
+}
+
+func (cfg *Config) HasFeeGranter() bool {
+	return cfg.FeeGranter != ""
+}
+
+func (cfg *Config) HasAuthzMinter() bool {
+	return cfg.AuthzMinter != ""
+}
+
+func (cfg *Config) HasSignedQuotes() bool {
+	return len(cfg.AuraPoolPublicKeys) > 0
+}
+
+func (cfg *Config) HasQuotes() bool {
+	return cfg.QuoteKeyFile != ""
+}
+
+func (cfg *Config) HasRemoteSigner() bool {
+	return cfg.SignerURL != ""
+}
+
 func (cfg *Config) String() string {
-	return fmt.Sprintf("Config { WalletMnemonic(Hidden for security), ChainID(%s), ChainRPC(%s), ChainGRPC(%s), AuraPoolBackend(%s), StartingHeight(%d), MaxRetries(%d), RetryInterval(%d), RelayInterval(%d), PaymentDenom(%s), Port(%d) PrettyLogging(%d) SendgridApiKey(%s) EmailFrom(%s) ServiceEmail(%s) EmailSendInterval(%d)}", cfg.ChainID, cfg.ChainRPC, cfg.ChainGRPC, cfg.AuraPoolBackend, cfg.StartingHeight, cfg.MaxRetries, cfg.RetryInterval, cfg.RelayInterval, cfg.PaymentDenom, cfg.Port, cfg.PrettyLogging, "Hidden for security", cfg.EmailFrom, cfg.ServiceEmail, cfg.EmailSendInterval)
+	return fmt.Sprintf("Config { WalletMnemonic(Hidden for security), WalletKeySource(%s), KeyringBackend(%s), KeyringDir(%s), WalletKeyName(%s), WalletKeyFile(%s), KeyPassphraseFile(%s), HDCoinType(%d), HDAccount(%d), HDAddressIndex(%d), Bip39PassphraseFile(%s), ChainID(%s), ChainRPC(%s), ChainGRPC(%s), PaymentSource(%s), QueryWindow(%d), QueryPageRetries(%d), TxIndexFile(%s), LedgerFile(%s), DecisionLogFile(%s), ShadowMode(%d), AuraPoolBackend(%s), AuraPoolPublicKeys(%v), PriceValidityGrace(%d), StartingHeight(%d), MaxRetries(%d), RetryInterval(%d), RelayInterval(%d), ConfirmationDepth(%d), MaxBlockAge(%d), PaymentDenom(%s), Port(%d) PrettyLogging(%d) SendgridApiKey(%s) EmailFrom(%s) ServiceEmail(%s) EmailSendInterval(%d) CartFailurePolicy(%s) MaxCartItems(%d) BatchTxs(%d) BatchGasLimit(%d) Workers(%d) MinterAccountIndices(%v) MinterKeys(%v) RetiredWalletKeys(%v) RetiredWalletAddresses(%v) RetiredPaymentPolicy(%s) BalanceWarningThreshold(%s) BalanceCriticalThreshold(%s) TreasuryAddress(%s) SweepFloat(%s) SweepInterval(%d) SweepLogFile(%s) FeeGranter(%s) FeeGrantMinAllowance(%s) AuthzMinter(%s) QuoteKeyFile(%s) QuoteStoreFile(%s) QuoteValidity(%d) QuoteRetention(%d) QuoteRateLimit(%d) QuoteClientRateLimit(%d) QuoteAuthTokenFile(%s) SignerURL(%s) SignerPubKey(%s) SignerAuthTokenFile(%s) SignerCAFile(%s)}", cfg.WalletKeySource, cfg.KeyringBackend, cfg.KeyringDir, cfg.WalletKeyName, cfg.WalletKeyFile, cfg.KeyPassphraseFile, cfg.HDCoinType, cfg.HDAccount, cfg.HDAddressIndex, cfg.Bip39PassphraseFile, cfg.ChainID, cfg.ChainRPC, cfg.ChainGRPC, cfg.PaymentSource, cfg.QueryWindow, cfg.QueryPageRetries, cfg.TxIndexFile, cfg.LedgerFile, cfg.DecisionLogFile, cfg.ShadowMode, cfg.AuraPoolBackend, cfg.AuraPoolPublicKeys, cfg.PriceValidityGrace, cfg.StartingHeight, cfg.MaxRetries, cfg.RetryInterval, cfg.RelayInterval, cfg.ConfirmationDepth, cfg.MaxBlockAge, cfg.PaymentDenom, cfg.Port, cfg.PrettyLogging, "Hidden for security", cfg.EmailFrom, cfg.ServiceEmail, cfg.EmailSendInterval, cfg.CartFailurePolicy, cfg.MaxCartItems, cfg.BatchTxs, cfg.BatchGasLimit, cfg.Workers, cfg.MinterAccountIndices, cfg.MinterKeys, cfg.RetiredWalletKeys, cfg.RetiredWalletAddresses, cfg.RetiredPaymentPolicy, cfg.BalanceWarningThreshold, cfg.BalanceCriticalThreshold, cfg.TreasuryAddress, cfg.SweepFloat, cfg.SweepInterval, cfg.SweepLogFile, cfg.FeeGranter, cfg.FeeGrantMinAllowance, cfg.AuthzMinter, cfg.QuoteKeyFile, cfg.QuoteStoreFile, cfg.QuoteValidity, cfg.QuoteRetention, cfg.QuoteRateLimit, cfg.QuoteClientRateLimit, cfg.QuoteAuthTokenFile, cfg.SignerURL, cfg.SignerPubKey, cfg.SignerAuthTokenFile, cfg.SignerCAFile)
 }
@@ -10,18 +10,40 @@ import (
 
 func TestShouldPass(t *testing.T) {
 	expectedCfg := Config{
-		WalletMnemonic:    "rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu",
-		ChainID:           "cudos-local-network",
-		ChainRPC:          "http://127.0.0.1:26657",
-		ChainGRPC:         "127.0.0.1:9090",
-		AuraPoolBackend:   "http://127.0.0.1:8080",
-		StartingHeight:    2,
-		MaxRetries:        10,
-		RetryInterval:     30 * time.Second,
-		RelayInterval:     5 * time.Second,
-		PaymentDenom:      "acudos",
-		Port:              3000,
-		EmailSendInterval: 30 * time.Minute,
+		WalletMnemonic:       "rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu",
+		WalletKeySource:      KeySourceMnemonic,
+		KeyringBackend:       "file",
+		HDCoinType:           118,
+		ChainID:              "cudos-local-network",
+		ChainRPC:             "http://127.0.0.1:26657",
+		ChainGRPC:            "127.0.0.1:9090",
+		PaymentSource:        PaymentSourceTxSearch,
+		QueryWindow:          10000,
+		QueryPageRetries:     3,
+		LedgerFile:           "ledger.jsonl",
+		AuraPoolBackend:      "http://127.0.0.1:8080",
+		PriceValidityGrace:   time.Minute,
+		StartingHeight:       2,
+		MaxRetries:           10,
+		RetryInterval:        30 * time.Second,
+		RelayInterval:        5 * time.Second,
+		MaxBlockAge:          time.Minute,
+		PaymentDenom:         "acudos",
+		Port:                 3000,
+		EmailSendInterval:    30 * time.Minute,
+		CartFailurePolicy:    CartPolicyRefundUnavailable,
+		MaxCartItems:         10,
+		BatchGasLimit:        2000000,
+		Workers:              1,
+		RetiredPaymentPolicy: RetiredPaymentPolicyRefund,
+		SweepFloat:           "0",
+		SweepInterval:        24 * time.Hour,
+		SweepLogFile:         "sweeps.jsonl",
+		QuoteStoreFile:       "quotes.jsonl",
+		QuoteValidity:        15 * time.Minute,
+		QuoteRetention:       24 * time.Hour,
+		QuoteRateLimit:       60,
+		QuoteClientRateLimit: 10,
 	}
 
 	haveCfg, err := NewConfig("../../.env.example")
@@ -39,6 +61,14 @@ func TestShouldFailIfConfigIsInvalidEnv(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestShouldFailIfMinterAccountIndexIsInvalid(t *testing.T) {
+	require.NoError(t, os.Setenv("MINTER_ACCOUNT_INDICES", "1,x"))
+	defer os.Unsetenv("MINTER_ACCOUNT_INDICES")
+
+	_, err := NewConfig("../../.env.example")
+	require.Error(t, err)
+}
+
 func TestGetEnvShouldReturnDefaultIfKeyNotFound(t *testing.T) {
 	require.Equal(t, "def", getEnv(str, "def"))
 }
@@ -51,6 +81,34 @@ func TestGetEnvAsInt(t *testing.T) {
 
 }
 
+func TestGetEnvAsUint32(t *testing.T) {
+	require.Equal(t, uint32(118), getEnvAsUint32(uintKey, 118))
+
+	require.NoError(t, os.Setenv(uintKey, "60"))
+	require.Equal(t, uint32(60), getEnvAsUint32(uintKey, 118))
+
+	require.NoError(t, os.Setenv(uintKey, "-1"))
+	require.Equal(t, uint32(118), getEnvAsUint32(uintKey, 118))
+	require.NoError(t, os.Unsetenv(uintKey))
+}
+
+func TestGetEnvAsList(t *testing.T) {
+	require.Nil(t, getEnvAsList(listKey))
+
+	require.NoError(t, os.Setenv(listKey, "a b, c ,,"))
+	require.Equal(t, []string{"a b", "c"}, getEnvAsList(listKey))
+
+	require.NoError(t, os.Setenv(listKey, "1, 3,"))
+	values, err := getEnvAsUint32List(listKey)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{1, 3}, values)
+
+	require.NoError(t, os.Setenv(listKey, "1,x,3"))
+	_, err = getEnvAsUint32List(listKey)
+	require.Error(t, err)
+	require.NoError(t, os.Unsetenv(listKey))
+}
+
 func TestGetEnvAsDurationShouldReturnDefaultIfKeyNotFound(t *testing.T) {
 	require.Equal(t, time.Second*1337, getEnvAsDuration(str, time.Second*1337))
 }
@@ -72,8 +130,55 @@ func TestHasValidEmailSettings(t *testing.T) {
 	require.False(t, (&Config{SendgridApiKey: str, EmailFrom: str, ServiceEmail: ""}).HasValidEmailConfig())
 }
 
+func TestIsAllOrNothingCart(t *testing.T) {
+	require.True(t, (&Config{CartFailurePolicy: CartPolicyAllOrNothing}).IsAllOrNothingCart())
+	require.False(t, (&Config{CartFailurePolicy: CartPolicyRefundUnavailable}).IsAllOrNothingCart())
+	require.False(t, (&Config{}).IsAllOrNothingCart())
+}
+
+func TestHasBatchTxs(t *testing.T) {
+	require.True(t, (&Config{BatchTxs: 1}).HasBatchTxs())
+	require.False(t, (&Config{}).HasBatchTxs())
+}
+
+func TestMintsRetiredPayments(t *testing.T) {
+	require.True(t, (&Config{RetiredPaymentPolicy: RetiredPaymentPolicyMint}).MintsRetiredPayments())
+	require.False(t, (&Config{RetiredPaymentPolicy: RetiredPaymentPolicyRefund}).MintsRetiredPayments())
+	require.False(t, (&Config{}).MintsRetiredPayments())
+}
+
+func TestHasTreasurySweep(t *testing.T) {
+	require.True(t, (&Config{TreasuryAddress: "cudos1a326k254fukx9jlp0h3fwcr2ymjgludzum67dv"}).HasTreasurySweep())
+	require.False(t, (&Config{}).HasTreasurySweep())
+}
+
+func TestHasFeeGranter(t *testing.T) {
+	require.True(t, (&Config{FeeGranter: "cudos1a326k254fukx9jlp0h3fwcr2ymjgludzum67dv"}).HasFeeGranter())
+	require.False(t, (&Config{}).HasFeeGranter())
+}
+
+func TestHasSignedQuotes(t *testing.T) {
+	require.True(t, (&Config{AuraPoolPublicKeys: []string{"O2onvM62pC1io6jQKm8Nc2UyFXcd4kOmOsBIoYtZ2ik="}}).HasSignedQuotes())
+	require.False(t, (&Config{}).HasSignedQuotes())
+}
+
+func TestHasQuotes(t *testing.T) {
+	require.True(t, (&Config{QuoteKeyFile: "quote.key"}).HasQuotes())
+	require.False(t, (&Config{}).HasQuotes())
+}
+
+func TestHasAuthzMinter(t *testing.T) {
+	require.True(t, (&Config{AuthzMinter: "cudos1a326k254fukx9jlp0h3fwcr2ymjgludzum67dv"}).HasAuthzMinter())
+	require.False(t, (&Config{}).HasAuthzMinter())
+}
+
+func TestHasRemoteSigner(t *testing.T) {
+	require.True(t, (&Config{SignerURL: "http://127.0.0.1:3001"}).HasRemoteSigner())
+	require.False(t, (&Config{}).HasRemoteSigner())
+}
+
 func TestString(t *testing.T) {
-	expectedCfgString := "Config { WalletMnemonic(Hidden for security), ChainID(cudos-local-network), ChainRPC(http://127.0.0.1:26657), ChainGRPC(127.0.0.1:9090), AuraPoolBackend(http://127.0.0.1:8080), StartingHeight(2), MaxRetries(10), RetryInterval(30000000000), RelayInterval(5000000000), PaymentDenom(acudos), Port(3000) PrettyLogging(0) SendgridApiKey(Hidden for security) EmailFrom() ServiceEmail() EmailSendInterval(1800000000000)}"
+	expectedCfgString := "Config { WalletMnemonic(Hidden for security), WalletKeySource(mnemonic), KeyringBackend(file), KeyringDir(), WalletKeyName(), WalletKeyFile(), KeyPassphraseFile(), HDCoinType(118), HDAccount(0), HDAddressIndex(0), Bip39PassphraseFile(), ChainID(cudos-local-network), ChainRPC(http://127.0.0.1:26657), ChainGRPC(127.0.0.1:9090), PaymentSource(tx_search), QueryWindow(10000), QueryPageRetries(3), TxIndexFile(), LedgerFile(ledger.jsonl), DecisionLogFile(), ShadowMode(0), AuraPoolBackend(http://127.0.0.1:8080), AuraPoolPublicKeys([]), PriceValidityGrace(60000000000), StartingHeight(2), MaxRetries(10), RetryInterval(30000000000), RelayInterval(5000000000), ConfirmationDepth(0), MaxBlockAge(60000000000), PaymentDenom(acudos), Port(3000) PrettyLogging(0) SendgridApiKey(Hidden for security) EmailFrom() ServiceEmail() EmailSendInterval(1800000000000) CartFailurePolicy(refund_unavailable) MaxCartItems(10) BatchTxs(0) BatchGasLimit(2000000) Workers(1) MinterAccountIndices([]) MinterKeys([]) RetiredWalletKeys([]) RetiredWalletAddresses([]) RetiredPaymentPolicy(refund) BalanceWarningThreshold() BalanceCriticalThreshold() TreasuryAddress() SweepFloat(0) SweepInterval(86400000000000) SweepLogFile(sweeps.jsonl) FeeGranter() FeeGrantMinAllowance() AuthzMinter() QuoteKeyFile() QuoteStoreFile(quotes.jsonl) QuoteValidity(900000000000) QuoteRetention(86400000000000) QuoteRateLimit(60) QuoteClientRateLimit(10) QuoteAuthTokenFile() SignerURL() SignerPubKey() SignerAuthTokenFile() SignerCAFile()}"
 
 	haveCfg, err := NewConfig("../../.env.example")
 	require.NoError(t, err)
@@ -81,3 +186,7 @@ func TestString(t *testing.T) {
 }
 
 const str = "test"
+
+const listKey = "testlist"
+
+const uintKey = "testuint"
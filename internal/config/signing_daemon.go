@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/joho/godotenv"
+)
+
+func NewSigningDaemonConfig(envPath string) (SigningDaemonConfig, error) {
+	if err := godotenv.Load(envPath); err != nil {
+		return SigningDaemonConfig{}, err
+	}
+
+	allowedMsgTypes := getEnvAsList("SIGNER_ALLOWED_MSG_TYPES")
+	if len(allowedMsgTypes) == 0 {
+		allowedMsgTypes = []string{mintNftMsgType, bankSendMsgType}
+	}
+
+	return SigningDaemonConfig{
+		WalletKeySource:     getEnv("SIGNER_KEY_SOURCE", KeySourceMnemonic),
+		MnemonicFile:        getEnv("SIGNER_MNEMONIC_FILE", ""),
+		KeyringBackend:      getEnv("SIGNER_KEYRING_BACKEND", "file"),
+		KeyringDir:          getEnv("SIGNER_KEYRING_DIR", ""),
+		WalletKeyName:       getEnv("SIGNER_KEY_NAME", ""),
+		WalletKeyFile:       getEnv("SIGNER_KEY_FILE", ""),
+		KeyPassphraseFile:   getEnv("SIGNER_KEY_PASSPHRASE_FILE", ""),
+		HDCoinType:          getEnvAsUint32("SIGNER_HD_COIN_TYPE", 118),
+		HDAccount:           getEnvAsUint32("SIGNER_HD_ACCOUNT", 0),
+		HDAddressIndex:      getEnvAsUint32("SIGNER_HD_ADDRESS_INDEX", 0),
+		Bip39PassphraseFile: getEnv("SIGNER_BIP39_PASSPHRASE_FILE", ""),
+		AuthTokenFile:       getEnv("SIGNER_AUTH_TOKEN_FILE", ""),
+		ChainID:             getEnv("CHAIN_ID", ""),
+		PaymentDenom:        getEnv("PAYMENT_DENOM", "acudos"),
+		Host:                getEnv("SIGNER_HOST", "127.0.0.1"),
+		Port:                getEnvAsInt("SIGNER_PORT", 3001),
+		TLSCertFile:         getEnv("SIGNER_TLS_CERT_FILE", ""),
+		TLSKeyFile:          getEnv("SIGNER_TLS_KEY_FILE", ""),
+		AllowedMsgTypes:     allowedMsgTypes,
+		MaxRefundAmount:     getEnv("SIGNER_MAX_REFUND_AMOUNT", ""),
+		MaxFeeAmount:        getEnv("SIGNER_MAX_FEE_AMOUNT", ""),
+		MaxGas:              getEnvAsInt64("SIGNER_MAX_GAS", 5000000),
+		FeeGranter:          getEnv("SIGNER_FEE_GRANTER", ""),
+		TreasuryAddress:     getEnv("SIGNER_TREASURY_ADDRESS", ""),
+		PrettyLogging:       getEnvAsInt("PRETTY_LOGGING", 0),
+	}, nil
+}
+
+// Config of the signing daemon which holds the key of the payment wallet and signs the transactions of the relayer.
+type SigningDaemonConfig struct {
+	// Where the key of the wallet is loaded from, see the key sources
+	WalletKeySource string
+	// File with the mnemonic of the wallet
+	MnemonicFile string
+	// Backend of the keyring, either file or test
+	KeyringBackend string
+	// Home directory of the keyring
+	KeyringDir string
+	// Name of the wallet key in the keyring
+	WalletKeyName string
+	// Armored encrypted private key of the wallet
+	WalletKeyFile string
+	// File with the passphrase of the keyring or the key file
+	KeyPassphraseFile string
+	// Derivation path m/44'/<coin type>'/<account>'/0/<address index> of the wallet mnemonic
+	HDCoinType     uint32
+	HDAccount      uint32
+	HDAddressIndex uint32
+	// File with the BIP39 passphrase of the wallet mnemonic
+	Bip39PassphraseFile string
+	// File with the token the relayer has to authenticate with
+	AuthTokenFile string
+	ChainID       string
+	PaymentDenom  string
+	// Address the daemon listens on, all interfaces if empty
+	Host string
+	Port int
+	// Certificate and private key the daemon serves TLS with
+	TLSCertFile string
+	TLSKeyFile  string
+	// Type urls of the messages the daemon is allowed to sign
+	AllowedMsgTypes []string
+	// Maximum amount of a single bank send, i.e. refund, in the payment denom
+	MaxRefundAmount string
+	// Maximum fee of a transaction in the payment denom
+	MaxFeeAmount string
+	// Maximum gas limit of a transaction
+	MaxGas int64
+	// The only fee granter allowed in the signed transactions, none if empty
+	FeeGranter string
+	// Bank sends to the treasury are sweeps and are not limited by the max refund amount
+	TreasuryAddress string
+	PrettyLogging   int
+}
+
+func (cfg *SigningDaemonConfig) HasPrettyLogging() bool {
+	return cfg.PrettyLogging == 1
+}
+
+func (cfg *SigningDaemonConfig) HasTLS() bool {
+	return cfg.TLSCertFile != "" && cfg.TLSKeyFile != ""
+}
+
+// Whether the daemon listens on the loopback interface only, so the sign requests never leave the host.
+func (cfg *SigningDaemonConfig) HasLoopbackHost() bool {
+	if cfg.Host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(cfg.Host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (cfg *SigningDaemonConfig) ListenAddress() string {
+	return net.JoinHostPort(cfg.Host, fmt.Sprintf("%d", cfg.Port))
+}
+
+func (cfg *SigningDaemonConfig) String() string {
+	return fmt.Sprintf("SigningDaemonConfig { WalletKeySource(%s), MnemonicFile(%s), KeyringBackend(%s), KeyringDir(%s), WalletKeyName(%s), WalletKeyFile(%s), KeyPassphraseFile(%s), HDCoinType(%d), HDAccount(%d), HDAddressIndex(%d), Bip39PassphraseFile(%s), AuthTokenFile(%s), ChainID(%s), PaymentDenom(%s), Host(%s), Port(%d), TLSCertFile(%s), TLSKeyFile(%s), AllowedMsgTypes(%v), MaxRefundAmount(%s), MaxFeeAmount(%s), MaxGas(%d), FeeGranter(%s), TreasuryAddress(%s), PrettyLogging(%d)}", cfg.WalletKeySource, cfg.MnemonicFile, cfg.KeyringBackend, cfg.KeyringDir, cfg.WalletKeyName, cfg.WalletKeyFile, cfg.KeyPassphraseFile, cfg.HDCoinType, cfg.HDAccount, cfg.HDAddressIndex, cfg.Bip39PassphraseFile, cfg.AuthTokenFile, cfg.ChainID, cfg.PaymentDenom, cfg.Host, cfg.Port, cfg.TLSCertFile, cfg.TLSKeyFile, cfg.AllowedMsgTypes, cfg.MaxRefundAmount, cfg.MaxFeeAmount, cfg.MaxGas, cfg.FeeGranter, cfg.TreasuryAddress, cfg.PrettyLogging)
+}
+
+const (
+	mintNftMsgType  = "/cudoventures.cudosnode.marketplace.MsgMintNft"
+	bankSendMsgType = "/cosmos.bank.v1beta1.MsgSend"
+)
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldCreateSigningDaemonConfig(t *testing.T) {
+	expectedCfg := SigningDaemonConfig{
+		WalletKeySource: KeySourceMnemonic,
+		MnemonicFile:    "./secrets/wallet_mnemonic",
+		KeyringBackend:  "file",
+		HDCoinType:      118,
+		AuthTokenFile:   "./secrets/signer_auth_token",
+		ChainID:         "cudos-local-network",
+		PaymentDenom:    "acudos",
+		Host:            "127.0.0.1",
+		Port:            3001,
+		AllowedMsgTypes: []string{mintNftMsgType, bankSendMsgType},
+		MaxRefundAmount: "100000000000000000000000",
+		MaxFeeAmount:    "25000000000000000000",
+		MaxGas:          5000000,
+	}
+
+	// The env file does not override variables loaded by the tests of the relayer config
+	require.NoError(t, os.Unsetenv("SIGNER_AUTH_TOKEN_FILE"))
+
+	haveCfg, err := NewSigningDaemonConfig("../../.env.signing-daemon.example")
+	require.NoError(t, err)
+	require.Equal(t, expectedCfg, haveCfg)
+}
+
+func TestShouldFailSigningDaemonConfigIfNotExistingFile(t *testing.T) {
+	_, err := NewSigningDaemonConfig("badpath")
+	require.Error(t, err)
+}
+
+func TestHasLoopbackHost(t *testing.T) {
+	require.True(t, (&SigningDaemonConfig{Host: "127.0.0.1"}).HasLoopbackHost())
+	require.True(t, (&SigningDaemonConfig{Host: "::1"}).HasLoopbackHost())
+	require.True(t, (&SigningDaemonConfig{Host: "localhost"}).HasLoopbackHost())
+	require.False(t, (&SigningDaemonConfig{Host: "10.0.0.5"}).HasLoopbackHost())
+	require.False(t, (&SigningDaemonConfig{Host: "signer.internal"}).HasLoopbackHost())
+	require.False(t, (&SigningDaemonConfig{}).HasLoopbackHost())
+}
+
+func TestHasTLS(t *testing.T) {
+	require.True(t, (&SigningDaemonConfig{TLSCertFile: "signer.crt", TLSKeyFile: "signer.key"}).HasTLS())
+	require.False(t, (&SigningDaemonConfig{TLSCertFile: "signer.crt"}).HasTLS())
+	require.False(t, (&SigningDaemonConfig{}).HasTLS())
+}
+
+func TestShouldJoinListenAddress(t *testing.T) {
+	require.Equal(t, "127.0.0.1:3001", (&SigningDaemonConfig{Host: "127.0.0.1", Port: 3001}).ListenAddress())
+	require.Equal(t, "[::1]:3001", (&SigningDaemonConfig{Host: "::1", Port: 3001}).ListenAddress())
+	require.Equal(t, ":3001", (&SigningDaemonConfig{Port: 3001}).ListenAddress())
+}
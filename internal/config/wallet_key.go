@@ -0,0 +1,59 @@
+package config
+
+// Where the private key of a wallet is loaded from. The service and the signing daemon load the key of the payment wallet the same way.
+type WalletKeyConfig struct {
+	// One of the key sources
+	Source string
+	// Mnemonic of the wallet, read from the mnemonic file if empty
+	Mnemonic     string
+	MnemonicFile string
+	// Backend and home directory of the keyring
+	KeyringBackend string
+	KeyringDir     string
+	// Name of the wallet key in the keyring
+	KeyName string
+	// Armored encrypted private key of the wallet
+	KeyFile string
+	// File with the passphrase of the keyring or the key file
+	PassphraseFile string
+	// Derivation path m/44'/<coin type>'/<account>'/0/<address index> of the wallet mnemonic
+	HDCoinType     uint32
+	HDAccount      uint32
+	HDAddressIndex uint32
+	// File with the BIP39 passphrase of the wallet mnemonic
+	Bip39PassphraseFile string
+}
+
+// The key source of the payment wallet.
+func (cfg *Config) WalletKeyConfig() WalletKeyConfig {
+	return WalletKeyConfig{
+		Source:              cfg.WalletKeySource,
+		Mnemonic:            cfg.WalletMnemonic,
+		KeyringBackend:      cfg.KeyringBackend,
+		KeyringDir:          cfg.KeyringDir,
+		KeyName:             cfg.WalletKeyName,
+		KeyFile:             cfg.WalletKeyFile,
+		PassphraseFile:      cfg.KeyPassphraseFile,
+		HDCoinType:          cfg.HDCoinType,
+		HDAccount:           cfg.HDAccount,
+		HDAddressIndex:      cfg.HDAddressIndex,
+		Bip39PassphraseFile: cfg.Bip39PassphraseFile,
+	}
+}
+
+// The key source of the wallet the signing daemon signs with.
+func (cfg *SigningDaemonConfig) WalletKeyConfig() WalletKeyConfig {
+	return WalletKeyConfig{
+		Source:              cfg.WalletKeySource,
+		MnemonicFile:        cfg.MnemonicFile,
+		KeyringBackend:      cfg.KeyringBackend,
+		KeyringDir:          cfg.KeyringDir,
+		KeyName:             cfg.WalletKeyName,
+		KeyFile:             cfg.WalletKeyFile,
+		PassphraseFile:      cfg.KeyPassphraseFile,
+		HDCoinType:          cfg.HDCoinType,
+		HDAccount:           cfg.HDAccount,
+		HDAddressIndex:      cfg.HDAddressIndex,
+		Bip39PassphraseFile: cfg.Bip39PassphraseFile,
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+)
+
+// Reporting the health of the relayer. It is unavailable while the node is catching up or its latest block is stale,
+// so a load balancer or a monitor can tell that payments are not being processed.
+// Before the first relay tick it is available with the status starting, so a restart is not reported as an outage.
+func GetHealth(hr healthReporter) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health := hr.Health()
+
+		statusCode := http.StatusOK
+		if health.Status == model.HealthStatusUnhealthy {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, statusCode, health)
+	}
+}
+
+type healthReporter interface {
+	Health() model.Health
+}
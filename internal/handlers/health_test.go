@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldReturnHealthy(t *testing.T) {
+	blockTime := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	res := serveHealth(&mockHealthReporter{health: model.Health{Status: model.HealthStatusHealthy, Healthy: true, LatestHeight: 10, LatestBlockTime: blockTime, ConfirmedHeight: 8}})
+
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, `{"status":"healthy","healthy":true,"catchingUp":false,"blockStale":false,"latestHeight":10,"latestBlockTime":"2022-10-01T12:00:00Z","confirmedHeight":8}`+"\n", res.Body.String())
+}
+
+func TestShouldReturnUnavailableIfUnhealthy(t *testing.T) {
+	res := serveHealth(&mockHealthReporter{health: model.Health{Status: model.HealthStatusUnhealthy, CatchingUp: true}})
+	require.Equal(t, http.StatusServiceUnavailable, res.Code)
+}
+
+func TestShouldReturnOkWhileStarting(t *testing.T) {
+	res := serveHealth(&mockHealthReporter{health: model.Health{Status: model.HealthStatusStarting}})
+
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Contains(t, res.Body.String(), `"status":"starting","healthy":false`)
+}
+
+func serveHealth(hr healthReporter) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	GetHealth(hr)(res, httptest.NewRequest(http.MethodGet, "/health", nil))
+	return res
+}
+
+func (m *mockHealthReporter) Health() model.Health {
+	return m.health
+}
+
+type mockHealthReporter struct {
+	health model.Health
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// Issuing a signed quote of the NFT with the uid for the recipient.
+// The buyer pays the price and the fee of the quote with the memo {"quote":"<id>"} before the quote expires.
+// Every issued quote is stored, so the requests are limited and, with an auth token, must carry it as a bearer token.
+func GetQuote(qi quoteIssuer, limiter *quoteLimiter, authToken string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if authToken != "" && !hasBearerToken(r, authToken) {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+
+		if !limiter.Allow(clientAddress(r)) {
+			writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: "too many quote requests"})
+			return
+		}
+
+		vars := mux.Vars(r)
+
+		quote, err := qi.IssueQuote(r.Context(), vars["uid"], vars["recipient"])
+		if err != nil {
+			log.Warn().Msgf("issuing quote of nft (%s) for (%s) failed: %s", vars["uid"], vars["recipient"], err)
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, quote)
+	}
+}
+
+// Limiting the requests per window in total and per client, a limit of 0 is no limit.
+// The counts are reset at the start of every window. A client is only counted once the total limit allowed its request,
+// so no more clients than the total limit are kept in memory.
+func NewQuoteLimiter(limit, clientLimit int, window time.Duration) *quoteLimiter {
+	return &quoteLimiter{
+		limit:       limit,
+		clientLimit: clientLimit,
+		window:      window,
+		now:         time.Now,
+		clients:     map[string]int{},
+	}
+}
+
+func (l *quoteLimiter) Allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart = now
+		l.total = 0
+		l.clients = map[string]int{}
+	}
+
+	if l.limit > 0 && l.total >= l.limit {
+		return false
+	}
+
+	if l.clientLimit > 0 && l.clients[client] >= l.clientLimit {
+		return false
+	}
+
+	l.total++
+	l.clients[client]++
+	return true
+}
+
+// The client is the remote address of the connection, a proxy in front of the service is a single client.
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func hasBearerToken(r *http.Request, authToken string) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(authToken)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Msgf("encoding response failed: %s", err)
+	}
+}
+
+type quoteLimiter struct {
+	mu          sync.Mutex
+	limit       int
+	clientLimit int
+	window      time.Duration
+	now         func() time.Time
+	windowStart time.Time
+	total       int
+	clients     map[string]int
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type quoteIssuer interface {
+	IssueQuote(ctx context.Context, uid, recipient string) (model.Quote, error)
+}
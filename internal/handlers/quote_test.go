@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldReturnQuote(t *testing.T) {
+	issuer := &mockQuoteIssuer{quote: model.Quote{Id: "quoteid", Uid: "uid1", Recipient: "cudos1recipient", Price: sdk.NewInt(1), Fee: sdk.NewInt(2), ExpiresAt: 3, Nonce: "nonce", Signature: "signature"}}
+
+	res := serveQuote(issuer, "/api/v1/quote/uid1/cudos1recipient")
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	require.Equal(t, `{"id":"quoteid","uid":"uid1","recipient":"cudos1recipient","price":"1","fee":"2","expiresAt":3,"nonce":"nonce","signature":"signature"}`+"\n", res.Body.String())
+	require.Equal(t, "uid1", issuer.uid)
+	require.Equal(t, "cudos1recipient", issuer.recipient)
+}
+
+func TestShouldReturnBadRequestIfIssuingQuoteFails(t *testing.T) {
+	res := serveQuote(&mockQuoteIssuer{err: errors.New("nft (uid1) was not found")}, "/api/v1/quote/uid1/cudos1recipient")
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	require.Equal(t, `{"error":"nft (uid1) was not found"}`+"\n", res.Body.String())
+}
+
+func TestShouldRequireAuthToken(t *testing.T) {
+	handler := newQuoteTestHandler(&mockQuoteIssuer{}, NewQuoteLimiter(0, 0, time.Minute), "token")
+
+	res := serveQuoteRequest(handler, newQuoteTestRequest("1.2.3.4:1000", ""))
+	require.Equal(t, http.StatusUnauthorized, res.Code)
+
+	res = serveQuoteRequest(handler, newQuoteTestRequest("1.2.3.4:1000", "Bearer other"))
+	require.Equal(t, http.StatusUnauthorized, res.Code)
+
+	res = serveQuoteRequest(handler, newQuoteTestRequest("1.2.3.4:1000", "Bearer token"))
+	require.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestShouldLimitQuotesPerClient(t *testing.T) {
+	issuer := &mockQuoteIssuer{}
+	handler := newQuoteTestHandler(issuer, NewQuoteLimiter(0, 2, time.Minute), "")
+
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusOK, serveQuoteRequest(handler, newQuoteTestRequest("1.2.3.4:1000", "")).Code)
+	}
+
+	res := serveQuoteRequest(handler, newQuoteTestRequest("1.2.3.4:2000", ""))
+	require.Equal(t, http.StatusTooManyRequests, res.Code)
+	require.Equal(t, `{"error":"too many quote requests"}`+"\n", res.Body.String())
+
+	require.Equal(t, http.StatusOK, serveQuoteRequest(handler, newQuoteTestRequest("5.6.7.8:1000", "")).Code)
+	require.Equal(t, 3, issuer.calls)
+}
+
+func TestShouldLimitQuotesInTotal(t *testing.T) {
+	handler := newQuoteTestHandler(&mockQuoteIssuer{}, NewQuoteLimiter(2, 0, time.Minute), "")
+
+	require.Equal(t, http.StatusOK, serveQuoteRequest(handler, newQuoteTestRequest("1.1.1.1:1000", "")).Code)
+	require.Equal(t, http.StatusOK, serveQuoteRequest(handler, newQuoteTestRequest("2.2.2.2:1000", "")).Code)
+	require.Equal(t, http.StatusTooManyRequests, serveQuoteRequest(handler, newQuoteTestRequest("3.3.3.3:1000", "")).Code)
+}
+
+func TestShouldResetQuoteLimitAfterWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := NewQuoteLimiter(1, 1, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	require.True(t, limiter.Allow("client"))
+	require.False(t, limiter.Allow("client"))
+
+	now = now.Add(time.Minute)
+	require.True(t, limiter.Allow("client"))
+	require.Len(t, limiter.clients, 1)
+}
+
+func serveQuote(issuer quoteIssuer, path string) *httptest.ResponseRecorder {
+	return serveQuoteRequest(newQuoteTestHandler(issuer, NewQuoteLimiter(0, 0, time.Minute), ""), httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+func newQuoteTestHandler(issuer quoteIssuer, limiter *quoteLimiter, authToken string) http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/quote/{uid}/{recipient}", GetQuote(issuer, limiter, authToken))
+	return r
+}
+
+func newQuoteTestRequest(remoteAddr, authorization string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/quote/uid1/cudos1recipient", nil)
+	req.RemoteAddr = remoteAddr
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return req
+}
+
+func serveQuoteRequest(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func (m *mockQuoteIssuer) IssueQuote(ctx context.Context, uid, recipient string) (model.Quote, error) {
+	m.uid = uid
+	m.recipient = recipient
+	m.calls++
+	return m.quote, m.err
+}
+
+type mockQuoteIssuer struct {
+	quote     model.Quote
+	err       error
+	uid       string
+	recipient string
+	calls     int
+}
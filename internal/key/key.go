@@ -1,20 +1,187 @@
 package key
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/crypto"
 	cryptohd "github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
 	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/go-bip39"
 )
 
 func PrivKeyFromMnemonic(mnemonic string) (*secp256k1.PrivKey, error) {
-	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	return privKeyFromMnemonicForPath(mnemonic, "", hdPath)
+}
+
+// Deriving the key of the mnemonic with the given derivation parameters, e.g. of a wallet created with another coin type or a BIP39 passphrase.
+func PrivKeyFromMnemonicWithParams(mnemonic string, params HDParams) (*secp256k1.PrivKey, error) {
+	return privKeyFromMnemonicForPath(mnemonic, params.Bip39Passphrase, params.path())
+}
+
+// Deriving the keys of the minter wallets of the pool.
+// These are the sub-accounts of the wallet mnemonic at the given address indices, derived with the coin type, account and BIP39 passphrase
+// of the payment wallet, followed by the keys of separate minter wallets loaded from the key source of the payment wallet.
+// Every minter must be a wallet of its own, so a key of the payment wallet address or of an address derived before is an error.
+func MinterPrivKeys(walletMnemonic string, params HDParams, indices []uint32, keys []*secp256k1.PrivKey, walletAddress sdk.AccAddress) ([]*secp256k1.PrivKey, error) {
+	privKeys := []*secp256k1.PrivKey{}
+	seen := map[string]string{walletAddress.String(): "the payment wallet"}
+
+	for _, index := range indices {
+		if index == params.AddressIndex {
+			return nil, fmt.Errorf("minter account index %d is the payment wallet", index)
+		}
+
+		indexParams := params
+		indexParams.AddressIndex = index
+
+		privKey, err := PrivKeyFromMnemonicWithParams(walletMnemonic, indexParams)
+		if err != nil {
+			return nil, fmt.Errorf("deriving minter account at index %d failed: %s", index, err)
+		}
+
+		if err := checkUniqueAddress(seen, privKey, fmt.Sprintf("minter account index %d", index)); err != nil {
+			return nil, err
+		}
+		privKeys = append(privKeys, privKey)
+	}
+
+	for i, privKey := range keys {
+		if err := checkUniqueAddress(seen, privKey, fmt.Sprintf("minter key %d", i)); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(privKeys, keys...), nil
+}
+
+func checkUniqueAddress(seen map[string]string, privKey *secp256k1.PrivKey, name string) error {
+	address := sdk.AccAddress(privKey.PubKey().Address()).String()
+	if other, ok := seen[address]; ok {
+		return fmt.Errorf("%s derives address (%s) of %s", name, address, other)
+	}
+
+	seen[address] = name
+	return nil
+}
+
+// Loading the key stored under the given name in a keyring of the file or test backend, e.g. one created by `cudos-noded keys add`.
+// The passphrase unlocks the file backend and is ignored by the test backend.
+func PrivKeyFromKeyring(backend, dir, keyName, passphrase string) (*secp256k1.PrivKey, error) {
+	if backend != keyring.BackendFile && backend != keyring.BackendTest {
+		return nil, fmt.Errorf("unsupported keyring backend (%s)", backend)
+	}
+
+	kr, err := keyring.New(sdk.KeyringServiceName(), backend, dir, strings.NewReader(fmt.Sprintf("%s\n", passphrase)))
+	if err != nil {
+		return nil, fmt.Errorf("opening keyring failed: %s", err)
+	}
+
+	privKeyHex, err := keyring.NewUnsafe(kr).UnsafeExportPrivKeyHex(keyName)
+	if err != nil {
+		return nil, fmt.Errorf("loading key (%s) from keyring failed: %s", keyName, err)
+	}
+
+	privKeyBytes, err := hex.DecodeString(privKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secp256k1.PrivKey{Key: privKeyBytes}, nil
+}
+
+// Loading an armored private key encrypted by the passphrase, e.g. one exported by `cudos-noded keys export`.
+func PrivKeyFromArmorFile(path, passphrase string) (*secp256k1.PrivKey, error) {
+	armor, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	privKey, algo, err := crypto.UnarmorDecryptPrivKey(string(armor), passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting key file failed: %s", err)
+	}
+
+	secpPrivKey, ok := privKey.(*secp256k1.PrivKey)
+	if !ok {
+		return nil, fmt.Errorf("key file has unsupported key type (%s)", algo)
+	}
+
+	return secpPrivKey, nil
+}
+
+// Parsing the base64 encoded compressed public key of a wallet whose private key is held by the signing daemon.
+func PubKeyFromBase64(pubKeyBase64 string) (*secp256k1.PubKey, error) {
+	pubKeyBytes, err := base64.StdEncoding.DecodeString(pubKeyBase64)
+	if err != nil {
+		return nil, fmt.Errorf("decoding public key failed: %s", err)
+	}
+
+	if len(pubKeyBytes) != secp256k1.PubKeySize {
+		return nil, fmt.Errorf("public key has length %d instead of %d", len(pubKeyBytes), secp256k1.PubKeySize)
+	}
+
+	return &secp256k1.PubKey{Key: pubKeyBytes}, nil
+}
+
+// Parsing the base64 encoded ed25519 public keys the AuraPool signs its quotes with.
+func Ed25519PubKeysFromBase64(pubKeysBase64 []string) ([]ed25519.PublicKey, error) {
+	pubKeys := []ed25519.PublicKey{}
+	for _, pubKeyBase64 := range pubKeysBase64 {
+		pubKeyBytes, err := base64.StdEncoding.DecodeString(pubKeyBase64)
+		if err != nil {
+			return nil, fmt.Errorf("decoding public key (%s) failed: %s", pubKeyBase64, err)
+		}
+
+		if len(pubKeyBytes) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("public key (%s) has length %d instead of %d", pubKeyBase64, len(pubKeyBytes), ed25519.PublicKeySize)
+		}
+
+		pubKeys = append(pubKeys, ed25519.PublicKey(pubKeyBytes))
+	}
+
+	return pubKeys, nil
+}
+
+// Creating the ed25519 key the service signs its quotes with from a base64 encoded seed.
+func Ed25519PrivKeyFromBase64Seed(seedBase64 string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(seedBase64)
+	if err != nil {
+		return nil, fmt.Errorf("decoding seed failed: %s", err)
+	}
+
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("seed has length %d instead of %d", len(seed), ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// Reading a secret, e.g. a mnemonic or an auth token, from a file so it is never passed through the environment.
+func ReadSecretFile(path string) (string, error) {
+	secret, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(secret)), nil
+}
+
+func privKeyFromMnemonicForPath(mnemonic, bip39Passphrase, path string) (*secp256k1.PrivKey, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, bip39Passphrase)
 	if err != nil {
 		return nil, err
 	}
 
 	masterPriv, ch := cryptohd.ComputeMastersFromSeed(seed)
 
-	derivedKey, err := cryptohd.DerivePrivateKeyForPath(masterPriv, ch, hdPath)
+	derivedKey, err := cryptohd.DerivePrivateKeyForPath(masterPriv, ch, path)
 	if err != nil {
 		return nil, err
 	}
@@ -22,4 +189,21 @@ func PrivKeyFromMnemonic(mnemonic string) (*secp256k1.PrivKey, error) {
 	return &secp256k1.PrivKey{Key: derivedKey}, nil
 }
 
+// Parameters of the derivation of a key from a mnemonic at m/44'/<coin type>'/<account>'/0/<address index>.
+type HDParams struct {
+	CoinType        uint32
+	Account         uint32
+	AddressIndex    uint32
+	Bip39Passphrase string
+}
+
+// The parameters of the default path m/44'/118'/0'/0/0 without a BIP39 passphrase.
+func DefaultHDParams() HDParams {
+	return HDParams{CoinType: sdk.CoinType}
+}
+
+func (p HDParams) path() string {
+	return cryptohd.NewParams(44, p.CoinType, p.Account, false, p.AddressIndex).String()
+}
+
 var hdPath = "m/44'/118'/0'/0/0"
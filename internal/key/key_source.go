@@ -0,0 +1,87 @@
+package key
+
+import (
+	"fmt"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+)
+
+// Loading the private key of a wallet from a keyring, an encrypted key file or its mnemonic derived by the given parameters.
+// The mnemonic is read from the mnemonic file if it is not given. The passphrase of the keyring or the key file is always read from a file,
+// never from the environment.
+func WalletPrivKey(cfg config.WalletKeyConfig, hdParams HDParams) (*secp256k1.PrivKey, error) {
+	switch cfg.Source {
+	case config.KeySourceMnemonic:
+		if cfg.Mnemonic == "" && cfg.MnemonicFile != "" {
+			return SourcePrivKey(cfg, hdParams, cfg.MnemonicFile)
+		}
+		return PrivKeyFromMnemonicWithParams(cfg.Mnemonic, hdParams)
+	case config.KeySourceKeyFile:
+		return SourcePrivKey(cfg, hdParams, cfg.KeyFile)
+	default:
+		return SourcePrivKey(cfg, hdParams, cfg.KeyName)
+	}
+}
+
+func SourcePrivKeys(cfg config.WalletKeyConfig, hdParams HDParams, keyRefs []string) ([]*secp256k1.PrivKey, error) {
+	privKeys := []*secp256k1.PrivKey{}
+	for _, keyRef := range keyRefs {
+		privKey, err := SourcePrivKey(cfg, hdParams, keyRef)
+		if err != nil {
+			return nil, fmt.Errorf("loading key (%s) failed: %s", keyRef, err)
+		}
+		privKeys = append(privKeys, privKey)
+	}
+
+	return privKeys, nil
+}
+
+// Loading the private key of a further wallet from the key source of the payment wallet, e.g. of a minter or a retired wallet. The key is referenced by its name in the keyring,
+// the path of its key file or, with the mnemonic source, the path of a file holding its mnemonic, so no secret is passed through the environment.
+func SourcePrivKey(cfg config.WalletKeyConfig, hdParams HDParams, keyRef string) (*secp256k1.PrivKey, error) {
+	switch cfg.Source {
+	case config.KeySourceMnemonic:
+		mnemonic, err := ReadSecretFile(keyRef)
+		if err != nil {
+			return nil, fmt.Errorf("reading mnemonic file failed: %s", err)
+		}
+		return PrivKeyFromMnemonicWithParams(mnemonic, hdParams)
+	case config.KeySourceKeyring, config.KeySourceKeyFile:
+	default:
+		return nil, fmt.Errorf("unknown wallet key source (%s)", cfg.Source)
+	}
+
+	passphrase := ""
+	if cfg.PassphraseFile != "" {
+		var err error
+		if passphrase, err = ReadSecretFile(cfg.PassphraseFile); err != nil {
+			return nil, fmt.Errorf("reading passphrase file failed: %s", err)
+		}
+	}
+
+	if cfg.Source == config.KeySourceKeyring {
+		return PrivKeyFromKeyring(cfg.KeyringBackend, cfg.KeyringDir, keyRef, passphrase)
+	}
+
+	return PrivKeyFromArmorFile(keyRef, passphrase)
+}
+
+// Creating the derivation parameters of the wallet mnemonic. The BIP39 passphrase is read from a file, never from the environment.
+func WalletHDParams(cfg config.WalletKeyConfig) (HDParams, error) {
+	hdParams := HDParams{
+		CoinType:     cfg.HDCoinType,
+		Account:      cfg.HDAccount,
+		AddressIndex: cfg.HDAddressIndex,
+	}
+
+	if cfg.Bip39PassphraseFile != "" {
+		bip39Passphrase, err := ReadSecretFile(cfg.Bip39PassphraseFile)
+		if err != nil {
+			return HDParams{}, fmt.Errorf("reading bip39 passphrase file failed: %s", err)
+		}
+		hdParams.Bip39Passphrase = bip39Passphrase
+	}
+
+	return hdParams, nil
+}
@@ -0,0 +1,54 @@
+package key
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldLoadWalletPrivKeyFromKeySources(t *testing.T) {
+	mnemonic := "rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu"
+	expectedPrivKey, err := PrivKeyFromMnemonic(mnemonic)
+	require.NoError(t, err)
+
+	privKey, err := WalletPrivKey(config.WalletKeyConfig{Source: config.KeySourceMnemonic, Mnemonic: mnemonic}, DefaultHDParams())
+	require.NoError(t, err)
+	require.True(t, expectedPrivKey.Equals(privKey))
+
+	dir := t.TempDir()
+	mnemonicFile := filepath.Join(dir, "wallet_mnemonic")
+	require.NoError(t, os.WriteFile(mnemonicFile, []byte(mnemonic+"\n"), 0600))
+	privKey, err = WalletPrivKey(config.WalletKeyConfig{Source: config.KeySourceMnemonic, MnemonicFile: mnemonicFile}, DefaultHDParams())
+	require.NoError(t, err)
+	require.True(t, expectedPrivKey.Equals(privKey))
+
+	kr, err := keyring.New(sdk.KeyringServiceName(), keyring.BackendTest, dir, nil)
+	require.NoError(t, err)
+	_, err = kr.NewAccount("wallet", mnemonic, "", sdk.FullFundraiserPath, hd.Secp256k1)
+	require.NoError(t, err)
+
+	privKey, err = WalletPrivKey(config.WalletKeyConfig{Source: config.KeySourceKeyring, KeyringBackend: keyring.BackendTest, KeyringDir: dir, KeyName: "wallet"}, DefaultHDParams())
+	require.NoError(t, err)
+	require.True(t, expectedPrivKey.Equals(privKey))
+}
+
+func TestShouldFailIfUnknownWalletKeySource(t *testing.T) {
+	_, err := WalletPrivKey(config.WalletKeyConfig{Source: "unknown"}, DefaultHDParams())
+	require.Error(t, err)
+}
+
+func TestShouldFailIfMissingPassphraseFile(t *testing.T) {
+	_, err := WalletPrivKey(config.WalletKeyConfig{Source: config.KeySourceKeyFile, PassphraseFile: "missing"}, DefaultHDParams())
+	require.Error(t, err)
+}
+
+func TestShouldFailIfMissingBip39PassphraseFile(t *testing.T) {
+	_, err := WalletHDParams(config.WalletKeyConfig{Bip39PassphraseFile: "missing"})
+	require.Error(t, err)
+}
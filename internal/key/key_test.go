@@ -1,8 +1,17 @@
 package key
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/cosmos/cosmos-sdk/crypto"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/stretchr/testify/require"
 )
@@ -20,9 +29,187 @@ func TestShouldFailIfInvalidMnemonic(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestShouldDeriveMinterPrivKeys(t *testing.T) {
+	mnemonic := "rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu"
+
+	walletPrivKey, err := PrivKeyFromMnemonicWithParams(mnemonic, DefaultHDParams())
+	require.NoError(t, err)
+	require.Equal(t, "cosmos1a326k254fukx9jlp0h3fwcr2ymjgludza2npne", sdk.AccAddress(walletPrivKey.PubKey().Address()).String())
+
+	otherPrivKey, err := PrivKeyFromMnemonic(otherMnemonic)
+	require.NoError(t, err)
+
+	privKeys, err := MinterPrivKeys(mnemonic, DefaultHDParams(), []uint32{1, 2}, []*secp256k1.PrivKey{otherPrivKey}, sdk.AccAddress(walletPrivKey.PubKey().Address()))
+	require.NoError(t, err)
+	require.Len(t, privKeys, 3)
+	require.NotEqual(t, walletPrivKey.PubKey().Address(), privKeys[0].PubKey().Address())
+	require.NotEqual(t, privKeys[0].PubKey().Address(), privKeys[1].PubKey().Address())
+	require.True(t, otherPrivKey.Equals(privKeys[2]))
+}
+
+func TestShouldFailDerivingDuplicateMinterPrivKeys(t *testing.T) {
+	mnemonic := "rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu"
+	walletPrivKey, err := PrivKeyFromMnemonic(mnemonic)
+	require.NoError(t, err)
+	walletAddress := sdk.AccAddress(walletPrivKey.PubKey().Address())
+
+	_, err = MinterPrivKeys(mnemonic, DefaultHDParams(), []uint32{1, 1}, nil, walletAddress)
+	require.ErrorContains(t, err, "minter account index 1 derives address")
+
+	_, err = MinterPrivKeys(mnemonic, DefaultHDParams(), nil, []*secp256k1.PrivKey{walletPrivKey}, walletAddress)
+	require.ErrorContains(t, err, "minter key 0 derives address (cosmos1a326k254fukx9jlp0h3fwcr2ymjgludza2npne) of the payment wallet")
+
+	otherPrivKey, err := PrivKeyFromMnemonic(otherMnemonic)
+	require.NoError(t, err)
+	_, err = MinterPrivKeys(mnemonic, DefaultHDParams(), nil, []*secp256k1.PrivKey{otherPrivKey, otherPrivKey}, walletAddress)
+	require.ErrorContains(t, err, "minter key 1 derives address")
+
+	// A wallet loaded from a keyring is not derived by the index, so it is recognised by its address
+	_, err = MinterPrivKeys(mnemonic, HDParams{CoinType: sdk.CoinType, AddressIndex: 3}, []uint32{0}, nil, walletAddress)
+	require.ErrorContains(t, err, "of the payment wallet")
+}
+
+func TestShouldDerivePrivKeyWithParams(t *testing.T) {
+	mnemonic := "rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu"
+
+	defaultPrivKey, err := PrivKeyFromMnemonic(mnemonic)
+	require.NoError(t, err)
+
+	for _, params := range []HDParams{
+		{CoinType: 60},
+		{CoinType: sdk.CoinType, Account: 1},
+		{CoinType: sdk.CoinType, AddressIndex: 1},
+		{CoinType: sdk.CoinType, Bip39Passphrase: "passphrase"},
+	} {
+		privKey, err := PrivKeyFromMnemonicWithParams(mnemonic, params)
+		require.NoError(t, err)
+		require.False(t, defaultPrivKey.Equals(privKey))
+	}
+
+	privKeys, err := MinterPrivKeys(mnemonic, HDParams{CoinType: sdk.CoinType, AddressIndex: 3}, []uint32{0}, nil, nil)
+	require.NoError(t, err)
+	require.True(t, defaultPrivKey.Equals(privKeys[0]))
+}
+
+func TestShouldFailDerivingMinterPrivKeyOfPaymentWallet(t *testing.T) {
+	_, err := MinterPrivKeys("rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu", DefaultHDParams(), []uint32{0}, nil, nil)
+	require.Error(t, err)
+}
+
+func TestShouldFailDerivingMinterPrivKeyFromInvalidMnemonic(t *testing.T) {
+	_, err := MinterPrivKeys("bad mnemonic", DefaultHDParams(), []uint32{1}, nil, nil)
+	require.Error(t, err)
+}
+
+func TestShouldLoadPrivKeyFromKeyring(t *testing.T) {
+	crypto.BcryptSecurityParameter = 1
+	mnemonic := "rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu"
+
+	expectedPrivKey, err := PrivKeyFromMnemonic(mnemonic)
+	require.NoError(t, err)
+
+	for _, backend := range []string{keyring.BackendTest, keyring.BackendFile} {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			kr, err := keyring.New(sdk.KeyringServiceName(), backend, dir, strings.NewReader("passphrase\npassphrase\n"))
+			require.NoError(t, err)
+			_, err = kr.NewAccount("minter", mnemonic, "", sdk.FullFundraiserPath, hd.Secp256k1)
+			require.NoError(t, err)
+
+			privKey, err := PrivKeyFromKeyring(backend, dir, "minter", "passphrase")
+			require.NoError(t, err)
+			require.True(t, expectedPrivKey.Equals(privKey))
+
+			_, err = PrivKeyFromKeyring(backend, dir, "missing", "passphrase")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestShouldFailLoadingPrivKeyFromUnsupportedKeyring(t *testing.T) {
+	_, err := PrivKeyFromKeyring(keyring.BackendOS, t.TempDir(), "minter", "")
+	require.Error(t, err)
+}
+
+func TestShouldLoadPrivKeyFromArmorFile(t *testing.T) {
+	crypto.BcryptSecurityParameter = 1
+
+	expectedPrivKey, err := PrivKeyFromMnemonic("rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.armor")
+	require.NoError(t, os.WriteFile(path, []byte(crypto.EncryptArmorPrivKey(expectedPrivKey, "passphrase", string(hd.Secp256k1Type))), 0600))
+
+	privKey, err := PrivKeyFromArmorFile(path, "passphrase")
+	require.NoError(t, err)
+	require.True(t, expectedPrivKey.Equals(privKey))
+
+	_, err = PrivKeyFromArmorFile(path, "wrong passphrase")
+	require.Error(t, err)
+
+	_, err = PrivKeyFromArmorFile(filepath.Join(t.TempDir(), "missing"), "passphrase")
+	require.Error(t, err)
+}
+
+func TestShouldParsePubKeyFromBase64(t *testing.T) {
+	privKey, err := PrivKeyFromMnemonic("rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu")
+	require.NoError(t, err)
+
+	pubKey, err := PubKeyFromBase64(base64.StdEncoding.EncodeToString(privKey.PubKey().Bytes()))
+	require.NoError(t, err)
+	require.True(t, privKey.PubKey().Equals(pubKey))
+
+	_, err = PubKeyFromBase64("not base64")
+	require.Error(t, err)
+
+	_, err = PubKeyFromBase64(base64.StdEncoding.EncodeToString([]byte("short")))
+	require.Error(t, err)
+}
+
+func TestShouldParseEd25519PubKeysFromBase64(t *testing.T) {
+	pubKey := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)).Public().(ed25519.PublicKey)
+
+	pubKeys, err := Ed25519PubKeysFromBase64([]string{base64.StdEncoding.EncodeToString(pubKey)})
+	require.NoError(t, err)
+	require.Equal(t, []ed25519.PublicKey{pubKey}, pubKeys)
+
+	_, err = Ed25519PubKeysFromBase64([]string{"not base64"})
+	require.Error(t, err)
+
+	_, err = Ed25519PubKeysFromBase64([]string{base64.StdEncoding.EncodeToString([]byte("short"))})
+	require.Error(t, err)
+}
+
+func TestShouldCreateEd25519PrivKeyFromBase64Seed(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	privKey, err := Ed25519PrivKeyFromBase64Seed(base64.StdEncoding.EncodeToString(seed))
+	require.NoError(t, err)
+	require.Equal(t, ed25519.NewKeyFromSeed(seed), privKey)
+
+	_, err = Ed25519PrivKeyFromBase64Seed("not base64")
+	require.Error(t, err)
+
+	_, err = Ed25519PrivKeyFromBase64Seed(base64.StdEncoding.EncodeToString([]byte("short")))
+	require.Error(t, err)
+}
+
+func TestShouldReadSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("  token\n"), 0600))
+
+	secret, err := ReadSecretFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "token", secret)
+
+	_, err = ReadSecretFile(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
+
 func TestShouldFailIfInvalidHdPath(t *testing.T) {
 	hdPath = "badpath"
 	privKey, err := PrivKeyFromMnemonic("rebel wet poet torch carpet gaze axis ribbon approve depend inflict menu")
 	require.Nil(t, privKey)
 	require.Error(t, err)
 }
+
+const otherMnemonic = "truck monkey myself beyond impulse normal holiday globe immense mutual dash equal"
@@ -1,6 +1,10 @@
 package model
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -20,12 +24,66 @@ type NFTData struct {
 	DenomID         string    `json:"denomId"`
 	Status          NFTStatus `json:"status"`
 	PriceValidUntil int64     `json:"priceAcudosValidUntil"`
+	// Base64 encoded ed25519 signature of the AuraPool over the sign bytes of the quote
+	Signature string `json:"signature"`
+}
+
+// The canonical encoding of the quote signed by the AuraPool. It is a JSON object of the quoted fields and the recipient
+// with keys in alphabetical order, without HTML escaping and without a trailing new line.
+func (t *NFTData) SignBytes(recipient string) ([]byte, error) {
+	return canonicalJSON(nftSignDoc{
+		Data:            t.Data,
+		DenomID:         t.DenomID,
+		Id:              t.Id,
+		Name:            t.Name,
+		Price:           t.Price.String(),
+		PriceValidUntil: t.PriceValidUntil,
+		Recipient:       recipient,
+		Status:          t.Status,
+		Uri:             t.Uri,
+	})
+}
+
+// Encoding a sign doc to JSON without HTML escaping and without a trailing new line.
+// The keys are in the order of the struct fields, so the fields of the sign docs are in alphabetical order.
+func canonicalJSON(signDoc any) ([]byte, error) {
+	buf := bytes.Buffer{}
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(signDoc); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+type nftSignDoc struct {
+	Data            string    `json:"data"`
+	DenomID         string    `json:"denomId"`
+	Id              string    `json:"id"`
+	Name            string    `json:"name"`
+	Price           string    `json:"price"`
+	PriceValidUntil int64     `json:"priceValidUntil"`
+	Recipient       string    `json:"recipient"`
+	Status          NFTStatus `json:"status"`
+	Uri             string    `json:"uri"`
 }
 
 func (t *NFTData) String() string {
 	return fmt.Sprintf("NFTData { Id(%s) Price(%s) Name(%s) Uri(%s) Data(%s) DenomID(%s) Status(%s) PriceValidUntil(%s) }", t.Id, t.Price.String(), t.Name, t.Uri, t.Data, t.DenomID, t.Status, time.Unix(t.PriceValidUntil/1000, 0).String())
 }
 
+// The error of a quote of the AuraPool failing the signature verification. It is not retried,
+// a quote that is unsigned, signed by an unknown key or of another NFT stays so.
+type UntrustedQuoteError struct {
+	Uid    string
+	Detail string
+}
+
+func (e *UntrustedQuoteError) Error() string {
+	return e.Detail
+}
+
 type NFTStatus string
 
 const (
@@ -45,3 +103,331 @@ type GasResult struct {
 	FeeAmount sdk.Coins
 	GasLimit  uint64
 }
+
+// The request of the remote signer to the signing daemon.
+type SignRequest struct {
+	SignBytes []byte `json:"sign_bytes"`
+}
+
+// The response of the signing daemon with the signature of the sign bytes.
+type SignResponse struct {
+	Signature []byte `json:"signature"`
+}
+
+// A price quote of an NFT issued and signed by the service. The buyer references it by its id in the memo of the payment,
+// so the quoted price is honoured if the payment lands before the quote expires.
+type Quote struct {
+	Id        string  `json:"id"`
+	Uid       string  `json:"uid"`
+	Recipient string  `json:"recipient"`
+	Price     sdk.Int `json:"price"`
+	Fee       sdk.Int `json:"fee"`
+	ExpiresAt int64   `json:"expiresAt"`
+	Nonce     string  `json:"nonce"`
+	// Base64 encoded ed25519 signature of the service over the sign bytes of the quote
+	Signature string `json:"signature"`
+}
+
+// The canonical encoding of the quote signed by the service. It is a JSON object of all fields except the id and the signature
+// with keys in alphabetical order, without HTML escaping and without a trailing new line.
+func (t *Quote) SignBytes() ([]byte, error) {
+	return canonicalJSON(quoteSignDoc{
+		ExpiresAt: t.ExpiresAt,
+		Fee:       t.Fee.String(),
+		Nonce:     t.Nonce,
+		Price:     t.Price.String(),
+		Recipient: t.Recipient,
+		Uid:       t.Uid,
+	})
+}
+
+// The id of the quote is derived from its sign bytes, so a stored quote can not be altered without changing its id.
+func (t *Quote) ComputeId() (string, error) {
+	signBytes, err := t.SignBytes()
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(signBytes)
+	return hex.EncodeToString(hash[:quoteIdLength]), nil
+}
+
+func (t *Quote) String() string {
+	return fmt.Sprintf("Quote { Id(%s) Uid(%s) Recipient(%s) Price(%s) Fee(%s) ExpiresAt(%s) Nonce(%s) }", t.Id, t.Uid, t.Recipient, t.Price.String(), t.Fee.String(), time.UnixMilli(t.ExpiresAt).String(), t.Nonce)
+}
+
+type quoteSignDoc struct {
+	ExpiresAt int64  `json:"expiresAt"`
+	Fee       string `json:"fee"`
+	Nonce     string `json:"nonce"`
+	Price     string `json:"price"`
+	Recipient string `json:"recipient"`
+	Uid       string `json:"uid"`
+}
+
+// Length in bytes of the quote id, it is hex encoded to twice as many characters.
+const quoteIdLength = 16
+
+// A sweep of the balance of the payment wallet above the float to the treasury.
+type SweepRecord struct {
+	Time    time.Time `json:"time"`
+	TxHash  string    `json:"tx_hash"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Amount  string    `json:"amount"`
+	Balance string    `json:"balance"`
+	Kept    string    `json:"kept"`
+}
+
+// The fee allowance of a grantee. An empty spend limit is unlimited and empty allowed messages allow all messages.
+type FeeAllowance struct {
+	SpendLimit      sdk.Coins
+	Expiration      *time.Time
+	AllowedMessages []string
+}
+
+// The sync status of the node the relayer is connected to.
+type NodeStatus struct {
+	CatchingUp      bool
+	LatestHeight    int64
+	LatestBlockTime time.Time
+}
+
+// The health of the relayer as of its last relay tick. It is unhealthy while the node is catching up or its latest block is stale,
+// because no payments are processed then. Before the first relay tick checked the node its status is starting.
+type Health struct {
+	Status          string    `json:"status"`
+	Healthy         bool      `json:"healthy"`
+	CatchingUp      bool      `json:"catchingUp"`
+	BlockStale      bool      `json:"blockStale"`
+	LatestHeight    int64     `json:"latestHeight"`
+	LatestBlockTime time.Time `json:"latestBlockTime"`
+	ConfirmedHeight int64     `json:"confirmedHeight"`
+}
+
+// Statuses of the health of the relayer.
+const (
+	HealthStatusStarting  = "starting"
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// The blocks covered by the tx index, the ones after From up to To, and the addresses whose transactions were indexed in them.
+type TxIndexRange struct {
+	From    int64    `json:"from"`
+	To      int64    `json:"to"`
+	Wallets []string `json:"wallets"`
+}
+
+// The outgoing transactions of the service found in a range of blocks. The entries of the index are appended range by range.
+type TxIndexEntry struct {
+	TxIndexRange
+	Txs []IndexedTx `json:"txs"`
+}
+
+// An outgoing mint or refund transaction of the service. Refs are the references of the payments listed in its memo.
+type IndexedTx struct {
+	Hash    string          `json:"hash"`
+	Height  int64           `json:"height"`
+	Memo    string          `json:"memo"`
+	Refs    []string        `json:"refs"`
+	Fees    sdk.Coins       `json:"fees"`
+	Mints   []IndexedMint   `json:"mints,omitempty"`
+	Refunds []IndexedRefund `json:"refunds,omitempty"`
+}
+
+type IndexedMint struct {
+	Uid       string   `json:"uid"`
+	Recipient string   `json:"recipient"`
+	Price     sdk.Coin `json:"price"`
+}
+
+type IndexedRefund struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Amount sdk.Coins `json:"amount"`
+}
+
+// A payment reconstructed from the chain history together with the outgoing transactions referencing it.
+type LedgerEntry struct {
+	Ref        string   `json:"ref"`
+	Height     int64    `json:"height"`
+	Sender     string   `json:"sender"`
+	Receiver   string   `json:"receiver"`
+	Amount     sdk.Coin `json:"amount"`
+	Uids       []string `json:"uids"`
+	Status     string   `json:"status"`
+	MintTxs    []string `json:"mintTxs"`
+	MintedUids []string `json:"mintedUids"`
+	RefundTxs  []string `json:"refundTxs"`
+	Refunded   sdk.Int  `json:"refunded"`
+}
+
+// Outcomes of a payment in the ledger.
+const (
+	LedgerStatusMinted            = "minted"
+	LedgerStatusRefunded          = "refunded"
+	LedgerStatusMintedAndRefunded = "minted_and_refunded"
+	LedgerStatusUnprocessed       = "unprocessed"
+)
+
+// Something in the chain history that the relayer would not have done, found by the rebuild.
+type RebuildAnomaly struct {
+	Kind     string   `json:"kind"`
+	Ref      string   `json:"ref,omitempty"`
+	TxHashes []string `json:"txHashes,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// Kinds of the anomalies of a rebuild.
+const (
+	// A payment with neither a mint nor a refund.
+	AnomalyUnprocessedPayment = "unprocessed_payment"
+	// An NFT of a payment minted more than once.
+	AnomalyDuplicateMint = "duplicate_mint"
+	// A payment or an item of a cart refunded more than once.
+	AnomalyDuplicateRefund = "duplicate_refund"
+	// An outgoing transaction referencing no payment in the rebuilt blocks.
+	AnomalyOrphanTx = "orphan_tx"
+)
+
+// The result of a rebuild. The resume height is the height before the first unprocessed payment or the end of the rebuilt blocks.
+// The state is set to it only if the rebuild was asked to write the state.
+type RebuildReport struct {
+	FromHeight   int64            `json:"fromHeight"`
+	ToHeight     int64            `json:"toHeight"`
+	ResumeHeight int64            `json:"resumeHeight"`
+	StateWritten bool             `json:"stateWritten"`
+	Payments     int              `json:"payments"`
+	Anomalies    []RebuildAnomaly `json:"anomalies"`
+}
+
+// The result of reprocessing a single incoming transaction or the payments of a height range.
+type ReprocessReport struct {
+	TxHash     string        `json:"txHash,omitempty"`
+	FromHeight int64         `json:"fromHeight"`
+	ToHeight   int64         `json:"toHeight"`
+	DryRun     bool          `json:"dryRun"`
+	Payments   int           `json:"payments"`
+	Txs        []ReprocessTx `json:"txs"`
+}
+
+// An outgoing transaction of a reprocessing. In a dry run it is built and its gas is estimated but it is not sent, so it has no hash.
+type ReprocessTx struct {
+	Sender string            `json:"sender"`
+	Memo   string            `json:"memo"`
+	Msgs   []json.RawMessage `json:"msgs"`
+	Hash   string            `json:"hash,omitempty"`
+}
+
+// A decision of the relayer about a payment. Mints and refunds are logged once they are sent, skips once the payment is found to be processed.
+// The sender, the amount and the items are the ones of the payment. In shadow mode nothing is sent, so the decisions have no tx hash.
+// The NFT data is the one the decision was based on, as returned by the AuraPool. The gas and the fee are the ones of the outgoing transaction,
+// shared by all payments of a batch. The payment time is known once the NFT data is requested, so skips have none.
+type Decision struct {
+	Ref       string     `json:"ref"`
+	Height    int64      `json:"height"`
+	Sender    string     `json:"sender"`
+	Amount    sdk.Coin   `json:"amount"`
+	Uids      []string   `json:"uids"`
+	Recipient string     `json:"recipient"`
+	Outcome   string     `json:"outcome"`
+	Reason    string     `json:"reason"`
+	Detail    string     `json:"detail,omitempty"`
+	Nfts      []NFTData  `json:"nfts,omitempty"`
+	Refunded  sdk.Coins  `json:"refunded,omitempty"`
+	TxHash    string     `json:"txHash,omitempty"`
+	GasLimit  uint64     `json:"gasLimit,omitempty"`
+	Fee       sdk.Coins  `json:"fee,omitempty"`
+	PaidAt    *time.Time `json:"paidAt,omitempty"`
+	DecidedAt time.Time  `json:"decidedAt"`
+}
+
+const (
+	DecisionMint   = "mint"
+	DecisionRefund = "refund"
+	DecisionSkip   = "skip"
+)
+
+// The reasons of the decisions.
+const (
+	// The payment covers the NFTs, the reason of every mint.
+	ReasonPaid = "paid"
+	// A transaction of the service minting for the payment exists.
+	ReasonAlreadyMinted = "already_minted"
+	// A transaction of the service refunding the payment exists.
+	ReasonAlreadyRefunded = "already_refunded"
+	// Every item of the cart was minted or refunded.
+	ReasonAlreadyProcessed = "already_processed"
+	// The payment was received by a retired wallet.
+	ReasonRetiredWallet = "retired_wallet"
+	// The quote of the payment is unknown, expired or does not match the payment.
+	ReasonInvalidQuote = "invalid_quote"
+	// The quote of the AuraPool is unsigned, has an invalid signature or is of another NFT.
+	ReasonUntrustedAuraPoolQuote = "untrusted_aurapool_quote"
+	// The NFT was minted for another payment.
+	ReasonNftMinted = "nft_already_minted"
+	// The NFT data is invalid, the payment does not cover the price or sending the mint failed.
+	ReasonMintFailed = "mint_failed"
+	// The refund without the gas is below the minimum refund amount, so nothing is refunded.
+	ReasonRefundBelowMinimum = "refund_below_minimum"
+	// The cart has more items than allowed.
+	ReasonCartTooLarge = "cart_too_large"
+	// The cart lists an item more than once.
+	ReasonCartDuplicateItems = "cart_duplicate_items"
+	// The items of the cart do not fit into the memo of a transaction.
+	ReasonCartMemoTooLong = "cart_memo_too_long"
+	// Items of the cart can not be minted.
+	ReasonCartUnavailableItems = "cart_unavailable_items"
+	// The funds of the cart were spent by earlier transactions.
+	ReasonCartNoFunds = "cart_no_funds"
+)
+
+// A record of the decision log. The records are chained by the hash of the previous record, so a changed, removed or reordered record
+// breaks the chain. The decision is kept as it was written, its hash is over the exact bytes.
+type DecisionRecord struct {
+	Sequence uint64          `json:"sequence"`
+	PrevHash string          `json:"prevHash"`
+	Decision json.RawMessage `json:"decision"`
+	Hash     string          `json:"hash"`
+}
+
+// Hashing the record with the previous hash, so the hash covers the whole chain up to the record.
+func (r *DecisionRecord) ComputeHash() string {
+	hash := sha256.New()
+	hash.Write([]byte(fmt.Sprintf("%d\n%s\n", r.Sequence, r.PrevHash)))
+	hash.Write(r.Decision)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// The result of the verification of the decision log. The head is the hash of the last record, operators can keep it elsewhere
+// to detect a truncated log later.
+type DecisionLogReport struct {
+	File     string               `json:"file"`
+	Records  int                  `json:"records"`
+	HeadHash string               `json:"headHash"`
+	Valid    bool                 `json:"valid"`
+	Problems []DecisionLogProblem `json:"problems"`
+}
+
+// A problem of a record of the decision log, at the given line of the file.
+type DecisionLogProblem struct {
+	Line     int    `json:"line"`
+	Sequence uint64 `json:"sequence,omitempty"`
+	Kind     string `json:"kind"`
+	Detail   string `json:"detail"`
+}
+
+// The kinds of the problems of the decision log.
+const (
+	// The line is not a record with a valid decision.
+	DecisionLogMalformedRecord = "malformed_record"
+	// The sequence of the record does not follow the one of the previous record, records were removed, inserted or reordered.
+	DecisionLogSequenceGap = "sequence_gap"
+	// The previous hash of the record is not the hash of the previous record.
+	DecisionLogBrokenChain = "broken_chain"
+	// The hash of the record does not match its content, the record was changed.
+	DecisionLogHashMismatch = "hash_mismatch"
+	// The expected head hash is not in the log, records were removed from its end.
+	DecisionLogMissingHead = "missing_head"
+)
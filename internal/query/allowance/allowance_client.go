@@ -0,0 +1,77 @@
+package allowance
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/cosmos/cosmos-sdk/simapp/params"
+	"github.com/cosmos/cosmos-sdk/x/feegrant"
+	"google.golang.org/grpc"
+)
+
+func NewAllowanceClient(grpcConn *grpc.ClientConn, encodingConfig *params.EncodingConfig) *allowanceClient {
+	return &allowanceClient{
+		encodingConfig: encodingConfig,
+		feegrantClient: feegrant.NewQueryClient(grpcConn),
+	}
+}
+
+// Querying the fee allowance granted by the granter to the grantee.
+// Allowances restricted to some messages are unwrapped, so the limits of the allowance they wrap are returned together with the allowed messages.
+func (ac *allowanceClient) QueryAllowance(ctx context.Context, granter, grantee string) (model.FeeAllowance, error) {
+	res, err := ac.feegrantClient.Allowance(ctx, &feegrant.QueryAllowanceRequest{Granter: granter, Grantee: grantee})
+	if err != nil {
+		return model.FeeAllowance{}, err
+	}
+
+	if res.Allowance == nil || res.Allowance.Allowance == nil {
+		return model.FeeAllowance{}, fmt.Errorf("no fee allowance from granter (%s) to grantee (%s)", granter, grantee)
+	}
+
+	var allowance feegrant.FeeAllowanceI
+	if err := ac.encodingConfig.InterfaceRegistry.UnpackAny(res.Allowance.Allowance, &allowance); err != nil {
+		return model.FeeAllowance{}, err
+	}
+
+	return toFeeAllowance(allowance)
+}
+
+func toFeeAllowance(allowance feegrant.FeeAllowanceI) (model.FeeAllowance, error) {
+	switch a := allowance.(type) {
+	case *feegrant.BasicAllowance:
+		return model.FeeAllowance{SpendLimit: a.SpendLimit, Expiration: a.Expiration}, nil
+	case *feegrant.PeriodicAllowance:
+		// Only what is left of the current period can be spent, unless the total limit is even lower
+		spendLimit := a.PeriodCanSpend
+		if !a.Basic.SpendLimit.Empty() && a.Basic.SpendLimit.IsAllLT(spendLimit) {
+			spendLimit = a.Basic.SpendLimit
+		}
+
+		return model.FeeAllowance{SpendLimit: spendLimit, Expiration: a.Basic.Expiration}, nil
+	case *feegrant.AllowedMsgAllowance:
+		inner, err := a.GetAllowance()
+		if err != nil {
+			return model.FeeAllowance{}, err
+		}
+
+		feeAllowance, err := toFeeAllowance(inner)
+		if err != nil {
+			return model.FeeAllowance{}, err
+		}
+
+		feeAllowance.AllowedMessages = a.AllowedMessages
+		return feeAllowance, nil
+	default:
+		return model.FeeAllowance{}, fmt.Errorf("unsupported fee allowance type %T", allowance)
+	}
+}
+
+type allowanceClient struct {
+	encodingConfig *params.EncodingConfig
+	feegrantClient feegrantQueryClient
+}
+
+type feegrantQueryClient interface {
+	Allowance(ctx context.Context, in *feegrant.QueryAllowanceRequest, opts ...grpc.CallOption) (*feegrant.QueryAllowanceResponse, error)
+}
@@ -0,0 +1,97 @@
+package allowance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/feegrant"
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestShouldQueryBasicAllowance(t *testing.T) {
+	expiration := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	allowance := &feegrant.BasicAllowance{SpendLimit: sdk.NewCoins(sdk.NewInt64Coin("acudos", 1000)), Expiration: &expiration}
+
+	feeAllowance, err := newTestAllowanceClient(t, allowance, nil).QueryAllowance(context.Background(), "granter", "grantee")
+	require.NoError(t, err)
+	require.Equal(t, model.FeeAllowance{SpendLimit: sdk.NewCoins(sdk.NewInt64Coin("acudos", 1000)), Expiration: &expiration}, feeAllowance)
+}
+
+func TestShouldQueryPeriodicAllowanceLimitedByTotalSpendLimit(t *testing.T) {
+	allowance := &feegrant.PeriodicAllowance{
+		Basic:            feegrant.BasicAllowance{SpendLimit: sdk.NewCoins(sdk.NewInt64Coin("acudos", 500))},
+		Period:           time.Hour,
+		PeriodSpendLimit: sdk.NewCoins(sdk.NewInt64Coin("acudos", 1000)),
+		PeriodCanSpend:   sdk.NewCoins(sdk.NewInt64Coin("acudos", 800)),
+	}
+
+	feeAllowance, err := newTestAllowanceClient(t, allowance, nil).QueryAllowance(context.Background(), "granter", "grantee")
+	require.NoError(t, err)
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("acudos", 500)), feeAllowance.SpendLimit)
+
+	allowance.Basic.SpendLimit = nil
+	feeAllowance, err = newTestAllowanceClient(t, allowance, nil).QueryAllowance(context.Background(), "granter", "grantee")
+	require.NoError(t, err)
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("acudos", 800)), feeAllowance.SpendLimit)
+}
+
+func TestShouldQueryAllowedMsgAllowance(t *testing.T) {
+	allowance, err := feegrant.NewAllowedMsgAllowance(&feegrant.BasicAllowance{}, []string{"/cosmos.bank.v1beta1.MsgSend"})
+	require.NoError(t, err)
+
+	feeAllowance, err := newTestAllowanceClient(t, allowance, nil).QueryAllowance(context.Background(), "granter", "grantee")
+	require.NoError(t, err)
+	require.Equal(t, model.FeeAllowance{AllowedMessages: []string{"/cosmos.bank.v1beta1.MsgSend"}}, feeAllowance)
+}
+
+func TestShouldFailIfAllowanceRequestFails(t *testing.T) {
+	_, err := newTestAllowanceClient(t, nil, failedAllowanceRequest).QueryAllowance(context.Background(), "granter", "grantee")
+	require.Equal(t, failedAllowanceRequest, err)
+}
+
+func TestShouldFailIfNoAllowance(t *testing.T) {
+	_, err := newTestAllowanceClient(t, nil, nil).QueryAllowance(context.Background(), "granter", "grantee")
+	require.Error(t, err)
+}
+
+func newTestAllowanceClient(t *testing.T, allowance feegrant.FeeAllowanceI, err error) *allowanceClient {
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+	allowanceClient := NewAllowanceClient(nil, &encodingConfig)
+
+	mockClient := &mockFeegrantQueryClient{err: err}
+	if allowance != nil {
+		packed, err := codectypes.NewAnyWithValue(allowance.(proto.Message))
+		require.NoError(t, err)
+		mockClient.allowance = packed
+	}
+	allowanceClient.feegrantClient = mockClient
+
+	return allowanceClient
+}
+
+type mockFeegrantQueryClient struct {
+	allowance *codectypes.Any
+	err       error
+}
+
+func (mfqc *mockFeegrantQueryClient) Allowance(ctx context.Context, in *feegrant.QueryAllowanceRequest, opts ...grpc.CallOption) (*feegrant.QueryAllowanceResponse, error) {
+	if mfqc.err != nil {
+		return nil, mfqc.err
+	}
+
+	if mfqc.allowance == nil {
+		return &feegrant.QueryAllowanceResponse{}, nil
+	}
+
+	return &feegrant.QueryAllowanceResponse{Allowance: &feegrant.Grant{Granter: in.Granter, Grantee: in.Grantee, Allowance: mfqc.allowance}}, nil
+}
+
+var failedAllowanceRequest = errors.New("failed allowance request")
@@ -0,0 +1,37 @@
+package balance
+
+import (
+	"context"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"google.golang.org/grpc"
+)
+
+func NewBalanceClient(grpcConn *grpc.ClientConn) *balanceClient {
+	return &balanceClient{
+		bankClient: bank.NewQueryClient(grpcConn),
+	}
+}
+
+// Querying the balance of the address in the given denom. An address without funds has zero balance.
+func (bc *balanceClient) QueryBalance(ctx context.Context, address, denom string) (sdk.Int, error) {
+	res, err := bc.bankClient.Balance(ctx, &bank.QueryBalanceRequest{Address: address, Denom: denom})
+	if err != nil {
+		return sdk.Int{}, err
+	}
+
+	if res.Balance == nil {
+		return sdk.ZeroInt(), nil
+	}
+
+	return res.Balance.Amount, nil
+}
+
+type balanceClient struct {
+	bankClient bankQueryClient
+}
+
+type bankQueryClient interface {
+	Balance(ctx context.Context, in *bank.QueryBalanceRequest, opts ...grpc.CallOption) (*bank.QueryBalanceResponse, error)
+}
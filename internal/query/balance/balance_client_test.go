@@ -0,0 +1,54 @@
+package balance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestShouldQueryBalance(t *testing.T) {
+	balance := sdk.NewInt64Coin("acudos", 1000)
+	balanceClient := NewBalanceClient(nil)
+	balanceClient.bankClient = &mockBankQueryClient{balance: &balance}
+
+	amount, err := balanceClient.QueryBalance(context.Background(), "some address", "acudos")
+	require.NoError(t, err)
+	require.Equal(t, sdk.NewInt(1000), amount)
+}
+
+func TestShouldReturnZeroIfNoBalance(t *testing.T) {
+	balanceClient := NewBalanceClient(nil)
+	balanceClient.bankClient = &mockBankQueryClient{}
+
+	amount, err := balanceClient.QueryBalance(context.Background(), "some address", "acudos")
+	require.NoError(t, err)
+	require.Equal(t, sdk.ZeroInt(), amount)
+}
+
+func TestShouldFailIfBalanceRequestFails(t *testing.T) {
+	balanceClient := NewBalanceClient(nil)
+	balanceClient.bankClient = &mockBankQueryClient{err: failedBalanceRequest}
+
+	_, err := balanceClient.QueryBalance(context.Background(), "some address", "acudos")
+	require.Equal(t, failedBalanceRequest, err)
+}
+
+type mockBankQueryClient struct {
+	balance *sdk.Coin
+	err     error
+}
+
+func (mbqc *mockBankQueryClient) Balance(ctx context.Context, in *bank.QueryBalanceRequest, opts ...grpc.CallOption) (*bank.QueryBalanceResponse, error) {
+	if mbqc.err != nil {
+		return nil, mbqc.err
+	}
+
+	return &bank.QueryBalanceResponse{Balance: mbqc.balance}, nil
+}
+
+var failedBalanceRequest = errors.New("failed balance request")
@@ -0,0 +1,128 @@
+package relayminter
+
+import (
+	"fmt"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+)
+
+// Checking the configured cold minter. In authz mode the wallets mint on its behalf, so an invalid address would fail every mint.
+func (rm *relayMinter) validateAuthzMinter() error {
+	if !rm.config.HasAuthzMinter() {
+		return nil
+	}
+
+	if _, err := sdk.AccAddressFromBech32(rm.config.AuthzMinter); err != nil {
+		return fmt.Errorf("invalid authz minter address (%s): %s", rm.config.AuthzMinter, err)
+	}
+
+	return nil
+}
+
+// The creator of the mints sent by the given wallet. In authz mode it is the cold minter account, otherwise the wallet itself.
+func (rm *relayMinter) mintCreator(wallet *minterWallet) string {
+	if rm.config.HasAuthzMinter() {
+		return rm.config.AuthzMinter
+	}
+
+	return wallet.address.String()
+}
+
+// Building the mint message of the NFT.
+func (rm *relayMinter) newMintMsg(wallet *minterWallet, uid, recipient string, nftData model.NFTData) *marketplacetypes.MsgMintNft {
+	return marketplacetypes.NewMsgMintNft(rm.mintCreator(wallet), nftData.DenomID, recipient, nftData.Name, nftData.Uri, nftData.Data, uid, sdk.NewCoin("acudos", nftData.Price))
+}
+
+// Wrapping the mint messages sent by the given wallet. In authz mode they are executed by the wallet on behalf of the cold minter,
+// which granted it MsgMintNft, so all of them are wrapped in a single MsgExec.
+func (rm *relayMinter) wrapMints(wallet *minterWallet, mints []sdk.Msg) []sdk.Msg {
+	if !rm.config.HasAuthzMinter() {
+		return mints
+	}
+
+	msgExec := authz.NewMsgExec(wallet.address, mints)
+	return []sdk.Msg{&msgExec}
+}
+
+// Extracting the mint messages sent by the service from the messages of a transaction.
+// Plain mints must be created by a wallet of the service. Mints wrapped in MsgExec must be executed by a wallet of the service
+// and created by the cold minter that granted it, or by the wallet itself.
+func (rm *relayMinter) serviceMintMsgs(txHash, logInfo string, msgs []sdk.Msg) []*marketplacetypes.MsgMintNft {
+	mints := []*marketplacetypes.MsgMintNft{}
+
+	for i, msg := range msgs {
+		switch m := msg.(type) {
+		case *marketplacetypes.MsgMintNft:
+			// TO DO: Why we are checking the creator?
+			if !rm.isOwnWallet(m.Creator) {
+				rm.logger.Warnf("during check if %s for tx(%s), creator (%s) of the mint msg is not a wallet of the pool %v", logInfo, txHash, m.Creator, rm.knownWalletAddresses())
+				continue
+			}
+
+			mints = append(mints, m)
+		case *authz.MsgExec:
+			if !rm.isOwnWallet(m.Grantee) {
+				rm.logger.Warnf("during check if %s for tx(%s), grantee (%s) of the exec msg is not a wallet of the pool %v", logInfo, txHash, m.Grantee, rm.knownWalletAddresses())
+				continue
+			}
+
+			nestedMsgs, err := m.GetMessages()
+			if err != nil {
+				rm.logger.Warnf("during check if %s for tx(%s), unpacking exec msg %d failed: %s", logInfo, txHash, i, err)
+				continue
+			}
+
+			for j, nestedMsg := range nestedMsgs {
+				mintMsg, ok := nestedMsg.(*marketplacetypes.MsgMintNft)
+				if !ok {
+					rm.logger.Warnf("during check if %s for tx(%s), message %d of exec msg %d was not mint msg", logInfo, txHash, j, i)
+					continue
+				}
+
+				if !rm.isMintGranter(mintMsg.Creator) {
+					rm.logger.Warnf("during check if %s for tx(%s), creator (%s) of the wrapped mint msg is not the authz minter (%s)", logInfo, txHash, mintMsg.Creator, rm.config.AuthzMinter)
+					continue
+				}
+
+				mints = append(mints, mintMsg)
+			}
+		default:
+			rm.logger.Warnf("during check if %s for tx(%s), message %d was not mint msg", logInfo, txHash, i)
+		}
+	}
+
+	return mints
+}
+
+// Checking whether the address is the granter of the mints wrapped in MsgExec.
+func (rm *relayMinter) isMintGranter(address string) bool {
+	return (rm.config.HasAuthzMinter() && address == rm.config.AuthzMinter) || rm.isOwnWallet(address)
+}
+
+// Replacing the MsgExec messages of a transaction by the messages they wrap. The chain executes a wrapped message only
+// if its signer granted it, so the wrapped bank sends are checked by the callers as the plain ones.
+// An exec message that can not be unpacked is kept as it is.
+func flattenExecMsgs(msgs []sdk.Msg) []sdk.Msg {
+	flattened := []sdk.Msg{}
+
+	for _, msg := range msgs {
+		msgExec, ok := msg.(*authz.MsgExec)
+		if !ok {
+			flattened = append(flattened, msg)
+			continue
+		}
+
+		nestedMsgs, err := msgExec.GetMessages()
+		if err != nil {
+			flattened = append(flattened, msg)
+			continue
+		}
+
+		flattened = append(flattened, flattenExecMsgs(nestedMsgs)...)
+	}
+
+	return flattened
+}
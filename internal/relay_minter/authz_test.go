@@ -0,0 +1,112 @@
+package relayminter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+func TestShouldMintOnBehalfOfColdMinterInAuthzMode(t *testing.T) {
+	relayMinter, mts := newAuthzTestRelayMinter(t)
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Equal(t, []string{batchTxHash}, mts.outputMemos)
+	require.Len(t, mts.outputMsgs, 1)
+
+	msgExec, ok := mts.outputMsgs[0].(*authz.MsgExec)
+	require.True(t, ok)
+	require.Equal(t, relayMinter.walletAddress.String(), msgExec.Grantee)
+
+	nestedMsgs, err := msgExec.GetMessages()
+	require.NoError(t, err)
+	require.Len(t, nestedMsgs, 1)
+	require.Equal(t, coldMinterAddress, nestedMsgs[0].(*marketplacetypes.MsgMintNft).Creator)
+	require.Equal(t, "nftuid#1", nestedMsgs[0].(*marketplacetypes.MsgMintNft).Uid)
+}
+
+func TestShouldRecogniseMintsWrappedInExec(t *testing.T) {
+	relayMinter, _ := newAuthzTestRelayMinter(t)
+
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
+	relayMinter.txQuerier = newMockTxQuerier(nil, newAuthzMintTxs(t, relayMinter.walletAddress, coldMinterAddress), nil, false)
+	isMinted, err := relayMinter.isMintedNft(context.Background(), "nftuid#1", 0)
+	require.NoError(t, err)
+	require.True(t, isMinted)
+
+	isMintingTransaction, err := relayMinter.isMintingTransaction(context.Background(), refundReceiver, batchTxHash, 0)
+	require.NoError(t, err)
+	require.True(t, isMintingTransaction)
+}
+
+func TestShouldNotRecogniseWrappedMintsOfOtherGranteeOrGranter(t *testing.T) {
+	relayMinter, _ := newAuthzTestRelayMinter(t)
+
+	otherAddress, err := sdk.AccAddressFromBech32(treasuryAddress)
+	require.NoError(t, err)
+
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
+	relayMinter.txQuerier = newMockTxQuerier(nil, newAuthzMintTxs(t, otherAddress, coldMinterAddress), nil, false)
+	isMinted, err := relayMinter.isMintedNft(context.Background(), "nftuid#1", 0)
+	require.NoError(t, err)
+	require.False(t, isMinted)
+
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
+	relayMinter.txQuerier = newMockTxQuerier(nil, newAuthzMintTxs(t, relayMinter.walletAddress, treasuryAddress), nil, false)
+	isMinted, err = relayMinter.isMintedNft(context.Background(), "nftuid#1", 0)
+	require.NoError(t, err)
+	require.False(t, isMinted)
+}
+
+func TestShouldRecogniseRefundsWrappedInExec(t *testing.T) {
+	relayMinter, _ := newAuthzTestRelayMinter(t)
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	buyer, err := sdk.AccAddressFromBech32(refundReceiver)
+	require.NoError(t, err)
+
+	msgExec := authz.NewMsgExec(relayMinter.walletAddress, []sdk.Msg{
+		banktypes.NewMsgSend(relayMinter.walletAddress, buyer, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000)))),
+	})
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
+	relayMinter.txQuerier = newMockTxQuerier(nil, nil, buildTestResultTxSearch(t, [][]sdk.Msg{{&msgExec}}, []string{batchTxHash}, &encodingConfig, batchTxHash), false)
+
+	isRefunded, err := relayMinter.isRefunded(context.Background(), batchTxHash, 0, refundReceiver)
+	require.NoError(t, err)
+	require.True(t, isRefunded)
+}
+
+func TestShouldFailWithInvalidAuthzMinter(t *testing.T) {
+	relayMinter, _ := newAuthzTestRelayMinter(t)
+	require.NoError(t, relayMinter.validateAuthzMinter())
+
+	relayMinter.config.AuthzMinter = "invalid"
+	require.Error(t, relayMinter.validateAuthzMinter())
+}
+
+// Building the mint transactions of a payment executed by the grantee on behalf of the creator.
+func newAuthzMintTxs(t *testing.T, grantee sdk.AccAddress, creator string) *ctypes.ResultTxSearch {
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	msgExec := authz.NewMsgExec(grantee, []sdk.Msg{
+		marketplacetypes.NewMsgMintNft(creator, "testdenom", refundReceiver, "", "", "", "nftuid#1", sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000))),
+	})
+
+	return buildTestResultTxSearch(t, [][]sdk.Msg{{&msgExec}}, []string{batchTxHash}, &encodingConfig, batchTxHash)
+}
+
+// Building a relay minter in authz mode with a single incoming payment for an available NFT.
+func newAuthzTestRelayMinter(t *testing.T) (*relayMinter, *mockTxSender) {
+	built := newTestRelayMinter(t, testRelayMinterOptions{cfg: config.Config{AuthzMinter: coldMinterAddress}})
+	return built.relayMinter, built.txSender
+}
+
+const coldMinterAddress = "cudos1vdhkcepdd45kuar9wgkkzcmrda6kuap322ttdm"
@@ -0,0 +1,108 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Checking the balances of all wallets that sign transactions, because every one of them pays the fees of its transactions.
+// A wallet below the warning threshold is reported by an alert. If any wallet is below the critical threshold then relaying is paused,
+// so the payments are not failed by transactions the wallet can not pay for. They are processed once the wallet is topped up.
+// Alerts are sent only when the level of a wallet or the pause changes, not on every tick.
+func (rm *relayMinter) checkBalances(ctx context.Context) (bool, error) {
+	warningThreshold, err := parseBalanceThreshold(rm.config.BalanceWarningThreshold)
+	if err != nil {
+		return false, fmt.Errorf("invalid balance warning threshold: %s", err)
+	}
+
+	criticalThreshold, err := parseBalanceThreshold(rm.config.BalanceCriticalThreshold)
+	if err != nil {
+		return false, fmt.Errorf("invalid balance critical threshold: %s", err)
+	}
+
+	if warningThreshold.IsNil() && criticalThreshold.IsNil() {
+		return false, nil
+	}
+
+	if rm.balanceLevels == nil {
+		rm.balanceLevels = map[string]balanceLevel{}
+	}
+
+	paused := false
+	for _, address := range rm.signingAddresses() {
+		balance, err := rm.balanceQuerier.QueryBalance(ctx, address, rm.config.PaymentDenom)
+		if err != nil {
+			return false, fmt.Errorf("querying balance of wallet (%s) failed: %s", address, err)
+		}
+
+		level := balanceLevelOk
+		if !criticalThreshold.IsNil() && balance.LT(criticalThreshold) {
+			level = balanceLevelCritical
+			paused = true
+		} else if !warningThreshold.IsNil() && balance.LT(warningThreshold) {
+			level = balanceLevelWarning
+		}
+
+		if level != rm.balanceLevels[address] {
+			rm.reportBalanceLevel(address, balance, level)
+			rm.balanceLevels[address] = level
+		}
+	}
+
+	if paused != rm.paused {
+		message := "balances of all wallets are above the critical threshold, resuming minting and refunding"
+		if paused {
+			message = "balance of a wallet is below the critical threshold, pausing minting and refunding until it is topped up"
+		}
+
+		rm.logger.Warn(message)
+		rm.emailService.SendEmail(message)
+		rm.paused = paused
+	}
+
+	return paused, nil
+}
+
+func (rm *relayMinter) reportBalanceLevel(address string, balance sdk.Int, level balanceLevel) {
+	switch level {
+	case balanceLevelCritical:
+		message := fmt.Sprintf("balance (%s%s) of wallet (%s) is below the critical threshold (%s%s)", balance.String(), rm.config.PaymentDenom, address, rm.config.BalanceCriticalThreshold, rm.config.PaymentDenom)
+		rm.logger.Warn(message)
+		rm.emailService.SendEmail(message)
+	case balanceLevelWarning:
+		message := fmt.Sprintf("balance (%s%s) of wallet (%s) is below the warning threshold (%s%s)", balance.String(), rm.config.PaymentDenom, address, rm.config.BalanceWarningThreshold, rm.config.PaymentDenom)
+		rm.logger.Warn(message)
+		rm.emailService.SendEmail(message)
+	default:
+		rm.logger.Infof("balance (%s%s) of wallet (%s) is restored", balance.String(), rm.config.PaymentDenom, address)
+	}
+}
+
+// The addresses of the wallets that sign transactions, i.e. the wallets of the pool and the retired wallets refunding their payments.
+func (rm *relayMinter) signingAddresses() []string {
+	return append(rm.walletAddresses(), rm.retiredAddresses()...)
+}
+
+// Parsing a balance threshold. An empty threshold is disabled and returned as nil.
+func parseBalanceThreshold(threshold string) (sdk.Int, error) {
+	if threshold == "" {
+		return sdk.Int{}, nil
+	}
+
+	amount, ok := sdk.NewIntFromString(threshold)
+	if !ok {
+		return sdk.Int{}, fmt.Errorf("(%s) is not an amount", threshold)
+	}
+
+	return amount, nil
+}
+
+type balanceLevel int
+
+const (
+	balanceLevelOk balanceLevel = iota
+	balanceLevelWarning
+	balanceLevelCritical
+)
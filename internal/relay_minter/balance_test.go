@@ -0,0 +1,63 @@
+package relayminter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldAlertOnceIfBalanceBelowWarningThreshold(t *testing.T) {
+	relayMinter, mts, emailService := newBalanceTestRelayMinter(t, sdk.NewInt(500))
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Len(t, mts.outputMsgs, 1)
+
+	paused, err := relayMinter.checkBalances(context.Background())
+	require.NoError(t, err)
+	require.False(t, paused)
+	require.Equal(t, []string{
+		"balance (500acudos) of wallet (" + relayMinter.walletAddress.String() + ") is below the warning threshold (1000acudos)",
+	}, emailService.emails)
+}
+
+func TestShouldPauseIfBalanceBelowCriticalThresholdAndResumeWhenToppedUp(t *testing.T) {
+	relayMinter, mts, emailService := newBalanceTestRelayMinter(t, sdk.NewInt(50))
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+	require.Equal(t, int64(0), relayMinter.stateStorage.(*mockState).state.Height)
+	require.Len(t, emailService.emails, 2)
+
+	relayMinter.balanceQuerier = newMockBalanceQuerier(sdk.NewInt(5000))
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Len(t, mts.outputMsgs, 1)
+	require.Equal(t, "balances of all wallets are above the critical threshold, resuming minting and refunding", emailService.emails[2])
+}
+
+func TestShouldNotCheckBalanceWithoutThresholds(t *testing.T) {
+	relayMinter, _, _ := newBalanceTestRelayMinter(t, sdk.ZeroInt())
+	relayMinter.config.BalanceWarningThreshold = ""
+	relayMinter.config.BalanceCriticalThreshold = ""
+	relayMinter.balanceQuerier = nil
+
+	paused, err := relayMinter.checkBalances(context.Background())
+	require.NoError(t, err)
+	require.False(t, paused)
+}
+
+func TestShouldFailIfInvalidBalanceThreshold(t *testing.T) {
+	relayMinter, _, _ := newBalanceTestRelayMinter(t, sdk.ZeroInt())
+	relayMinter.config.BalanceCriticalThreshold = "invalid"
+
+	_, err := relayMinter.checkBalances(context.Background())
+	require.Error(t, err)
+}
+
+// Building a relay minter with balance thresholds of 1000 and 100 and a single incoming payment for an available NFT.
+func newBalanceTestRelayMinter(t *testing.T, balance sdk.Int) (*relayMinter, *mockTxSender, *mockEmailService) {
+	built := newTestRelayMinter(t, testRelayMinterOptions{cfg: config.Config{BalanceWarningThreshold: "1000", BalanceCriticalThreshold: "100"}, balance: balance})
+	return built.relayMinter, built.txSender, built.emailService
+}
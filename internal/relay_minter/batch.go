@@ -0,0 +1,377 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func newTxBatch() *txBatch {
+	return &txBatch{
+		mints:   []batchItem{},
+		refunds: []batchItem{},
+		uids:    map[string]bool{},
+	}
+}
+
+// Checking whether the NFT is going to be minted by the batch, so a second payment for it in the same tick is refunded.
+func (b *txBatch) hasMint(uid string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.uids[uid]
+}
+
+func (b *txBatch) addMint(uid string, item batchItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.mints = append(b.mints, item)
+	b.uids[uid] = true
+}
+
+func (b *txBatch) addRefund(item batchItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refunds = append(b.refunds, item)
+}
+
+// Minting the NFT of a single NFT payment if its price is valid at the given time. When batching the mint is queued and sent at the end of the relay tick.
+func (rm *relayMinter) mintPayment(ctx context.Context, sendInfo receivedBankSend, nftData model.NFTData, validAt time.Time) error {
+	if err := validateNftData(nftData.Id, nftData, validAt); err != nil {
+		return err
+	}
+
+	decision := newDecision(sendInfo, model.ReasonPaid, []string{nftData.Id})
+	decision.Nfts = []model.NFTData{nftData}
+	if rm.batch == nil {
+		return rm.mint(ctx, sendInfo.Ref(), nftData.Id, sendInfo.Memo.RecipientAddress, nftData, sendInfo.Amount, decision)
+	}
+
+	wallet := rm.nextWallet()
+	msgMintNft, gasResult, err := rm.prepareMint(ctx, wallet, nftData.Id, sendInfo.Memo.RecipientAddress, nftData, sendInfo.Amount)
+	if err != nil {
+		return err
+	}
+
+	rm.batch.addMint(nftData.Id, batchItem{
+		wallet:         wallet,
+		memo:           outgoingMemo{TxHash: sendInfo.Ref()},
+		msg:            msgMintNft,
+		gasResult:      gasResult,
+		amount:         sendInfo.Amount,
+		receivedBy:     sendInfo.ToAddress,
+		refundReceiver: sendInfo.FromAddress,
+		decision:       decision,
+	})
+
+	rm.logger.Infof("queued mint of NFT(%s) for incomingPaymentTxHash(%s)", nftData.Id, sendInfo.Ref())
+	return nil
+}
+
+// Refunding a single NFT payment for the given reason. When batching the refund is queued and sent at the end of the relay tick.
+// The NFT data the refund is based on, if any, is logged with the decision.
+func (rm *relayMinter) refundPayment(ctx context.Context, sendInfo receivedBankSend, reason, detail string, nftsData []model.NFTData) error {
+	decision := newDecision(sendInfo, reason, nil)
+	decision.Detail = detail
+	decision.Nfts = nftsData
+	if rm.batch == nil {
+		return rm.refund(ctx, sendInfo.Ref(), sendInfo.ToAddress, sendInfo.FromAddress, sendInfo.Amount, decision)
+	}
+
+	wallet := rm.refundWallet(sendInfo.ToAddress)
+	msgSend, gasResult, err := rm.prepareRefund(ctx, wallet, sendInfo.Ref(), sendInfo.FromAddress, sendInfo.Amount)
+	if err != nil {
+		return err
+	}
+
+	if msgSend == nil {
+		rm.recordDecision(belowMinimumRefund(decision), model.DecisionSkip)
+		return nil
+	}
+
+	rm.batch.addRefund(batchItem{
+		wallet:         wallet,
+		memo:           outgoingMemo{TxHash: sendInfo.Ref()},
+		msg:            msgSend,
+		gasResult:      gasResult,
+		amount:         sendInfo.Amount,
+		receivedBy:     sendInfo.ToAddress,
+		refundReceiver: sendInfo.FromAddress,
+		decision:       decision,
+	})
+
+	rm.logger.Infof("queued refund of incomingPaymentTxHash(%s) to address(%s)", sendInfo.Ref(), sendInfo.FromAddress)
+	return nil
+}
+
+// Sending the mints and refunds collected during the relay tick.
+// Mints and refunds are never mixed in a single transaction, so a payment referenced by the memo of a batch transaction is either minted or refunded by it.
+// The items of every wallet are packed in batches up to the batch gas limit and the maximum memo length.
+func (rm *relayMinter) flushBatch(ctx context.Context) error {
+	for _, walletItems := range groupBatchItemsByWallet(rm.batch.mints) {
+		for _, items := range packBatchItems(walletItems, uint64(rm.config.BatchGasLimit)) {
+			if err := rm.sendMintBatch(ctx, items); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, walletItems := range groupBatchItemsByWallet(rm.batch.refunds) {
+		for _, items := range packBatchItems(walletItems, uint64(rm.config.BatchGasLimit)) {
+			if err := rm.sendRefundBatch(ctx, items); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Sending mints in a single transaction. If the simulation of the batch fails then every mint is sent in its own transaction.
+func (rm *relayMinter) sendMintBatch(ctx context.Context, items []batchItem) error {
+	if len(items) == 1 {
+		return rm.sendSingleMint(ctx, items[0])
+	}
+
+	memo, msgs, err := batchTx(items)
+	if err != nil {
+		return err
+	}
+
+	gasResult, err := items[0].wallet.txSender.EstimateGas(ctx, msgs, memo)
+	if err != nil {
+		rm.logger.Warnf("simulation of batch mint of %d payments failed, sending them one by one: %s", len(items), err)
+		for _, item := range items {
+			if err := rm.sendSingleMint(ctx, item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	txHash, err := items[0].wallet.txSender.SendTx(ctx, msgs, memo, gasResult)
+	if err != nil {
+		return fmt.Errorf("sending batch mint of %d payments failed: %s", len(items), err)
+	}
+
+	rm.logger.Infof("success batch mint tx %s with memo %s", txHash, memo)
+	for _, item := range items {
+		rm.recordDecision(sentDecision(item.decision, txHash, gasResult), model.DecisionMint)
+	}
+	return nil
+}
+
+// Sending a queued mint in its own transaction. The payment is refunded if the mint is not successful.
+func (rm *relayMinter) sendSingleMint(ctx context.Context, item batchItem) error {
+	txHash, errMint := item.wallet.txSender.SendTx(ctx, []sdk.Msg{item.msg}, item.memo.TxHash, item.gasResult)
+	if errMint != nil {
+		errMint = fmt.Errorf("failed to mint: %s", errMint)
+		rm.logger.Warnf("minting failed from address(%s) with tx incomingPaymentTxHash(%s) with error[%v]", item.refundReceiver, item.memo.TxHash, errMint)
+		decision := item.decision
+		decision.Reason = model.ReasonMintFailed
+		decision.Detail = errMint.Error()
+		if errRefund := rm.refund(ctx, item.memo.TxHash, item.receivedBy, item.refundReceiver, item.amount, decision); errRefund != nil {
+			return fmt.Errorf("%s, failed to refund after unsuccessful minting: %s", errMint, errRefund)
+		}
+
+		return nil
+	}
+
+	rm.logger.Infof("success mint tx %s", txHash)
+	rm.recordDecision(sentDecision(item.decision, txHash, item.gasResult), model.DecisionMint)
+	return nil
+}
+
+// Sending refunds in a single transaction. If the simulation of the batch fails then every refund is sent in its own transaction.
+// The fee of the batch is split across the refunded payments proportionally to the gas of their single refunds.
+func (rm *relayMinter) sendRefundBatch(ctx context.Context, items []batchItem) error {
+	if len(items) == 1 {
+		return rm.sendSingleRefund(ctx, items[0])
+	}
+
+	memo, msgs, err := batchTx(items)
+	if err != nil {
+		return err
+	}
+
+	gasResult, err := items[0].wallet.txSender.EstimateGas(ctx, msgs, memo)
+	if err == nil {
+		msgs, err = rm.splitRefundFee(items, gasResult)
+	}
+
+	if err != nil {
+		rm.logger.Warnf("simulation of batch refund of %d payments failed, sending them one by one: %s", len(items), err)
+		for _, item := range items {
+			if err := rm.sendSingleRefund(ctx, item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	refundTxHash, err := items[0].wallet.txSender.SendTx(ctx, msgs, memo, gasResult)
+	if err != nil {
+		return fmt.Errorf("sending batch refund of %d payments failed: %s", len(items), err)
+	}
+
+	rm.logger.Infof("successfull batch refund with memo %s with refund tx hash(%s)", memo, refundTxHash)
+	// The bank sends are in the order of the items
+	for i, item := range items {
+		decision := item.decision
+		decision.Refunded = refundedAmount(msgs[i])
+		rm.recordDecision(sentDecision(decision, refundTxHash, gasResult), model.DecisionRefund)
+	}
+	return nil
+}
+
+// Sending a queued refund in its own transaction.
+func (rm *relayMinter) sendSingleRefund(ctx context.Context, item batchItem) error {
+	refundTxHash, err := item.wallet.txSender.SendTx(ctx, []sdk.Msg{item.msg}, item.memo.TxHash, item.gasResult)
+	if err != nil {
+		return err
+	}
+
+	rm.logger.Info(fmt.Sprintf("successfull refund incomingPaymentTxHash(%s) to address(%s) with refund tx hash(%s)", item.memo.TxHash, item.refundReceiver, refundTxHash))
+	decision := item.decision
+	decision.Refunded = refundedAmount(item.msg)
+	rm.recordDecision(sentDecision(decision, refundTxHash, item.gasResult), model.DecisionRefund)
+	return nil
+}
+
+// Building the bank sends of a batch refund, each one deducting the share of the batch fee of its payment.
+// The share is proportional to the gas of the single refund of the payment and the last payment covers the rounding remainder.
+func (rm *relayMinter) splitRefundFee(items []batchItem, gasResult model.GasResult) ([]sdk.Msg, error) {
+	fee := sdk.NewIntFromUint64(gasResult.GasLimit).Mul(sdk.NewIntFromUint64(gasPrice))
+
+	totalGas := sdk.ZeroInt()
+	for _, item := range items {
+		totalGas = totalGas.Add(sdk.NewIntFromUint64(item.gasResult.GasLimit))
+	}
+
+	msgs := []sdk.Msg{}
+	remainingFee := fee
+	for i, item := range items {
+		share := remainingFee
+		if i < len(items)-1 {
+			share = fee.Mul(sdk.NewIntFromUint64(item.gasResult.GasLimit)).Quo(totalGas)
+		}
+		remainingFee = remainingFee.Sub(share)
+
+		refundAmount := item.amount.Amount.Sub(share)
+		if !refundAmount.IsPositive() {
+			return nil, fmt.Errorf("fee share (%s) of incomingPaymentTxHash(%s) exceeds its amount (%s)", share.String(), item.memo.TxHash, item.amount.Amount.String())
+		}
+
+		msgSend, ok := item.msg.(*banktypes.MsgSend)
+		if !ok {
+			return nil, fmt.Errorf("queued refund of incomingPaymentTxHash(%s) is not a bank send", item.memo.TxHash)
+		}
+
+		msgs = append(msgs, &banktypes.MsgSend{
+			FromAddress: msgSend.FromAddress,
+			ToAddress:   msgSend.ToAddress,
+			Amount:      sdk.NewCoins(sdk.NewCoin(rm.config.PaymentDenom, refundAmount)),
+		})
+	}
+
+	return msgs, nil
+}
+
+// Packing the queued items in batches that fit in the gas limit and whose references fit in a memo.
+func packBatchItems(items []batchItem, gasLimit uint64) [][]batchItem {
+	batches := [][]batchItem{}
+	current := []batchItem{}
+	currentGas := uint64(0)
+
+	for _, item := range items {
+		if len(current) > 0 {
+			_, errMemo := encodeBatchMemo(batchMemos(append(append([]batchItem{}, current...), item)))
+			if currentGas+item.gasResult.GasLimit > gasLimit || errMemo != nil {
+				batches = append(batches, current)
+				current = []batchItem{}
+				currentGas = 0
+			}
+		}
+
+		current = append(current, item)
+		currentGas += item.gasResult.GasLimit
+	}
+
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+// Grouping the queued items by the wallet that signs them, keeping their order.
+func groupBatchItemsByWallet(items []batchItem) [][]batchItem {
+	groups := [][]batchItem{}
+	groupIdx := map[string]int{}
+	for _, item := range items {
+		idx, ok := groupIdx[item.wallet.address.String()]
+		if !ok {
+			idx = len(groups)
+			groupIdx[item.wallet.address.String()] = idx
+			groups = append(groups, []batchItem{})
+		}
+		groups[idx] = append(groups[idx], item)
+	}
+
+	return groups
+}
+
+func batchTx(items []batchItem) (string, []sdk.Msg, error) {
+	memo, err := encodeBatchMemo(batchMemos(items))
+	if err != nil {
+		return "", nil, err
+	}
+
+	msgs := []sdk.Msg{}
+	for _, item := range items {
+		msgs = append(msgs, item.msg)
+	}
+
+	return memo, msgs, nil
+}
+
+func batchMemos(items []batchItem) []outgoingMemo {
+	memos := []outgoingMemo{}
+	for _, item := range items {
+		memos = append(memos, item.memo)
+	}
+
+	return memos
+}
+
+// The mints and refunds of single NFT payments collected during a relay tick.
+// The payments are processed by multiple workers, so the batch is guarded by a mutex.
+type txBatch struct {
+	mu      sync.Mutex
+	mints   []batchItem
+	refunds []batchItem
+	uids    map[string]bool
+}
+
+// A queued mint or refund together with its single transaction gas, so it can be sent on its own if the batch fails.
+type batchItem struct {
+	wallet    *minterWallet
+	memo      outgoingMemo
+	msg       sdk.Msg
+	gasResult model.GasResult
+	amount    sdk.Coin
+	// The wallet that received the payment
+	receivedBy     string
+	refundReceiver string
+	// The decision logged once the item is sent
+	decision model.Decision
+}
@@ -0,0 +1,117 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldSendRefundsInBatchAndSplitTheFee(t *testing.T) {
+	relayMinter, buyer, mts := newBatchTestRelayMinter(t, "notfoundnftuid", false)
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Equal(t, []string{"[\"" + batchTxHash + "\",\"" + batchTxHash + "#1\"]"}, mts.outputMemos)
+	require.Equal(t, []sdk.Msg{
+		banktypes.NewMsgSend(relayMinter.walletAddress, buyer, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000).Sub(sdk.NewIntFromUint64(2502500000000000))))),
+		banktypes.NewMsgSend(relayMinter.walletAddress, buyer, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000).Sub(sdk.NewIntFromUint64(2502500000000000))))),
+	}, mts.outputMsgs)
+}
+
+func TestShouldSendBatchItemsOneByOneIfBatchSimulationFails(t *testing.T) {
+	relayMinter, buyer, mts := newBatchTestRelayMinter(t, "notfoundnftuid", true)
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Equal(t, []string{batchTxHash, batchTxHash + "#1"}, mts.outputMemos)
+	require.Equal(t, []sdk.Msg{
+		banktypes.NewMsgSend(relayMinter.walletAddress, buyer, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+		banktypes.NewMsgSend(relayMinter.walletAddress, buyer, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+	}, mts.outputMsgs)
+}
+
+func TestShouldRefundSecondPaymentForNftQueuedInBatch(t *testing.T) {
+	relayMinter, buyer, mts := newBatchTestRelayMinter(t, "nftuid#1", false)
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Equal(t, []string{batchTxHash, batchTxHash + "#1"}, mts.outputMemos)
+	require.Len(t, mts.outputMsgs, 2)
+	require.Equal(t, "nftuid#1", mts.outputMsgs[0].(*marketplacetypes.MsgMintNft).Uid)
+	require.Equal(t, banktypes.NewMsgSend(relayMinter.walletAddress, buyer, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))), mts.outputMsgs[1])
+}
+
+func TestShouldPackBatchItemsWithinGasLimitAndMemoLength(t *testing.T) {
+	item := func(ref string) batchItem {
+		return batchItem{memo: outgoingMemo{TxHash: ref}, gasResult: model.GasResult{GasLimit: 100}}
+	}
+
+	batches := packBatchItems([]batchItem{item("A"), item("B"), item("C")}, 200)
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0], 2)
+	require.Len(t, batches[1], 1)
+
+	hash := "0123456789012345678901234567890123456789012345678901234567890123"
+	items := []batchItem{}
+	for i := 0; i < 14; i++ {
+		items = append(items, item(hash))
+	}
+	batches = packBatchItems(items, 10000)
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0], 13)
+}
+
+func TestShouldSplitBatchOfMorePaymentsThanFitInMemo(t *testing.T) {
+	relayMinter, buyer, mts := newBatchTestRelayMinter(t, "notfoundnftuid", false)
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	msgs := []sdk.Msg{}
+	for i := 0; i < 14; i++ {
+		msgs = append(msgs, banktypes.NewMsgSend(buyer, relayMinter.walletAddress, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000)))))
+	}
+	hash := strings.Repeat("AB", 32)
+	relayMinter.txQuerier = newMockTxQuerier(buildTestResultTxSearch(t, [][]sdk.Msg{msgs}, []string{"{\"uuid\":\"notfoundnftuid\"}"}, &encodingConfig, hash), nil, nil, false)
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Len(t, mts.outputMsgs, 14)
+
+	// The short references of twelve payments fit in the memo, the rest go in the next batch
+	refs := [][]string{}
+	for _, memo := range mts.outputMemos {
+		require.LessOrEqual(t, len(memo), maxMemoCharacters)
+
+		batchRefs := []string{}
+		for _, decoded := range decodeOutgoingMemos(memo) {
+			batchRefs = append(batchRefs, decoded.TxHash)
+		}
+		refs = append(refs, batchRefs)
+	}
+	short := hash[:shortRefHashCharacters]
+	expectedRefs := [][]string{{short}, {}}
+	for i := 1; i < 14; i++ {
+		batch := i / 12
+		expectedRefs[batch] = append(expectedRefs[batch], fmt.Sprintf("%s#%d", short, i))
+	}
+	require.Equal(t, expectedRefs, refs)
+
+	memo, ok := findOutgoingMemo(mts.outputMemos[1], hash+"#13")
+	require.True(t, ok)
+	require.Equal(t, short+"#13", memo.TxHash)
+}
+
+// Building a relay minter in batch mode with a single incoming transaction that holds two payments of the buyer for the same NFT.
+func newBatchTestRelayMinter(t *testing.T, uid string, failBatchEstimateGas bool) (*relayMinter, sdk.AccAddress, *mockTxSender) {
+	built := newTestRelayMinter(t, testRelayMinterOptions{
+		cfg:        config.Config{BatchTxs: 1, BatchGasLimit: 2000000},
+		paymentTxs: []testPaymentTx{{memo: "{\"uuid\":\"" + uid + "\"}", amounts: []uint64{9000000000000000000, 9000000000000000000}}},
+	})
+	built.txSender.failBatchEstimateGas = failBatchEstimateGas
+
+	return built.relayMinter, built.buyer, built.txSender
+}
@@ -0,0 +1,298 @@
+package relayminter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Processing a payment that covers multiple NFTs.
+//
+// 1. Finding the items of the cart that have already been minted or refunded by previous runs, together with the funds already spent on them.
+// If all items are handled then no further processing is required.
+//
+// 2. Getting NFT's information from the AuraPool for every pending item and checking whether it can be minted.
+//
+// 3. If some of the items are not available and the cart policy is all or nothing then the whole cart is refunded.
+//
+// 4. Minting all available items in a single transaction. If minting is not successful then all pending items are considered unavailable.
+//
+// 5. Refunding the unavailable items with the rest of the funds.
+//
+// The memo of every mint and refund transaction lists the positions of the items it is for, so a partially processed cart can be resumed.
+func (rm *relayMinter) processCart(ctx context.Context, sendInfo receivedBankSend, incomingPaymentTxHeight int64) error {
+	incomingPaymentTxHash := sendInfo.Ref()
+
+	progress, err := rm.getCartProgress(ctx, sendInfo, incomingPaymentTxHeight)
+	if err != nil {
+		return err
+	}
+
+	if progress.refundedAll {
+		rm.logger.Infof("transaction(%s) has already been refunded to buyer(%s)", incomingPaymentTxHash, sendInfo.FromAddress)
+		rm.recordDecision(newDecision(sendInfo, model.ReasonAlreadyRefunded, nil), model.DecisionSkip)
+		return nil
+	}
+
+	pending := []string{}
+	for _, uid := range sendInfo.Memo.UIDs {
+		if !progress.minted[uid] && !progress.refunded[uid] {
+			pending = append(pending, uid)
+		}
+	}
+
+	if len(pending) == 0 {
+		rm.logger.Infof("transaction(%s) has already been successfully processed for all cart items of buyer (%s)", incomingPaymentTxHash, sendInfo.Memo.RecipientAddress)
+		rm.recordDecision(newDecision(sendInfo, model.ReasonAlreadyProcessed, nil), model.DecisionSkip)
+		return nil
+	}
+
+	budget := sendInfo.Amount.Amount.Sub(progress.spent)
+	if !budget.IsPositive() {
+		rm.logger.Warnf("transaction(%s) has no funds left for cart items %v", incomingPaymentTxHash, pending)
+		rm.recordDecision(newDecision(sendInfo, model.ReasonCartNoFunds, pending), model.DecisionSkip)
+		return nil
+	}
+
+	if rm.refundsRetiredPayment(sendInfo) {
+		rm.logger.Infof("refunding cart items %v of transaction(%s) received by retired wallet (%s)", pending, incomingPaymentTxHash, sendInfo.ToAddress)
+		return rm.refundCartItems(ctx, incomingPaymentTxHash, pending, sendInfo, budget, model.ReasonRetiredWallet, "", nil)
+	}
+
+	if rm.config.MaxCartItems > 0 && len(sendInfo.Memo.UIDs) > rm.config.MaxCartItems {
+		rm.logger.Warnf("cart of transaction(%s) has %d items which is more than the maximum of %d", incomingPaymentTxHash, len(sendInfo.Memo.UIDs), rm.config.MaxCartItems)
+		return rm.refundCartItems(ctx, incomingPaymentTxHash, nil, sendInfo, budget, model.ReasonCartTooLarge, "", nil)
+	}
+
+	if hasDuplicates(sendInfo.Memo.UIDs) {
+		rm.logger.Warnf("cart of transaction(%s) has duplicated items", incomingPaymentTxHash)
+		return rm.refundCartItems(ctx, incomingPaymentTxHash, nil, sendInfo, budget, model.ReasonCartDuplicateItems, "", nil)
+	}
+
+	if _, err := encodeOutgoingMemo(newCartMemo(incomingPaymentTxHash, sendInfo.Memo.UIDs, pending)); err != nil {
+		rm.logger.Warnf("cart items of transaction(%s) can not be listed in a memo: %s", incomingPaymentTxHash, err)
+		return rm.refundCartItems(ctx, incomingPaymentTxHash, nil, sendInfo, budget, model.ReasonCartMemoTooLong, err.Error(), nil)
+	}
+
+	paidAt, err := rm.blockQuerier.BlockTime(ctx, incomingPaymentTxHeight)
+	if err != nil {
+		return err
+	}
+	sendInfo.PaidAt = paidAt
+
+	onCudos, _ := sdk.NewIntFromString("1000000000000000000")
+	paidAmount := sdk.NewCoin(sendInfo.Amount.Denom, sdk.ZeroInt())
+	if budget.GT(onCudos) {
+		paidAmount = sdk.NewCoin(sendInfo.Amount.Denom, budget.Sub(onCudos))
+	}
+
+	available := []model.NFTData{}
+	unavailable := []string{}
+	// The NFT data of every pending item, logged with the decisions
+	fetched := []model.NFTData{}
+	for _, uid := range pending {
+		nftData, err := rm.GetNFTData(ctx, rm.config, uid, sendInfo.Memo.RecipientAddress, paidAmount, paidAt)
+		var untrustedErr *model.UntrustedQuoteError
+		if errors.As(err, &untrustedErr) {
+			rm.logger.Warnf("cart item (%s) of transaction(%s) has untrusted quote of the AuraPool: %s", uid, incomingPaymentTxHash, err)
+			unavailable = append(unavailable, uid)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		rm.logger.Infof("NFT Data(%s)", nftData.String())
+		fetched = append(fetched, nftData)
+
+		if err := validateNftData(uid, nftData, rm.priceValidAt(paidAt)); err != nil {
+			rm.logger.Warnf("cart item (%s) of transaction(%s) is not available: %s", uid, incomingPaymentTxHash, err)
+			unavailable = append(unavailable, uid)
+			continue
+		}
+
+		isMintedNft, err := rm.isMintedNft(ctx, nftData.Id, incomingPaymentTxHeight)
+		if err != nil {
+			return err
+		}
+
+		if isMintedNft {
+			unavailable = append(unavailable, uid)
+			continue
+		}
+
+		available = append(available, nftData)
+	}
+
+	if len(unavailable) > 0 && rm.config.IsAllOrNothingCart() {
+		rm.logger.Infof("refunding all items of cart of transaction(%s) because items %v are not available", incomingPaymentTxHash, unavailable)
+		return rm.refundCartItems(ctx, incomingPaymentTxHash, pending, sendInfo, budget, model.ReasonCartUnavailableItems, fmt.Sprintf("items %v are not available", unavailable), fetched)
+	}
+
+	if len(available) > 0 {
+		spent, errMint := rm.mintCart(ctx, incomingPaymentTxHash, sendInfo.Memo.UIDs, sendInfo.Memo.RecipientAddress, available, budget, newDecision(sendInfo, model.ReasonPaid, nil))
+		if errMint != nil {
+			errMint = fmt.Errorf("failed to mint: %s", errMint)
+			rm.logger.Warnf("minting of cart items %v failed from address(%s) with tx incomingPaymentTxHash(%s) with error[%v]", pending, sendInfo.FromAddress, incomingPaymentTxHash, errMint)
+			if errRefund := rm.refundCartItems(ctx, incomingPaymentTxHash, pending, sendInfo, budget, model.ReasonMintFailed, errMint.Error(), fetched); errRefund != nil {
+				return fmt.Errorf("%s, failed to refund after unsuccessful minting: %s", errMint, errRefund)
+			}
+
+			return nil
+		}
+
+		budget = budget.Sub(spent)
+	}
+
+	if len(unavailable) > 0 {
+		if err := rm.refundCartItems(ctx, incomingPaymentTxHash, unavailable, sendInfo, budget, model.ReasonCartUnavailableItems, "", nftDataOf(fetched, unavailable)); err != nil {
+			return fmt.Errorf("%s, failed to refund unavailable cart items", err)
+		}
+	}
+
+	return nil
+}
+
+// Minting all available cart items in a single transaction.
+// The received funds must cover the total price of the items plus the gas. The funds spent by the transaction are returned.
+// The decision is logged for the minted items once the transaction is sent.
+func (rm *relayMinter) mintCart(ctx context.Context, incomingPaymentTxHash string, cart []string, recipient string, nftsData []model.NFTData, budget sdk.Int, decision model.Decision) (sdk.Int, error) {
+	wallet := rm.nextWallet()
+	uids := []string{}
+	msgs := []sdk.Msg{}
+	totalPrice := sdk.ZeroInt()
+	for _, nftData := range nftsData {
+		uids = append(uids, nftData.Id)
+		msgs = append(msgs, rm.newMintMsg(wallet, nftData.Id, recipient, nftData))
+		totalPrice = totalPrice.Add(nftData.Price)
+	}
+	msgs = rm.wrapMints(wallet, msgs)
+
+	memo, err := encodeOutgoingMemo(newCartMemo(incomingPaymentTxHash, cart, uids))
+	if err != nil {
+		return sdk.ZeroInt(), err
+	}
+
+	gasResult, err := wallet.txSender.EstimateGas(ctx, msgs, memo)
+	if err != nil {
+		return sdk.ZeroInt(), err
+	}
+
+	gas := sdk.NewIntFromUint64(gasResult.GasLimit).Mul(sdk.NewIntFromUint64(gasPrice))
+	total := totalPrice.Add(gas)
+	if total.GT(budget) {
+		return sdk.ZeroInt(), fmt.Errorf("during cart mint received amount (%s) is smaller than the total price with gas (%s)", budget.String(), total.String())
+	}
+
+	txHash, err := wallet.txSender.SendTx(ctx, msgs, memo, gasResult)
+	if err != nil {
+		return sdk.ZeroInt(), err
+	}
+
+	rm.logger.Infof("success cart mint tx %s of items %v", txHash, uids)
+	decision.Uids = uids
+	decision.Nfts = nftsData
+	rm.recordDecision(sentDecision(decision, txHash, gasResult), model.DecisionMint)
+	return total, nil
+}
+
+// Refunding cart items to the sender of the payment for the given reason. If no items are given then the refund is for the whole payment.
+// The NFT data the refund is based on is logged with the decision.
+func (rm *relayMinter) refundCartItems(ctx context.Context, incomingPaymentTxHash string, uids []string, sendInfo receivedBankSend, amount sdk.Int, reason, detail string, nftsData []model.NFTData) error {
+	memo, err := encodeOutgoingMemo(newCartMemo(incomingPaymentTxHash, sendInfo.Memo.UIDs, uids))
+	if err != nil {
+		return err
+	}
+
+	decision := newDecision(sendInfo, reason, uids)
+	decision.Detail = detail
+	decision.Nfts = nftsData
+	return rm.refund(ctx, memo, sendInfo.ToAddress, sendInfo.FromAddress, sdk.NewCoin(rm.config.PaymentDenom, amount), decision)
+}
+
+// Finding the cart items that have already been minted or refunded for a payment and the funds spent by these transactions.
+func (rm *relayMinter) getCartProgress(ctx context.Context, sendInfo receivedBankSend, incomingPaymentTxHeight int64) (cartProgress, error) {
+	incomingPaymentTxHash := sendInfo.Ref()
+	progress := cartProgress{
+		minted:   map[string]bool{},
+		refunded: map[string]bool{},
+		spent:    sdk.ZeroInt(),
+	}
+
+	mintTxs, err := rm.queryNftMintTransactionByBuyer(ctx, sendInfo.Memo.RecipientAddress, incomingPaymentTxHash, incomingPaymentTxHeight, "minting transaction")
+	if err != nil {
+		return cartProgress{}, err
+	}
+
+	for _, mintTx := range mintTxs {
+		if _, ok := findOutgoingMemo(mintTx.Memo, incomingPaymentTxHash); !ok {
+			continue
+		}
+
+		for _, mintMsg := range mintTx.MintMsgs {
+			progress.minted[mintMsg.Uid] = true
+			progress.spent = progress.spent.Add(mintMsg.Price.Amount)
+		}
+		progress.spent = progress.spent.Add(mintTx.Fee(rm.config.PaymentDenom))
+	}
+
+	refundTxs, err := rm.queryRefundTransactions(ctx, incomingPaymentTxHash, incomingPaymentTxHeight, sendInfo.FromAddress)
+	if err != nil {
+		return cartProgress{}, err
+	}
+
+	for _, refundTx := range refundTxs {
+		memo, _ := findOutgoingMemo(refundTx.Memo, incomingPaymentTxHash)
+		if memo.isForWholePayment() {
+			progress.refundedAll = true
+		}
+
+		for _, uid := range memo.cartUIDs(sendInfo.Memo.UIDs) {
+			progress.refunded[uid] = true
+		}
+
+		for _, bankSendMsg := range refundTx.BankSendMsgs {
+			progress.spent = progress.spent.Add(bankSendMsg.Amount.AmountOf(rm.config.PaymentDenom))
+		}
+		progress.spent = progress.spent.Add(refundTx.Fee(rm.config.PaymentDenom))
+	}
+
+	rm.logger.Infof("cart progress of %s: minted %d, refunded %d, spent %s", incomingPaymentTxHash, len(progress.minted), len(progress.refunded), progress.spent.String())
+	return progress, nil
+}
+
+func hasDuplicates(uids []string) bool {
+	seen := map[string]bool{}
+	for _, uid := range uids {
+		if seen[uid] {
+			return true
+		}
+		seen[uid] = true
+	}
+
+	return false
+}
+
+type cartProgress struct {
+	minted      map[string]bool
+	refunded    map[string]bool
+	refundedAll bool
+	spent       sdk.Int
+}
+
+// The NFT data of the given items.
+func nftDataOf(nftsData []model.NFTData, uids []string) []model.NFTData {
+	selected := []model.NFTData{}
+	for _, nftData := range nftsData {
+		for _, uid := range uids {
+			if nftData.Id == uid {
+				selected = append(selected, nftData)
+				break
+			}
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,126 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/state"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// Starting a decision about the payment with the given reason. Without items the decision is about all items of the payment.
+func newDecision(sendInfo receivedBankSend, reason string, uids []string) model.Decision {
+	if uids == nil {
+		uids = sendInfo.Memo.UIDs
+		if !sendInfo.Memo.IsCart() {
+			uids = []string{sendInfo.Memo.UID}
+		}
+	}
+
+	decision := model.Decision{
+		Ref:       sendInfo.Ref(),
+		Height:    sendInfo.Height,
+		Sender:    sendInfo.FromAddress,
+		Amount:    sendInfo.Amount,
+		Uids:      uids,
+		Recipient: sendInfo.Memo.RecipientAddress,
+		Reason:    reason,
+	}
+
+	if !sendInfo.PaidAt.IsZero() {
+		paidAt := sendInfo.PaidAt.UTC()
+		decision.PaidAt = &paidAt
+	}
+
+	return decision
+}
+
+// Completing the decision by the outgoing transaction once it is sent.
+func sentDecision(decision model.Decision, txHash string, gasResult model.GasResult) model.Decision {
+	decision.TxHash = txHash
+	decision.GasLimit = gasResult.GasLimit
+	decision.Fee = gasResult.FeeAmount
+	return decision
+}
+
+// Turning the decision to refund into a skip, because the refund without the gas is below the minimum refund amount.
+func belowMinimumRefund(decision model.Decision) model.Decision {
+	decision.Detail = fmt.Sprintf("refund because of %s is below the minimum refund amount", decision.Reason)
+	decision.Reason = model.ReasonRefundBelowMinimum
+	return decision
+}
+
+// The amount sent by the bank send of a refund.
+func refundedAmount(msg sdk.Msg) sdk.Coins {
+	if msgSend, ok := msg.(*banktypes.MsgSend); ok {
+		return msgSend.Amount
+	}
+
+	return nil
+}
+
+// Logging the decision with the given outcome. The outgoing transaction of a mint or a refund has already been sent,
+// so a failure to log it is reported but does not fail the payment, which would refund a minted NFT.
+func (rm *relayMinter) recordDecision(decision model.Decision, outcome string) {
+	decision.Outcome = outcome
+	decision.DecidedAt = time.Now().UTC()
+	rm.logger.Infof("decided to %s payment(%s) because of %s", decision.Outcome, decision.Ref, decision.Reason)
+
+	if rm.decisionLog == nil {
+		return
+	}
+
+	if err := rm.decisionLog.RecordDecision(decision); err != nil {
+		rm.logger.Error(fmt.Errorf("logging decision to %s payment(%s) failed: %s", decision.Outcome, decision.Ref, err))
+	}
+}
+
+// Opening the decision log. It is opened once, so after a reconnect the relayer keeps appending to the same log.
+func (rm *relayMinter) setUpDecisions() {
+	if rm.config.HasDecisionLog() && rm.decisionLog == nil {
+		rm.decisionLog = state.NewFileDecisionLog(rm.config.DecisionLogFile)
+	}
+}
+
+// In shadow mode wrapping the tx sender of a wallet by one that broadcasts nothing. The tx senders are wrapped when they are created,
+// so after a reconnect no wallet is left with a sender that broadcasts and none is wrapped twice.
+func (rm *relayMinter) shadowed(sender txSender, address string) txSender {
+	if !rm.config.HasShadowMode() {
+		return sender
+	}
+
+	return &shadowTxSender{txSender: sender, logger: rm.logger, address: address}
+}
+
+// Replacing the tx senders of all wallets by the ones returned by the wrap function.
+func (rm *relayMinter) wrapTxSenders(wrap func(sender txSender, address string) txSender) {
+	rm.sendersMu.Lock()
+	defer rm.sendersMu.Unlock()
+
+	rm.txSender = wrap(rm.txSender, rm.walletAddress.String())
+	for _, minter := range rm.minters {
+		minter.txSender = wrap(minter.txSender, minter.address.String())
+	}
+	for _, retired := range rm.retired {
+		retired.txSender = wrap(retired.txSender, retired.address.String())
+	}
+}
+
+// The gas is still estimated by the node, so a shadow instance refunds and mints the same amounts as a live one.
+func (s *shadowTxSender) EstimateGas(ctx context.Context, msgs []sdk.Msg, memo string) (model.GasResult, error) {
+	return s.txSender.EstimateGas(ctx, msgs, memo)
+}
+
+func (s *shadowTxSender) SendTx(ctx context.Context, msgs []sdk.Msg, memo string, gasResult model.GasResult) (string, error) {
+	s.logger.Infof("shadow mode, not sending transaction of wallet %s with %d messages and memo (%s)", s.address, len(msgs), memo)
+	return "", nil
+}
+
+type shadowTxSender struct {
+	txSender txSender
+	logger   relayLogger
+	address  string
+}
@@ -0,0 +1,163 @@
+package relayminter
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	ggrpc "google.golang.org/grpc"
+)
+
+func TestShouldLogMintDecision(t *testing.T) {
+	relayMinter, mts, decisionLog := newDecisionTestRelayMinter(t)
+	blockQuerier := relayMinter.blockQuerier.(*mockBlockQuerier)
+
+	_, err := relayMinter.reprocessPayments(context.Background(), relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults.Txs)
+	require.NoError(t, err)
+	require.Len(t, mts.outputMsgs, 1)
+
+	require.Len(t, decisionLog.decisions, 1)
+	decision := decisionLog.decisions[0]
+	require.Equal(t, model.DecisionMint, decision.Outcome)
+	require.Equal(t, model.ReasonPaid, decision.Reason)
+	require.Equal(t, batchTxHash, decision.Ref)
+	require.Equal(t, refundReceiver, decision.Sender)
+	require.Equal(t, []string{"nftuid#1"}, decision.Uids)
+	require.Equal(t, sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000)), decision.Amount)
+	require.Equal(t, int64(10), decision.Height)
+	require.Equal(t, blockQuerier.blockTime.UTC(), *decision.PaidAt)
+	require.False(t, decision.DecidedAt.IsZero())
+	require.Len(t, decision.Nfts, 1)
+	require.Equal(t, "nftuid#1", decision.Nfts[0].Id)
+	require.Equal(t, uint64(mockGasLimit), decision.GasLimit)
+	require.Equal(t, mockFeeAmount, decision.Fee)
+}
+
+func TestShouldLogSkipDecision(t *testing.T) {
+	relayMinter, mts, decisionLog := newDecisionTestRelayMinter(t)
+	relayMinter.txQuerier.(*mockTxQuerier).refundQueryResults = buildIndexTestTxs(t, 20, []sdk.Msg{newIndexTestRefund(t, relayMinter.walletAddress.String())}, batchTxHash)
+
+	_, err := relayMinter.reprocessPayments(context.Background(), relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults.Txs)
+	require.NoError(t, err)
+	require.Empty(t, mts.outputMsgs)
+
+	require.Len(t, decisionLog.decisions, 1)
+	require.Equal(t, model.DecisionSkip, decisionLog.decisions[0].Outcome)
+	require.Equal(t, model.ReasonAlreadyRefunded, decisionLog.decisions[0].Reason)
+	require.Empty(t, decisionLog.decisions[0].TxHash)
+	require.Nil(t, decisionLog.decisions[0].PaidAt)
+	require.Empty(t, decisionLog.decisions[0].Nfts)
+}
+
+func TestShouldLogRefundDecisionWithReason(t *testing.T) {
+	relayMinter, mts, decisionLog := newDecisionTestRelayMinter(t)
+	nftData := relayMinter.nftDataClient.(*mockTokenisedInfraClient).nftDataEntires["nftuid#1"]
+	nftData.Status = model.RejectedNFTStatus
+	relayMinter.nftDataClient.(*mockTokenisedInfraClient).nftDataEntires["nftuid#1"] = nftData
+
+	_, err := relayMinter.reprocessPayments(context.Background(), relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults.Txs)
+	require.NoError(t, err)
+	require.Len(t, mts.outputMsgs, 1)
+
+	require.Len(t, decisionLog.decisions, 1)
+	decision := decisionLog.decisions[0]
+	require.Equal(t, model.DecisionRefund, decision.Outcome)
+	require.Equal(t, model.ReasonMintFailed, decision.Reason)
+	require.Contains(t, decision.Detail, "failed to mint")
+	require.Len(t, decision.Refunded, 1)
+	require.True(t, decision.Refunded[0].Amount.LT(decision.Amount.Amount))
+	require.Equal(t, model.NFTStatus(model.RejectedNFTStatus), decision.Nfts[0].Status)
+	require.Equal(t, uint64(mockGasLimit), decision.GasLimit)
+}
+
+func TestShouldRefundPaymentWithUntrustedAuraPoolQuote(t *testing.T) {
+	relayMinter, mts, decisionLog := newDecisionTestRelayMinter(t)
+	relayMinter.nftDataClient.(*mockTokenisedInfraClient).getNftDataErrors = map[string]error{
+		"nftuid#1": &model.UntrustedQuoteError{Uid: "nftuid#1", Detail: "quote of nft (nftuid#1) has invalid signature"},
+	}
+
+	_, err := relayMinter.reprocessPayments(context.Background(), relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults.Txs)
+	require.NoError(t, err)
+	require.Len(t, mts.outputMsgs, 1)
+
+	require.Len(t, decisionLog.decisions, 1)
+	require.Equal(t, model.DecisionRefund, decisionLog.decisions[0].Outcome)
+	require.Equal(t, model.ReasonUntrustedAuraPoolQuote, decisionLog.decisions[0].Reason)
+	require.Equal(t, "quote of nft (nftuid#1) has invalid signature", decisionLog.decisions[0].Detail)
+}
+
+func TestShouldNotSendTxsInShadowMode(t *testing.T) {
+	relayMinter, mts, decisionLog := newDecisionTestRelayMinter(t)
+	relayMinter.config.ShadowMode = 1
+	relayMinter.txSender = relayMinter.shadowed(mts, relayMinter.walletAddress.String())
+
+	_, err := relayMinter.reprocessPayments(context.Background(), relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults.Txs)
+	require.NoError(t, err)
+	require.Empty(t, mts.outputMsgs)
+
+	require.Len(t, decisionLog.decisions, 1)
+	require.Equal(t, model.DecisionMint, decisionLog.decisions[0].Outcome)
+	require.Empty(t, decisionLog.decisions[0].TxHash)
+}
+
+func TestShouldWrapTxSendersInShadowModeOnceAcrossReconnects(t *testing.T) {
+	relayMinter, _, decisionLog := newDecisionTestRelayMinter(t)
+	relayMinter.config.ShadowMode = 1
+	relayMinter.config.DecisionLogFile = filepath.Join(t.TempDir(), "decisions.jsonl")
+
+	// Dialing is lazy, nothing is sent to the address
+	grpcConn, err := ggrpc.Dial("127.0.0.1:1", ggrpc.WithInsecure())
+	require.NoError(t, err)
+	defer grpcConn.Close()
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, relayMinter.connectWallets(context.Background(), grpcConn))
+		relayMinter.setUpDecisions()
+
+		shadow, ok := relayMinter.txSender.(*shadowTxSender)
+		require.True(t, ok)
+		_, wrappedTwice := shadow.txSender.(*shadowTxSender)
+		require.False(t, wrappedTwice)
+	}
+	require.Equal(t, decisionLog, relayMinter.decisionLog)
+}
+
+func TestShouldNotFailPaymentIfDecisionLogFails(t *testing.T) {
+	relayMinter, mts, decisionLog := newDecisionTestRelayMinter(t)
+	decisionLog.err = errors.New("failed to write")
+
+	_, err := relayMinter.reprocessPayments(context.Background(), relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults.Txs)
+	require.NoError(t, err)
+	require.Len(t, mts.outputMsgs, 1)
+}
+
+func newDecisionTestRelayMinter(t *testing.T) (*relayMinter, *mockTxSender, *mockDecisionLog) {
+	relayMinter, mts, _, _ := newReprocessTestRelayMinter(t)
+	decisionLog := &mockDecisionLog{}
+	relayMinter.decisionLog = decisionLog
+
+	return relayMinter, mts, decisionLog
+}
+
+func (mdl *mockDecisionLog) RecordDecision(decision model.Decision) error {
+	mdl.mu.Lock()
+	defer mdl.mu.Unlock()
+
+	if mdl.err != nil {
+		return mdl.err
+	}
+
+	mdl.decisions = append(mdl.decisions, decision)
+	return nil
+}
+
+type mockDecisionLog struct {
+	mu        sync.Mutex
+	decisions []model.Decision
+	err       error
+}
@@ -0,0 +1,90 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// Parsing the configured fee granter. No granter is returned if it is not configured, so the signing wallets pay their own fees.
+func (rm *relayMinter) feeGranterAddress() (sdk.AccAddress, error) {
+	if !rm.config.HasFeeGranter() {
+		return nil, nil
+	}
+
+	feeGranter, err := sdk.AccAddressFromBech32(rm.config.FeeGranter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee granter address (%s): %s", rm.config.FeeGranter, err)
+	}
+
+	return feeGranter, nil
+}
+
+// Checking that the fee granter has granted an allowance to every wallet that signs transactions, before anything is sent.
+// The allowance must not be expired, must allow all messages sent by the service and its spend limit must not be below the configured minimum.
+func (rm *relayMinter) checkFeeAllowances(ctx context.Context) error {
+	if rm.feeGranter == nil {
+		return nil
+	}
+
+	minAllowance, err := parseBalanceThreshold(rm.config.FeeGrantMinAllowance)
+	if err != nil {
+		return fmt.Errorf("invalid fee grant min allowance: %s", err)
+	}
+
+	for _, address := range rm.signingAddresses() {
+		allowance, err := rm.allowanceQuerier.QueryAllowance(ctx, rm.feeGranter.String(), address)
+		if err != nil {
+			return fmt.Errorf("querying fee allowance of wallet (%s) from granter (%s) failed: %s", address, rm.feeGranter.String(), err)
+		}
+
+		if allowance.Expiration != nil && !allowance.Expiration.After(time.Now()) {
+			return fmt.Errorf("fee allowance of wallet (%s) from granter (%s) expired at %s", address, rm.feeGranter.String(), allowance.Expiration.String())
+		}
+
+		if len(allowance.AllowedMessages) > 0 {
+			for _, msgType := range rm.sentMsgTypes() {
+				if !containsString(allowance.AllowedMessages, msgType) {
+					return fmt.Errorf("fee allowance of wallet (%s) from granter (%s) does not allow %s", address, rm.feeGranter.String(), msgType)
+				}
+			}
+		}
+
+		if !minAllowance.IsNil() && !allowance.SpendLimit.Empty() && allowance.SpendLimit.AmountOf(rm.config.PaymentDenom).LT(minAllowance) {
+			return fmt.Errorf("fee allowance (%s) of wallet (%s) from granter (%s) is below the minimum (%s%s)", allowance.SpendLimit.String(), address, rm.feeGranter.String(), minAllowance.String(), rm.config.PaymentDenom)
+		}
+
+		spendLimit := "unlimited"
+		if !allowance.SpendLimit.Empty() {
+			spendLimit = allowance.SpendLimit.String()
+		}
+		rm.logger.Infof("fees of wallet %s are paid by granter %s with allowance %s", address, rm.feeGranter.String(), spendLimit)
+	}
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The types of the messages sent by the service. The transactions of all of them must be allowed to use the fee grant.
+// In authz mode the mints are wrapped in MsgExec.
+func (rm *relayMinter) sentMsgTypes() []string {
+	if rm.config.HasAuthzMinter() {
+		return []string{sdk.MsgTypeURL(&authz.MsgExec{}), sdk.MsgTypeURL(&banktypes.MsgSend{})}
+	}
+
+	return []string{sdk.MsgTypeURL(&marketplacetypes.MsgMintNft{}), sdk.MsgTypeURL(&banktypes.MsgSend{})}
+}
@@ -0,0 +1,73 @@
+package relayminter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldAcceptSufficientFeeAllowance(t *testing.T) {
+	relayMinter := newFeeGrantTestRelayMinter(t, model.FeeAllowance{
+		SpendLimit:      sdk.NewCoins(sdk.NewInt64Coin("acudos", 1000)),
+		AllowedMessages: []string{"/cosmos.bank.v1beta1.MsgSend", "/cudoventures.cudosnode.marketplace.MsgMintNft"},
+	})
+	require.NoError(t, relayMinter.checkFeeAllowances(context.Background()))
+
+	relayMinter = newFeeGrantTestRelayMinter(t, model.FeeAllowance{})
+	require.NoError(t, relayMinter.checkFeeAllowances(context.Background()))
+}
+
+func TestShouldNotCheckFeeAllowancesWithoutGranter(t *testing.T) {
+	relayMinter := newFeeGrantTestRelayMinter(t, model.FeeAllowance{})
+	relayMinter.feeGranter = nil
+	relayMinter.allowanceQuerier = nil
+	require.NoError(t, relayMinter.checkFeeAllowances(context.Background()))
+}
+
+func TestShouldFailIfFeeAllowanceIsMissing(t *testing.T) {
+	relayMinter := newFeeGrantTestRelayMinter(t, model.FeeAllowance{})
+	relayMinter.allowanceQuerier = &mockAllowanceQuerier{}
+	require.Error(t, relayMinter.checkFeeAllowances(context.Background()))
+}
+
+func TestShouldFailIfFeeAllowanceIsExpired(t *testing.T) {
+	expiration := time.Now().Add(-time.Hour)
+	relayMinter := newFeeGrantTestRelayMinter(t, model.FeeAllowance{Expiration: &expiration})
+	require.Error(t, relayMinter.checkFeeAllowances(context.Background()))
+}
+
+func TestShouldFailIfFeeAllowanceDoesNotAllowMints(t *testing.T) {
+	relayMinter := newFeeGrantTestRelayMinter(t, model.FeeAllowance{AllowedMessages: []string{"/cosmos.bank.v1beta1.MsgSend"}})
+	require.Error(t, relayMinter.checkFeeAllowances(context.Background()))
+}
+
+func TestShouldFailIfFeeAllowanceIsBelowMinimum(t *testing.T) {
+	relayMinter := newFeeGrantTestRelayMinter(t, model.FeeAllowance{SpendLimit: sdk.NewCoins(sdk.NewInt64Coin("acudos", 999))})
+	require.Error(t, relayMinter.checkFeeAllowances(context.Background()))
+}
+
+func TestShouldFailWithInvalidFeeGranter(t *testing.T) {
+	relayMinter := newFeeGrantTestRelayMinter(t, model.FeeAllowance{})
+	relayMinter.config.FeeGranter = "invalid"
+	_, err := relayMinter.feeGranterAddress()
+	require.Error(t, err)
+}
+
+// Building a relay minter with a fee granter that requires an allowance of at least 1000 and grants the given allowance to the payment wallet.
+func newFeeGrantTestRelayMinter(t *testing.T, allowance model.FeeAllowance) *relayMinter {
+	relayMinter := newTestRelayMinter(t, testRelayMinterOptions{cfg: config.Config{FeeGranter: treasuryAddress, FeeGrantMinAllowance: "1000"}}).relayMinter
+
+	feeGranter, err := relayMinter.feeGranterAddress()
+	require.NoError(t, err)
+	relayMinter.feeGranter = feeGranter
+	relayMinter.allowanceQuerier = &mockAllowanceQuerier{allowances: map[string]model.FeeAllowance{
+		relayMinter.walletAddress.String(): allowance,
+	}}
+
+	return relayMinter
+}
@@ -0,0 +1,180 @@
+package relayminter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Encoding the memo of a mint or refund transaction.
+// Transactions of a single NFT payment have the reference of the payment as memo, so they stay compatible with the already processed payments.
+// Transactions of a cart payment have JSON memo which lists the items they are for, so the idempotency checks can recognise each of them.
+func encodeOutgoingMemo(memo outgoingMemo) (string, error) {
+	if memo.isForWholePayment() {
+		return memo.TxHash, nil
+	}
+
+	memoBytes, err := json.Marshal(memo)
+	if err != nil {
+		return "", err
+	}
+
+	if len(memoBytes) > maxMemoCharacters {
+		return "", fmt.Errorf("memo length (%d) exceeds the maximum of %d characters", len(memoBytes), maxMemoCharacters)
+	}
+
+	return string(memoBytes), nil
+}
+
+// Encoding the memo of a batch transaction, which lists the references of all payments it is for.
+// The references are shortened to the prefix of their hash, so a batch memo holds about a dozen payments instead of three.
+func encodeBatchMemo(memos []outgoingMemo) (string, error) {
+	refs := []string{}
+	for _, memo := range memos {
+		refs = append(refs, shortRef(memo.TxHash))
+	}
+
+	memoBytes, err := json.Marshal(refs)
+	if err != nil {
+		return "", err
+	}
+
+	if len(memoBytes) > maxMemoCharacters {
+		return "", fmt.Errorf("memo length (%d) exceeds the maximum of %d characters", len(memoBytes), maxMemoCharacters)
+	}
+
+	return string(memoBytes), nil
+}
+
+// Decoding the memo of a mint or refund transaction.
+// Memos that are not JSON are references of a payment without listed items.
+func decodeOutgoingMemo(memo string) outgoingMemo {
+	decoded := outgoingMemo{}
+	if err := json.Unmarshal([]byte(memo), &decoded); err == nil && decoded.TxHash != "" {
+		return decoded
+	}
+
+	return outgoingMemo{TxHash: memo}
+}
+
+// Decoding all payment references in the memo of a mint or refund transaction.
+// Batch transactions have a JSON array memo of short references, all other transactions reference a single payment.
+// Batches sent by earlier versions have an array of memo objects instead.
+func decodeOutgoingMemos(memo string) []outgoingMemo {
+	refs := []string{}
+	if err := json.Unmarshal([]byte(memo), &refs); err == nil && len(refs) > 0 {
+		decoded := []outgoingMemo{}
+		for _, ref := range refs {
+			decoded = append(decoded, outgoingMemo{TxHash: ref})
+		}
+		return decoded
+	}
+
+	decoded := []outgoingMemo{}
+	if err := json.Unmarshal([]byte(memo), &decoded); err == nil && len(decoded) > 0 {
+		return decoded
+	}
+
+	return []outgoingMemo{decodeOutgoingMemo(memo)}
+}
+
+// The reference of a payment as listed in a batch memo, the prefix of its hash followed by the index of the transfer if any.
+func shortRef(ref string) string {
+	parts := strings.SplitN(ref, "#", 2)
+	if len(parts[0]) > shortRefHashCharacters {
+		parts[0] = parts[0][:shortRefHashCharacters]
+	}
+
+	return strings.Join(parts, "#")
+}
+
+// Checking whether the reference in an outgoing memo is the one of the payment, in full or shortened by a batch memo.
+func matchesRef(memoRef, ref string) bool {
+	return memoRef == ref || memoRef == shortRef(ref)
+}
+
+// The memo of a transaction for the given items of a cart. The items are listed by their position in the cart of the payment memo,
+// so the memo stays short whatever the length of the uids. Without items the transaction is for the whole payment.
+func newCartMemo(incomingPaymentTxHash string, cart, uids []string) outgoingMemo {
+	positions := map[string]int{}
+	for i, uid := range cart {
+		if _, ok := positions[uid]; !ok {
+			positions[uid] = i
+		}
+	}
+
+	items := []int{}
+	for _, uid := range uids {
+		items = append(items, positions[uid])
+	}
+
+	return outgoingMemo{TxHash: incomingPaymentTxHash, Items: items}
+}
+
+// Checking whether the memo lists no items, i.e. the transaction is for the whole payment.
+func (m outgoingMemo) isForWholePayment() bool {
+	return len(m.UIDs) == 0 && len(m.Items) == 0
+}
+
+// The uids of the items of the cart the memo is for. Memos sent before the items were listed by their position list the uids themselves.
+func (m outgoingMemo) cartUIDs(cart []string) []string {
+	if len(m.Items) == 0 {
+		return m.UIDs
+	}
+
+	uids := []string{}
+	for _, i := range m.Items {
+		if i >= 0 && i < len(cart) {
+			uids = append(uids, cart[i])
+		}
+	}
+
+	return uids
+}
+
+// Finding the entry of the memo that references the given payment.
+func findOutgoingMemo(memo, incomingPaymentTxHash string) (outgoingMemo, bool) {
+	for _, decoded := range decodeOutgoingMemos(memo) {
+		if matchesRef(decoded.TxHash, incomingPaymentTxHash) {
+			return decoded, true
+		}
+	}
+
+	return outgoingMemo{}, false
+}
+
+// Encoding the memo of a treasury sweep. It is a JSON object without payment fields, so it is never taken for a payment or a refund.
+func encodeSweepMemo(sweepTime time.Time) (string, error) {
+	memoBytes, err := json.Marshal(sweepMemo{Sweep: sweepTime.Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	return string(memoBytes), nil
+}
+
+func isSweepMemo(memo string) bool {
+	decoded := sweepMemo{}
+	return json.Unmarshal([]byte(memo), &decoded) == nil && decoded.Sweep != 0
+}
+
+// The memo of the mint and refund transactions. It references the incoming payment and optionally the cart items the transaction is for.
+type outgoingMemo struct {
+	TxHash string   `json:"tx_hash"`
+	UIDs   []string `json:"uuids,omitempty"`
+	// The positions of the items in the cart of the payment
+	Items []int `json:"items,omitempty"`
+}
+
+// The memo of a treasury sweep. It holds the unix time of the sweep.
+type sweepMemo struct {
+	Sweep int64 `json:"sweep"`
+}
+
+// The number of characters of the hash kept by the short references of a batch memo.
+// The checks of a payment only look at transactions to its buyer or its recipient, so a 64 bit prefix cannot collide in practice.
+const shortRefHashCharacters = 16
+
+// The default maximum memo length of the cosmos sdk auth module.
+const maxMemoCharacters = 256
@@ -0,0 +1,113 @@
+package relayminter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldEncodePaymentReferenceAsPlainMemo(t *testing.T) {
+	memo, err := encodeOutgoingMemo(outgoingMemo{TxHash: "ABCDEF"})
+	require.NoError(t, err)
+	require.Equal(t, "ABCDEF", memo)
+	require.Equal(t, outgoingMemo{TxHash: "ABCDEF"}, decodeOutgoingMemo(memo))
+}
+
+func TestShouldEncodeCartItemsInMemo(t *testing.T) {
+	memo, err := encodeOutgoingMemo(outgoingMemo{TxHash: "ABCDEF", UIDs: []string{"uid1", "uid2"}})
+	require.NoError(t, err)
+	require.Equal(t, "{\"tx_hash\":\"ABCDEF\",\"uuids\":[\"uid1\",\"uid2\"]}", memo)
+	require.Equal(t, outgoingMemo{TxHash: "ABCDEF", UIDs: []string{"uid1", "uid2"}}, decodeOutgoingMemo(memo))
+}
+
+func TestShouldListCartItemsByTheirPositionInMemo(t *testing.T) {
+	cart := []string{"uid1", "uid2", "uid3"}
+
+	memo, err := encodeOutgoingMemo(newCartMemo("ABCDEF", cart, []string{"uid1", "uid3"}))
+	require.NoError(t, err)
+	require.Equal(t, "{\"tx_hash\":\"ABCDEF\",\"items\":[0,2]}", memo)
+	require.Equal(t, []string{"uid1", "uid3"}, decodeOutgoingMemo(memo).cartUIDs(cart))
+	require.False(t, decodeOutgoingMemo(memo).isForWholePayment())
+
+	legacy := decodeOutgoingMemo("{\"tx_hash\":\"ABCDEF\",\"uuids\":[\"uid2\"]}")
+	require.Equal(t, []string{"uid2"}, legacy.cartUIDs(cart))
+	require.True(t, decodeOutgoingMemo("ABCDEF").isForWholePayment())
+}
+
+func TestShouldFitMemoOfCartWithMaximumItemsOfLongUIDs(t *testing.T) {
+	cart := []string{}
+	for i := 0; i < 10; i++ {
+		cart = append(cart, strings.Repeat("a", 34)+fmt.Sprintf("%02d", i))
+	}
+
+	memo, err := encodeOutgoingMemo(newCartMemo(strings.Repeat("A", 64)+"#1", cart, cart))
+	require.NoError(t, err)
+	require.Equal(t, cart, decodeOutgoingMemo(memo).cartUIDs(cart))
+}
+
+func TestShouldFailEncodingMemoLongerThanMaximum(t *testing.T) {
+	_, err := encodeOutgoingMemo(outgoingMemo{TxHash: "ABCDEF", UIDs: []string{strings.Repeat("a", maxMemoCharacters)}})
+	require.Error(t, err)
+}
+
+func TestShouldDecodeNonJSONMemoAsPaymentReference(t *testing.T) {
+	require.Equal(t, outgoingMemo{TxHash: "{\"uuid\":\"uid1\"}"}, decodeOutgoingMemo("{\"uuid\":\"uid1\"}"))
+	require.Equal(t, outgoingMemo{TxHash: ""}, decodeOutgoingMemo(""))
+}
+
+func TestShouldEncodeBatchMemo(t *testing.T) {
+	hash := strings.Repeat("AB", 32)
+	memo, err := encodeBatchMemo([]outgoingMemo{{TxHash: hash}, {TxHash: hash + "#1"}, {TxHash: "ABCDEF"}})
+	require.NoError(t, err)
+	require.Equal(t, "[\"ABABABABABABABAB\",\"ABABABABABABABAB#1\",\"ABCDEF\"]", memo)
+	require.Equal(t, []outgoingMemo{{TxHash: "ABABABABABABABAB"}, {TxHash: "ABABABABABABABAB#1"}, {TxHash: "ABCDEF"}}, decodeOutgoingMemos(memo))
+
+	memos := []outgoingMemo{}
+	for i := 0; i < 14; i++ {
+		memos = append(memos, outgoingMemo{TxHash: fmt.Sprintf("%s#%d", hash, i)})
+	}
+	_, err = encodeBatchMemo(memos)
+	require.Error(t, err)
+
+	_, err = encodeBatchMemo(memos[:12])
+	require.NoError(t, err)
+}
+
+func TestShouldDecodeBatchMemoOfEarlierVersions(t *testing.T) {
+	require.Equal(t, []outgoingMemo{{TxHash: "ABCDEF"}, {TxHash: "ABCDEF#1"}}, decodeOutgoingMemos("[{\"tx_hash\":\"ABCDEF\"},{\"tx_hash\":\"ABCDEF#1\"}]"))
+}
+
+func TestShouldFindPaymentReferenceInMemo(t *testing.T) {
+	_, ok := findOutgoingMemo("[{\"tx_hash\":\"ABCDEF\"},{\"tx_hash\":\"ABCDEF#1\"}]", "ABCDEF#1")
+	require.True(t, ok)
+
+	hash := strings.Repeat("AB", 32)
+	_, ok = findOutgoingMemo("[\"ABABABABABABABAB\",\"ABABABABABABABAB#1\"]", hash+"#1")
+	require.True(t, ok)
+
+	_, ok = findOutgoingMemo("[\"ABABABABABABABAB#1\"]", hash)
+	require.False(t, ok)
+
+	_, ok = findOutgoingMemo("[{\"tx_hash\":\"ABCDEF\"}]", "ABCDEF#1")
+	require.False(t, ok)
+
+	memo, ok := findOutgoingMemo("{\"tx_hash\":\"ABCDEF\",\"uuids\":[\"uid1\"]}", "ABCDEF")
+	require.True(t, ok)
+	require.Equal(t, []string{"uid1"}, memo.UIDs)
+
+	_, ok = findOutgoingMemo("ABCDEF", "ABCDEF")
+	require.True(t, ok)
+}
+
+func TestShouldRecogniseSweepMemo(t *testing.T) {
+	memo, err := encodeSweepMemo(time.Unix(1664625600, 0))
+	require.NoError(t, err)
+	require.Equal(t, "{\"sweep\":1664625600}", memo)
+	require.True(t, isSweepMemo(memo))
+	require.False(t, isSweepMemo("ABCDEF"))
+	require.False(t, isSweepMemo("{\"uuid\":\"uid1\"}"))
+	require.False(t, isSweepMemo("{\"tx_hash\":\"ABCDEF\",\"uuids\":[\"uid1\"]}"))
+}
@@ -0,0 +1,81 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+)
+
+// Checking the status of the node before any payment is processed. Nothing is processed while the node is catching up
+// or its latest block is older than the configured max block age, i.e. the node is stuck or the chain is halted,
+// because the transactions the idempotency checks look for may not be indexed yet.
+// Returning the height up to which the payments are processed, which is the configured confirmation depth behind the latest block,
+// and whether the node is ready.
+func (rm *relayMinter) checkNode(ctx context.Context) (int64, bool, error) {
+	status, err := rm.blockQuerier.NodeStatus(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	blockStale := rm.config.MaxBlockAge > 0 && time.Since(status.LatestBlockTime) > rm.config.MaxBlockAge
+
+	health := model.Health{
+		Healthy:         !status.CatchingUp && !blockStale,
+		CatchingUp:      status.CatchingUp,
+		BlockStale:      blockStale,
+		LatestHeight:    status.LatestHeight,
+		LatestBlockTime: status.LatestBlockTime,
+		ConfirmedHeight: status.LatestHeight - rm.config.ConfirmationDepth,
+	}
+
+	health.Status = model.HealthStatusHealthy
+	if !health.Healthy {
+		health.Status = model.HealthStatusUnhealthy
+	}
+
+	rm.reportHealth(health)
+	return health.ConfirmedHeight, health.Healthy, nil
+}
+
+// Storing the health for the health endpoint. Alerts are sent only when the node starts or stops catching up
+// or its latest block becomes stale or fresh again, not on every tick.
+func (rm *relayMinter) reportHealth(health model.Health) {
+	rm.healthMu.Lock()
+	previous := rm.health
+	rm.health = health
+	rm.healthMu.Unlock()
+
+	if health.CatchingUp != previous.CatchingUp {
+		message := fmt.Sprintf("node is synced at height %d, resuming relaying", health.LatestHeight)
+		if health.CatchingUp {
+			message = fmt.Sprintf("node is catching up at height %d, pausing relaying until it is synced", health.LatestHeight)
+		}
+
+		rm.logger.Warn(message)
+		rm.emailService.SendEmail(message)
+	}
+
+	if health.BlockStale != previous.BlockStale {
+		message := fmt.Sprintf("latest block %d of the node is from %s, resuming relaying", health.LatestHeight, health.LatestBlockTime.String())
+		if health.BlockStale {
+			message = fmt.Sprintf("latest block %d of the node is from %s, older than %s, pausing relaying until the chain progresses", health.LatestHeight, health.LatestBlockTime.String(), rm.config.MaxBlockAge.String())
+		}
+
+		rm.logger.Warn(message)
+		rm.emailService.SendEmail(message)
+	}
+}
+
+// The health of the relayer as of its last relay tick, starting until the first tick checked the node.
+func (rm *relayMinter) Health() model.Health {
+	rm.healthMu.Lock()
+	defer rm.healthMu.Unlock()
+
+	if rm.health.Status == "" {
+		return model.Health{Status: model.HealthStatusStarting}
+	}
+
+	return rm.health
+}
@@ -0,0 +1,160 @@
+package relayminter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/stretchr/testify/require"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+func TestShouldProcessPaymentsOnlyUpToConfirmationDepth(t *testing.T) {
+	relayMinter, mts, _, blockQuerier := newNodeStatusTestRelayMinter(t)
+	txQuerier := &recordingTxQuerier{txQuerier: relayMinter.txQuerier}
+	relayMinter.txQuerier = txQuerier
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Len(t, mts.outputMsgs, 1)
+	require.Equal(t, fmt.Sprintf("tx.height>0 AND tx.height<=97 AND transfer.recipient='%s'", relayMinter.walletAddress.String()), txQuerier.queries[0])
+
+	health := relayMinter.Health()
+	require.Equal(t, model.HealthStatusHealthy, health.Status)
+	require.True(t, health.Healthy)
+	require.Equal(t, int64(100), health.LatestHeight)
+	require.Equal(t, int64(97), health.ConfirmedHeight)
+	require.Equal(t, blockQuerier.status.LatestBlockTime, health.LatestBlockTime)
+}
+
+func TestShouldReportStartingHealthBeforeFirstTick(t *testing.T) {
+	relayMinter, _, _, _ := newNodeStatusTestRelayMinter(t)
+	require.Equal(t, model.Health{Status: model.HealthStatusStarting}, relayMinter.Health())
+}
+
+func TestShouldQueryPaymentsInHeightWindows(t *testing.T) {
+	relayMinter, mts, _, _ := newNodeStatusTestRelayMinter(t)
+	relayMinter.config.QueryWindow = 40
+	txQuerier := &recordingTxQuerier{txQuerier: newMockTxQuerier(&ctypes.ResultTxSearch{}, nil, nil, false)}
+	relayMinter.txQuerier = txQuerier
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+	wallet := relayMinter.walletAddress.String()
+	require.Equal(t, []string{
+		fmt.Sprintf("tx.height>0 AND tx.height<=40 AND transfer.recipient='%s'", wallet),
+		fmt.Sprintf("tx.height>40 AND tx.height<=80 AND transfer.recipient='%s'", wallet),
+		fmt.Sprintf("tx.height>80 AND tx.height<=97 AND transfer.recipient='%s'", wallet),
+	}, txQuerier.queries)
+	require.Equal(t, int64(97), relayMinter.stateStorage.(*mockState).state.Height)
+}
+
+func TestShouldStopWindowsAtFailingQuery(t *testing.T) {
+	relayMinter, _, _, _ := newNodeStatusTestRelayMinter(t)
+	relayMinter.config.QueryWindow = 40
+	relayMinter.txQuerier = &failingWindowTxQuerier{failAbove: 40}
+
+	require.Error(t, relayMinter.relay(context.Background()))
+	require.Equal(t, int64(40), relayMinter.stateStorage.(*mockState).state.Height)
+}
+
+func TestShouldNotProcessPaymentsBeforeConfirmationDepth(t *testing.T) {
+	relayMinter, mts, _, blockQuerier := newNodeStatusTestRelayMinter(t)
+	blockQuerier.status.LatestHeight = 3
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+	require.Equal(t, int64(0), relayMinter.stateStorage.(*mockState).state.Height)
+}
+
+func TestShouldPauseWhileNodeIsCatchingUpAndResumeWhenSynced(t *testing.T) {
+	relayMinter, mts, emailService, blockQuerier := newNodeStatusTestRelayMinter(t)
+	blockQuerier.status.CatchingUp = true
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+	require.Equal(t, int64(0), relayMinter.stateStorage.(*mockState).state.Height)
+	require.Equal(t, []string{"node is catching up at height 100, pausing relaying until it is synced"}, emailService.emails)
+
+	health := relayMinter.Health()
+	require.Equal(t, model.HealthStatusUnhealthy, health.Status)
+	require.False(t, health.Healthy)
+	require.True(t, health.CatchingUp)
+
+	blockQuerier.status.CatchingUp = false
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Len(t, mts.outputMsgs, 1)
+	require.Equal(t, "node is synced at height 100, resuming relaying", emailService.emails[1])
+	require.True(t, relayMinter.Health().Healthy)
+}
+
+func TestShouldPauseWhileLatestBlockIsStale(t *testing.T) {
+	relayMinter, mts, emailService, blockQuerier := newNodeStatusTestRelayMinter(t)
+	blockQuerier.status.LatestBlockTime = time.Now().Add(-time.Hour)
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+	require.Len(t, emailService.emails, 1)
+	require.Contains(t, emailService.emails[0], "older than 1m0s, pausing relaying until the chain progresses")
+
+	health := relayMinter.Health()
+	require.False(t, health.Healthy)
+	require.True(t, health.BlockStale)
+
+	blockQuerier.status.LatestBlockTime = time.Now()
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Len(t, mts.outputMsgs, 1)
+	require.Len(t, emailService.emails, 2)
+	require.False(t, relayMinter.Health().BlockStale)
+}
+
+func TestShouldNotCheckBlockAgeIfDisabled(t *testing.T) {
+	relayMinter, mts, _, blockQuerier := newNodeStatusTestRelayMinter(t)
+	relayMinter.config.MaxBlockAge = 0
+	blockQuerier.status.LatestBlockTime = time.Now().Add(-time.Hour)
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Len(t, mts.outputMsgs, 1)
+}
+
+func TestShouldFailRelayingIfNodeStatusQueryFails(t *testing.T) {
+	relayMinter, mts, _, blockQuerier := newNodeStatusTestRelayMinter(t)
+	blockQuerier.statusErr = errors.New("failed to fetch status")
+
+	require.Equal(t, errors.New("failed to fetch status"), relayMinter.relay(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+}
+
+// Building a relay minter with a confirmation depth of 3 blocks, a max block age of a minute and a single incoming payment for an available NFT.
+// The node is synced at height 100.
+func newNodeStatusTestRelayMinter(t *testing.T) (*relayMinter, *mockTxSender, *mockEmailService, *mockBlockQuerier) {
+	built := newTestRelayMinter(t, testRelayMinterOptions{cfg: config.Config{ConfirmationDepth: 3, MaxBlockAge: time.Minute}})
+	return built.relayMinter, built.txSender, built.emailService, built.blockQuerier
+}
+
+func (rtq *recordingTxQuerier) Query(ctx context.Context, query string) (*ctypes.ResultTxSearch, error) {
+	rtq.queries = append(rtq.queries, query)
+	return rtq.txQuerier.Query(ctx, query)
+}
+
+type recordingTxQuerier struct {
+	txQuerier txQuerier
+	queries   []string
+}
+
+// Failing every query of a window starting above the given height.
+func (fq *failingWindowTxQuerier) Query(ctx context.Context, query string) (*ctypes.ResultTxSearch, error) {
+	var from int64
+	if _, err := fmt.Sscanf(query, "tx.height>%d", &from); err == nil && from >= fq.failAbove {
+		return nil, errors.New("failed window query")
+	}
+	return &ctypes.ResultTxSearch{}, nil
+}
+
+type failingWindowTxQuerier struct {
+	failAbove int64
+}
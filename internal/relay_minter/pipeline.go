@@ -0,0 +1,123 @@
+package relayminter
+
+import (
+	"context"
+	"sync"
+)
+
+// Processing the payments of a relay tick by a pool of workers.
+// The payments are started in the order of their transactions. Every payment waits for the previous payments for any of its NFTs to finish,
+// so payments for the same NFT stay strictly ordered while unrelated payments are processed in parallel. The outgoing transactions are
+// ordered by the tx sender, which builds and broadcasts a single transaction at a time.
+// Once a payment fails no further payments are started. Payments that have been waiting for a failed one are not processed either.
+// The returned height is the one up to which all payments are settled, together with the error of the first failed payment.
+func (rm *relayMinter) processPayments(ctx context.Context, payments []pendingPayment) (int64, error) {
+	workers := rm.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	slots := make(chan struct{}, workers)
+	lastDone := map[string]chan struct{}{}
+	settled := make([]bool, len(payments))
+	errs := make([]error, len(payments))
+
+	var mu sync.Mutex
+	failed := false
+	hasFailed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return failed
+	}
+
+	var wg sync.WaitGroup
+	for i, payment := range payments {
+		slots <- struct{}{}
+		if hasFailed() {
+			<-slots
+			break
+		}
+
+		prevDone := []chan struct{}{}
+		done := make(chan struct{})
+		for _, key := range payment.orderingKeys() {
+			if prev, ok := lastDone[key]; ok {
+				prevDone = append(prevDone, prev)
+			}
+			lastDone[key] = done
+		}
+
+		wg.Add(1)
+		go func(i int, payment pendingPayment, prevDone []chan struct{}, done chan struct{}) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer close(done)
+
+			for _, prev := range prevDone {
+				<-prev
+			}
+
+			if hasFailed() {
+				return
+			}
+
+			rm.logger.Infof("%d: processing incomingPaymentTxHash(%s) at height(%d) with payment(%s)", payment.txIndex+1, payment.sendInfo.Ref(), payment.height, payment.sendInfo.String())
+			if err := rm.processPayment(ctx, payment.sendInfo, payment.height); err != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+				errs[i] = err
+				return
+			}
+
+			settled[i] = true
+		}(i, payment, prevDone, done)
+	}
+
+	wg.Wait()
+
+	for i, payment := range payments {
+		if !settled[i] {
+			for _, err := range errs[i:] {
+				if err != nil {
+					return payment.height - 1, err
+				}
+			}
+		}
+	}
+
+	return lastPaymentHeight(payments), nil
+}
+
+func lastPaymentHeight(payments []pendingPayment) int64 {
+	if len(payments) == 0 {
+		return 0
+	}
+
+	return payments[len(payments)-1].height
+}
+
+// The keys a payment is ordered by with the other payments. These are the NFTs it is for.
+func (p *pendingPayment) orderingKeys() []string {
+	if !p.sendInfo.Memo.IsCart() {
+		return []string{p.sendInfo.Memo.UID}
+	}
+
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, uid := range p.sendInfo.Memo.UIDs {
+		if !seen[uid] {
+			keys = append(keys, uid)
+			seen[uid] = true
+		}
+	}
+
+	return keys
+}
+
+// A payment of an incoming transaction waiting to be processed.
+type pendingPayment struct {
+	sendInfo receivedBankSend
+	height   int64
+	txIndex  int
+}
@@ -0,0 +1,97 @@
+package relayminter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldProcessUnrelatedPaymentsInParallelAndKeepSameNftOrdered(t *testing.T) {
+	// The first payment for the slow NFT completes only after the later payment for the fast one, so the workers must run them in parallel
+	nftDataClient := &recordingNftDataClient{waitFor: map[string]string{"slow:8000000000000000000": "fast:7000000000000000000"}}
+	relayMinter, mts := newPipelineTestRelayMinter(t, config.Config{PaymentDenom: "acudos", Workers: 3}, nftDataClient, []string{"slow", "fast", "slow"})
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Equal(t, []string{"fast:7000000000000000000", "slow:8000000000000000000", "slow:6000000000000000000"}, nftDataClient.calls)
+	require.Len(t, mts.outputMsgs, 3)
+	require.Equal(t, int64(2), relayMinter.stateStorage.(*mockState).state.Height)
+}
+
+func TestShouldAdvanceStateOnlyPastSettledPayments(t *testing.T) {
+	nftDataClient := &recordingNftDataClient{errs: map[string]error{"failing": errors.New("aura pool unavailable")}}
+	relayMinter, mts := newPipelineTestRelayMinter(t, config.Config{PaymentDenom: "acudos"}, nftDataClient, []string{"uid1", "uid2", "failing", "uid3"})
+
+	require.Equal(t, errors.New("aura pool unavailable"), relayMinter.relay(context.Background()))
+	require.Equal(t, int64(1), relayMinter.stateStorage.(*mockState).state.Height)
+	require.Len(t, mts.outputMsgs, 2)
+	require.Equal(t, []string{"uid1:8000000000000000000", "uid2:7000000000000000000", "failing:6000000000000000000"}, nftDataClient.calls)
+}
+
+// Building a relay minter with an incoming transaction at consecutive heights for every uid, each one paying 1 CUDOS less than the previous.
+func newPipelineTestRelayMinter(t *testing.T, cfg config.Config, nftDataClient nftDataClient, uids []string) (*relayMinter, *mockTxSender) {
+	paymentTxs := []testPaymentTx{}
+	amount := uint64(9000000000000000000)
+	for _, uid := range uids {
+		paymentTxs = append(paymentTxs, testPaymentTx{memo: "{\"uuid\":\"" + uid + "\"}", amounts: []uint64{amount}})
+		amount -= 1000000000000000000
+	}
+
+	built := newTestRelayMinter(t, testRelayMinterOptions{cfg: cfg, nftDataClient: nftDataClient, paymentTxs: paymentTxs})
+	return built.relayMinter, built.txSender
+}
+
+// Recording the calls to the AuraPool in the order they complete. No NFT data is returned, so every payment is refunded.
+// A call listed in waitFor blocks until the call it waits for completed. If they are not run in parallel it fails after a timeout instead of blocking the test.
+func (c *recordingNftDataClient) GetNFTData(ctx context.Context, cfg config.Config, uid, recipientCudosAddress string, amountPaid sdk.Coin, paidAt time.Time) (model.NFTData, error) {
+	call := fmt.Sprintf("%s:%s", uid, amountPaid.Amount.String())
+	if waitFor, ok := c.waitFor[call]; ok {
+		select {
+		case <-c.completed(waitFor):
+		case <-time.After(5 * time.Second):
+			return model.NFTData{}, fmt.Errorf("call %s did not run in parallel with %s", call, waitFor)
+		}
+	}
+
+	done := c.completed(call)
+	c.mu.Lock()
+	c.calls = append(c.calls, call)
+	c.mu.Unlock()
+	close(done)
+
+	if err, ok := c.errs[uid]; ok {
+		return model.NFTData{}, err
+	}
+
+	return model.NFTData{}, nil
+}
+
+// The channel closed once the call completed.
+func (c *recordingNftDataClient) completed(call string) chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done == nil {
+		c.done = map[string]chan struct{}{}
+	}
+	if _, ok := c.done[call]; !ok {
+		c.done[call] = make(chan struct{})
+	}
+
+	return c.done[call]
+}
+
+type recordingNftDataClient struct {
+	mu      sync.Mutex
+	calls   []string
+	waitFor map[string]string
+	done    map[string]chan struct{}
+	errs    map[string]error
+}
@@ -0,0 +1,95 @@
+package relayminter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldEvaluatePriceValidityAtPaymentTime(t *testing.T) {
+	priceValidUntil := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name         string
+		memo         string
+		paidAt       time.Time
+		expectedMint bool
+	}{
+		{
+			name:         "PaidBeforeExpiry",
+			memo:         "{\"uuid\":\"nftuid#1\"}",
+			paidAt:       priceValidUntil.Add(-time.Minute),
+			expectedMint: true,
+		},
+		{
+			name:         "PaidWithinGrace",
+			memo:         "{\"uuid\":\"nftuid#1\"}",
+			paidAt:       priceValidUntil.Add(time.Second * 30),
+			expectedMint: true,
+		},
+		{
+			name:         "PaidAfterGrace",
+			memo:         "{\"uuid\":\"nftuid#1\"}",
+			paidAt:       priceValidUntil.Add(time.Minute * 2),
+			expectedMint: false,
+		},
+		{
+			name:         "CartPaidBeforeExpiry",
+			memo:         "{\"uuids\":[\"nftuid#1\"]}",
+			paidAt:       priceValidUntil.Add(-time.Minute),
+			expectedMint: true,
+		},
+		{
+			name:         "CartPaidAfterGrace",
+			memo:         "{\"uuids\":[\"nftuid#1\"]}",
+			paidAt:       priceValidUntil.Add(time.Minute * 2),
+			expectedMint: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			relayMinter, mts := newPriceValidityTestRelayMinter(t, priceValidUntil, tc.memo)
+			relayMinter.blockQuerier = newMockBlockQuerier(tc.paidAt)
+
+			require.NoError(t, relayMinter.relay(context.Background()))
+
+			require.Len(t, mts.outputMsgs, 1)
+			if tc.expectedMint {
+				_, ok := mts.outputMsgs[0].(*marketplacetypes.MsgMintNft)
+				require.True(t, ok)
+			} else {
+				_, ok := mts.outputMsgs[0].(*banktypes.MsgSend)
+				require.True(t, ok)
+			}
+		})
+	}
+}
+
+func TestShouldFailRelayingIfBlockTimeQueryFails(t *testing.T) {
+	relayMinter, mts := newPriceValidityTestRelayMinter(t, time.Now().Add(time.Hour), "{\"uuid\":\"nftuid#1\"}")
+	blockQuerier := newMockBlockQuerier(time.Now())
+	blockQuerier.err = errors.New("failed to fetch block")
+	relayMinter.blockQuerier = blockQuerier
+
+	require.Equal(t, errors.New("failed to fetch block"), relayMinter.relay(context.Background()))
+	require.Len(t, mts.outputMsgs, 0)
+}
+
+// Building a relay minter with a single incoming payment for an NFT whose price is valid until the given time.
+// The grace window of the price validity is a minute.
+func newPriceValidityTestRelayMinter(t *testing.T, priceValidUntil time.Time, memo string) (*relayMinter, *mockTxSender) {
+	built := newTestRelayMinter(t, testRelayMinterOptions{
+		cfg:        config.Config{PriceValidityGrace: time.Minute},
+		nfts:       map[string]model.NFTData{"nftuid#1": newTestNftData("nftuid#1", 8000000000000000000, priceValidUntil.UnixMilli())},
+		paymentTxs: []testPaymentTx{{memo: memo, amounts: []uint64{9000000000000000000}}},
+	})
+	return built.relayMinter, built.txSender
+}
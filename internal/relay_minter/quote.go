@@ -0,0 +1,154 @@
+package relayminter
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Issuing a signed quote of the NFT for the recipient. The price is the current price of the AuraPool and the fee is the estimated gas of the mint.
+// The quote expires after the configured validity, but not later than the price of the AuraPool. It is stored before it is returned,
+// so a payment referencing it is recognised even after a restart.
+func (rm *relayMinter) IssueQuote(ctx context.Context, uid, recipient string) (model.Quote, error) {
+	if rm.quoteKey == nil {
+		return model.Quote{}, errQuotesDisabled
+	}
+
+	if _, err := sdk.AccAddressFromBech32(recipient); err != nil {
+		return model.Quote{}, fmt.Errorf("invalid recipient (%s): %s", recipient, err)
+	}
+
+	wallet := rm.quoteWallet()
+	if wallet.txSender == nil {
+		return model.Quote{}, errors.New("relayer is not started")
+	}
+
+	// Nothing is paid yet, the quote tells the buyer how much to pay
+	now := time.Now()
+	nftData, err := rm.GetNFTData(ctx, rm.config, uid, recipient, sdk.NewCoin(rm.config.PaymentDenom, sdk.ZeroInt()), now)
+	if err != nil {
+		return model.Quote{}, err
+	}
+
+	if err := validateNftData(uid, nftData, now); err != nil {
+		return model.Quote{}, err
+	}
+
+	msgMintNft := rm.wrapMints(wallet, []sdk.Msg{rm.newMintMsg(wallet, uid, recipient, nftData)})[0]
+	gasResult, err := wallet.txSender.EstimateGas(ctx, []sdk.Msg{msgMintNft}, "")
+	if err != nil {
+		return model.Quote{}, err
+	}
+
+	nonce := make([]byte, quoteNonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return model.Quote{}, fmt.Errorf("generating nonce failed: %s", err)
+	}
+
+	expiresAt := now.Add(rm.config.QuoteValidity).UnixMilli()
+	if nftData.PriceValidUntil < expiresAt {
+		expiresAt = nftData.PriceValidUntil
+	}
+
+	quote := model.Quote{
+		Uid:       uid,
+		Recipient: recipient,
+		Price:     nftData.Price,
+		Fee:       gasResult.FeeAmount.AmountOf(rm.config.PaymentDenom),
+		ExpiresAt: expiresAt,
+		Nonce:     hex.EncodeToString(nonce),
+	}
+
+	if quote.Id, err = quote.ComputeId(); err != nil {
+		return model.Quote{}, err
+	}
+
+	signBytes, err := quote.SignBytes()
+	if err != nil {
+		return model.Quote{}, err
+	}
+	quote.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(rm.quoteKey, signBytes))
+
+	if err := rm.quoteStore.SaveQuote(quote); err != nil {
+		return model.Quote{}, fmt.Errorf("storing quote failed: %s", err)
+	}
+
+	rm.logger.Infof("issued %s", quote.String())
+	return quote, nil
+}
+
+// A copy of the payment wallet the gas of the quoted mints is estimated with. The quotes are issued by the http handlers,
+// so the tx sender is read under the lock, the relayer replaces it on every connect.
+func (rm *relayMinter) quoteWallet() *minterWallet {
+	rm.sendersMu.RLock()
+	defer rm.sendersMu.RUnlock()
+
+	return &minterWallet{key: rm.walletKey, address: rm.walletAddress, txSender: rm.txSender}
+}
+
+// Looking up the quote referenced by a payment and verifying it has not been altered since it was issued.
+func (rm *relayMinter) resolveQuote(id string) (model.Quote, error) {
+	if rm.quoteKey == nil {
+		return model.Quote{}, errQuotesDisabled
+	}
+
+	quote, ok := rm.quoteStore.GetQuote(id)
+	if !ok {
+		return model.Quote{}, fmt.Errorf("quote (%s) not found", id)
+	}
+
+	computedId, err := quote.ComputeId()
+	if err != nil {
+		return model.Quote{}, err
+	}
+
+	if computedId != id {
+		return model.Quote{}, fmt.Errorf("quote (%s) does not match its id (%s)", id, computedId)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(quote.Signature)
+	if err != nil {
+		return model.Quote{}, fmt.Errorf("decoding signature of quote (%s) failed: %s", id, err)
+	}
+
+	signBytes, err := quote.SignBytes()
+	if err != nil {
+		return model.Quote{}, err
+	}
+
+	if !ed25519.Verify(rm.quoteKey.Public().(ed25519.PublicKey), signBytes, signature) {
+		return model.Quote{}, fmt.Errorf("quote (%s) has invalid signature", id)
+	}
+
+	return quote, nil
+}
+
+// Honouring the quoted price if the payment landed before the quote expired. The expiry is evaluated at the time the price has to be valid at,
+// which is derived from the time of the block of the payment, see priceValidAt.
+// A payment after the expiry of its quote is processed with the current price of the AuraPool.
+func (rm *relayMinter) applyQuote(quote model.Quote, nftData model.NFTData, validAt time.Time) model.NFTData {
+	if nftData == (model.NFTData{}) {
+		return nftData
+	}
+
+	if validAt.UnixMilli() > quote.ExpiresAt {
+		rm.logger.Infof("quote (%s) expired at %s before the payment, using the current price", quote.Id, time.UnixMilli(quote.ExpiresAt).String())
+		return nftData
+	}
+
+	nftData.Price = quote.Price
+	nftData.PriceValidUntil = quote.ExpiresAt
+	return nftData
+}
+
+var errQuotesDisabled = errors.New("quotes are not enabled")
+
+const quoteNonceLength = 16
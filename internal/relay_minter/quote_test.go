@@ -0,0 +1,294 @@
+package relayminter
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldIssueQuote(t *testing.T) {
+	relayMinter, _, store := newQuoteTestRelayMinter(t)
+
+	before := time.Now()
+	quote, err := relayMinter.IssueQuote(context.Background(), "nftuid#1", quoteRecipient)
+	require.NoError(t, err)
+
+	require.Equal(t, "nftuid#1", quote.Uid)
+	require.Equal(t, quoteRecipient, quote.Recipient)
+	require.Equal(t, sdk.NewIntFromUint64(10000000000000000000), quote.Price)
+	require.Equal(t, mockFeeAmount.AmountOf("acudos"), quote.Fee)
+	require.GreaterOrEqual(t, quote.ExpiresAt, before.Add(time.Minute).UnixMilli())
+	require.LessOrEqual(t, quote.ExpiresAt, time.Now().Add(time.Minute).UnixMilli())
+	require.Len(t, quote.Nonce, quoteNonceLength*2)
+
+	computedId, err := quote.ComputeId()
+	require.NoError(t, err)
+	require.Equal(t, computedId, quote.Id)
+
+	signature, err := base64.StdEncoding.DecodeString(quote.Signature)
+	require.NoError(t, err)
+	signBytes, err := quote.SignBytes()
+	require.NoError(t, err)
+	require.True(t, ed25519.Verify(quoteTestKey.Public().(ed25519.PublicKey), signBytes, signature))
+
+	require.Equal(t, map[string]model.Quote{quote.Id: quote}, store.quotes)
+}
+
+func TestShouldLimitQuoteExpiryToPriceValidity(t *testing.T) {
+	relayMinter, _, _ := newQuoteTestRelayMinter(t)
+
+	priceValidUntil := time.Now().Add(time.Second * 10).UnixMilli()
+	relayMinter.nftDataClient = newTokenisedInfraClient(map[string]model.NFTData{
+		"nftuid#1": {Id: "nftuid#1", Price: sdk.NewIntFromUint64(1), DenomID: "testdenom", Status: model.QueuedNFTStatus, PriceValidUntil: priceValidUntil},
+	}, nil, nil)
+
+	quote, err := relayMinter.IssueQuote(context.Background(), "nftuid#1", quoteRecipient)
+	require.NoError(t, err)
+	require.Equal(t, priceValidUntil, quote.ExpiresAt)
+}
+
+func TestShouldFailIssuingQuote(t *testing.T) {
+	tests := []struct {
+		name      string
+		uid       string
+		recipient string
+		setup     func(relayMinter *relayMinter)
+	}{
+		{
+			name:      "QuotesDisabled",
+			uid:       "nftuid#1",
+			recipient: quoteRecipient,
+			setup: func(relayMinter *relayMinter) {
+				relayMinter.quoteKey = nil
+			},
+		},
+		{
+			name:      "InvalidRecipient",
+			uid:       "nftuid#1",
+			recipient: "invalid",
+			setup:     func(relayMinter *relayMinter) {},
+		},
+		{
+			name:      "RelayerNotStarted",
+			uid:       "nftuid#1",
+			recipient: quoteRecipient,
+			setup: func(relayMinter *relayMinter) {
+				relayMinter.txSender = nil
+			},
+		},
+		{
+			name:      "NftNotFound",
+			uid:       "nftuid#2",
+			recipient: quoteRecipient,
+			setup:     func(relayMinter *relayMinter) {},
+		},
+		{
+			name:      "StoringFails",
+			uid:       "nftuid#1",
+			recipient: quoteRecipient,
+			setup: func(relayMinter *relayMinter) {
+				relayMinter.quoteStore = &mockQuoteStore{quotes: map[string]model.Quote{}, failSave: true}
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			relayMinter, _, _ := newQuoteTestRelayMinter(t)
+			tc.setup(relayMinter)
+
+			_, err := relayMinter.IssueQuote(context.Background(), tc.uid, tc.recipient)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestShouldIssueQuotesWhileTxSendersAreReplaced(t *testing.T) {
+	relayMinter, mts, _ := newQuoteTestRelayMinter(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			relayMinter.wrapTxSenders(func(sender txSender, address string) txSender { return mts })
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		_, err := relayMinter.IssueQuote(context.Background(), "nftuid#1", quoteRecipient)
+		require.NoError(t, err)
+	}
+	<-done
+}
+
+func TestShouldMintQuotedPaymentWithQuotedPrice(t *testing.T) {
+	relayMinter, mts, _ := newQuoteTestRelayMinter(t)
+
+	quote := issueTestQuote(t, relayMinter, sdk.NewIntFromUint64(8000000000000000000))
+	relayMinter.txQuerier = newQuotedPaymentTxQuerier(t, relayMinter, "{\"quote\":\""+quote.Id+"\"}")
+	relayMinter.blockQuerier = newMockBlockQuerier(time.UnixMilli(quote.ExpiresAt).Add(-time.Second))
+
+	// The payment is processed after the quote expired, but it landed before
+	nftData := relayMinter.nftDataClient.(*mockTokenisedInfraClient).nftDataEntires["nftuid#1"]
+	nftData.PriceValidUntil = time.Now().Add(-time.Hour).UnixMilli()
+	relayMinter.nftDataClient.(*mockTokenisedInfraClient).nftDataEntires["nftuid#1"] = nftData
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+
+	require.Len(t, mts.outputMsgs, 1)
+	msgMintNft, ok := mts.outputMsgs[0].(*marketplacetypes.MsgMintNft)
+	require.True(t, ok)
+	require.Equal(t, quoteRecipient, msgMintNft.Recipient)
+	require.Equal(t, "nftuid#1", msgMintNft.Uid)
+	require.Equal(t, sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)), msgMintNft.Price)
+	require.Equal(t, []string{batchTxHash}, mts.outputMemos)
+}
+
+func TestShouldUseCurrentPriceIfQuoteExpiredBeforePayment(t *testing.T) {
+	relayMinter, mts, _ := newQuoteTestRelayMinter(t)
+
+	quote := issueTestQuote(t, relayMinter, sdk.NewIntFromUint64(8000000000000000000))
+	relayMinter.txQuerier = newQuotedPaymentTxQuerier(t, relayMinter, "{\"quote\":\""+quote.Id+"\"}")
+	relayMinter.blockQuerier = newMockBlockQuerier(time.UnixMilli(quote.ExpiresAt).Add(time.Second))
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+
+	// The current price is above the paid amount, so the payment is refunded
+	require.Len(t, mts.outputMsgs, 1)
+	_, ok := mts.outputMsgs[0].(*banktypes.MsgSend)
+	require.True(t, ok)
+}
+
+func TestShouldRefundPaymentWithInvalidQuote(t *testing.T) {
+	tests := []struct {
+		name  string
+		quote func(t *testing.T, relayMinter *relayMinter, store *mockQuoteStore) string
+	}{
+		{
+			name: "UnknownQuote",
+			quote: func(t *testing.T, relayMinter *relayMinter, store *mockQuoteStore) string {
+				return "unknown"
+			},
+		},
+		{
+			name: "AlteredQuote",
+			quote: func(t *testing.T, relayMinter *relayMinter, store *mockQuoteStore) string {
+				quote := issueTestQuote(t, relayMinter, sdk.NewIntFromUint64(8000000000000000000))
+				quote.Price = sdk.NewIntFromUint64(1)
+				store.quotes[quote.Id] = quote
+				return quote.Id
+			},
+		},
+		{
+			name: "QuoteSignedByOtherKey",
+			quote: func(t *testing.T, relayMinter *relayMinter, store *mockQuoteStore) string {
+				quote := issueTestQuote(t, relayMinter, sdk.NewIntFromUint64(8000000000000000000))
+				signBytes, err := quote.SignBytes()
+				require.NoError(t, err)
+				quote.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(ed25519.NewKeyFromSeed([]byte("another-test-quote-signing-seed!")), signBytes))
+				store.quotes[quote.Id] = quote
+				return quote.Id
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			relayMinter, mts, store := newQuoteTestRelayMinter(t)
+
+			quoteId := tc.quote(t, relayMinter, store)
+			relayMinter.txQuerier = newQuotedPaymentTxQuerier(t, relayMinter, "{\"quote\":\""+quoteId+"\"}")
+
+			require.NoError(t, relayMinter.relay(context.Background()))
+
+			require.Len(t, mts.outputMsgs, 1)
+			msgSend, ok := mts.outputMsgs[0].(*banktypes.MsgSend)
+			require.True(t, ok)
+			require.Equal(t, refundReceiver, msgSend.ToAddress)
+		})
+	}
+}
+
+func TestShouldSkipPaymentWithQuoteAndUids(t *testing.T) {
+	relayMinter, mts, _ := newQuoteTestRelayMinter(t)
+
+	quote := issueTestQuote(t, relayMinter, sdk.NewIntFromUint64(8000000000000000000))
+	relayMinter.txQuerier = newQuotedPaymentTxQuerier(t, relayMinter, "{\"quote\":\""+quote.Id+"\",\"uuid\":\"nftuid#1\"}")
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Len(t, mts.outputMsgs, 0)
+}
+
+// Issuing a quote with the given price, which is below the current price of the AuraPool.
+func issueTestQuote(t *testing.T, relayMinter *relayMinter, price sdk.Int) model.Quote {
+	nftDataClient := relayMinter.nftDataClient
+	relayMinter.nftDataClient = newTokenisedInfraClient(map[string]model.NFTData{
+		"nftuid#1": {Id: "nftuid#1", Price: price, DenomID: "testdenom", Status: model.QueuedNFTStatus, PriceValidUntil: tomorrow},
+	}, nil, nil)
+	defer func() { relayMinter.nftDataClient = nftDataClient }()
+
+	quote, err := relayMinter.IssueQuote(context.Background(), "nftuid#1", quoteRecipient)
+	require.NoError(t, err)
+
+	return quote
+}
+
+func newQuotedPaymentTxQuerier(t *testing.T, relayMinter *relayMinter, memo string) *mockTxQuerier {
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	buyer, err := sdk.AccAddressFromBech32(refundReceiver)
+	require.NoError(t, err)
+
+	return newMockTxQuerier(buildTestResultTxSearch(t, [][]sdk.Msg{
+		{
+			banktypes.NewMsgSend(buyer, relayMinter.walletAddress, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000)))),
+		},
+	}, []string{memo}, &encodingConfig, batchTxHash), nil, nil, false)
+}
+
+// Building a relay minter issuing quotes. The current price of the NFT at the AuraPool is above the payments of the tests,
+// so only a quoted price can be minted.
+func newQuoteTestRelayMinter(t *testing.T) (*relayMinter, *mockTxSender, *mockQuoteStore) {
+	store := &mockQuoteStore{quotes: map[string]model.Quote{}}
+	built := newTestRelayMinter(t, testRelayMinterOptions{
+		cfg:        config.Config{QuoteValidity: time.Minute},
+		nfts:       map[string]model.NFTData{"nftuid#1": newTestNftData("nftuid#1", 10000000000000000000, tomorrow)},
+		quoteKey:   quoteTestKey,
+		quoteStore: store,
+	})
+	return built.relayMinter, built.txSender, store
+}
+
+func (m *mockQuoteStore) SaveQuote(quote model.Quote) error {
+	if m.failSave {
+		return errors.New("failed to save quote")
+	}
+
+	m.quotes[quote.Id] = quote
+	return nil
+}
+
+func (m *mockQuoteStore) GetQuote(id string) (model.Quote, bool) {
+	quote, ok := m.quotes[id]
+	return quote, ok
+}
+
+type mockQuoteStore struct {
+	quotes   map[string]model.Quote
+	failSave bool
+}
+
+var quoteTestKey = ed25519.NewKeyFromSeed([]byte("relay-minter-quote-signing-seed!"))
+
+const quoteRecipient = "cudos1w96k7ar994hxvapdwfjkx6tsd9jkuap37egfhq"
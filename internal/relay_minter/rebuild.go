@@ -0,0 +1,360 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/state"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Rebuilding the ledger and the state of the service from the chain history, e.g. after the state file was lost.
+// The payments after the from height up to the to height are matched with the outgoing transactions in the same blocks by the references in their memos.
+// The ledger is written to the ledger file. The state is set to resume before the first unprocessed payment only if writeState is set,
+// otherwise the report just plans the resume height. Without a to height the blocks up to the confirmed height are rebuilt.
+func (rm *relayMinter) Rebuild(ctx context.Context, fromHeight, toHeight int64, writeState bool) (model.RebuildReport, error) {
+	grpcConn, err := rm.grpcConnector.MakeGRPCClient(rm.config.ChainGRPC)
+	if err != nil {
+		return model.RebuildReport{}, fmt.Errorf("dialing GRPC url (%s) failed: %s", rm.config.ChainGRPC, err)
+	}
+	defer grpcConn.Close()
+
+	closeQueriers, err := rm.connectQueriers(grpcConn)
+	if err != nil {
+		return model.RebuildReport{}, err
+	}
+	defer closeQueriers()
+
+	if toHeight == 0 {
+		status, err := rm.blockQuerier.NodeStatus(ctx)
+		if err != nil {
+			return model.RebuildReport{}, err
+		}
+		toHeight = status.LatestHeight - rm.config.ConfirmationDepth
+	}
+
+	return rm.rebuild(ctx, fromHeight, toHeight, state.NewFileLedger(rm.config.LedgerFile), writeState)
+}
+
+func (rm *relayMinter) rebuild(ctx context.Context, fromHeight, toHeight int64, ledger ledgerWriter, writeState bool) (model.RebuildReport, error) {
+	if toHeight <= fromHeight {
+		return model.RebuildReport{}, fmt.Errorf("nothing to rebuild after %d up to %d", fromHeight, toHeight)
+	}
+
+	rm.logger.Infof("rebuilding payments after %d up to %d", fromHeight, toHeight)
+
+	entries := []model.LedgerEntry{}
+	payments := map[string]*receivedBankSend{}
+	outgoingTxs := []model.IndexedTx{}
+
+	err := rm.forEachWindow(fromHeight, toHeight, func(from, to int64) error {
+		txs, err := rm.queryPaymentTransactions(ctx, from, to)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range txs {
+			sendInfos, err := rm.getReceivedBankSendInfos(result)
+			if err != nil {
+				rm.logger.Warnf("getting received bank send info for tx(%s) failed: %s", result.Hash.String(), err)
+				continue
+			}
+
+			for i := range sendInfos {
+				payments[sendInfos[i].Ref()] = &sendInfos[i]
+				entries = append(entries, newLedgerEntry(sendInfos[i], result.Height))
+			}
+		}
+
+		txsOfWindow, err := rm.queryOutgoingTransactions(ctx, from, to, rm.trackedAddresses())
+		if err != nil {
+			return err
+		}
+
+		outgoingTxs = append(outgoingTxs, txsOfWindow...)
+		return nil
+	})
+	if err != nil {
+		return model.RebuildReport{}, err
+	}
+
+	// The payments of a window are returned wallet by wallet
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Height < entries[j].Height
+	})
+
+	report := model.RebuildReport{FromHeight: fromHeight, ToHeight: toHeight, ResumeHeight: toHeight, Payments: len(entries), Anomalies: []model.RebuildAnomaly{}}
+	entryIdx := map[string]int{}
+	for i, entry := range entries {
+		entryIdx[entry.Ref] = i
+		// Batch memos reference the payments by their short reference
+		if _, ok := entryIdx[shortRef(entry.Ref)]; !ok {
+			entryIdx[shortRef(entry.Ref)] = i
+		}
+	}
+
+	outgoingByHash := map[string]model.IndexedTx{}
+	for _, tx := range outgoingTxs {
+		outgoingByHash[tx.Hash] = tx
+
+		orphanRefs := []string{}
+		for refIdx, ref := range tx.Refs {
+			i, ok := entryIdx[ref]
+			if !ok {
+				found, err := rm.hasPaymentBefore(ctx, ref, fromHeight)
+				if err != nil {
+					return model.RebuildReport{}, err
+				}
+
+				if !found {
+					orphanRefs = append(orphanRefs, ref)
+				}
+				continue
+			}
+
+			memo, _ := findOutgoingMemo(tx.Memo, entries[i].Ref)
+			matchOutgoingTx(&entries[i], payments[entries[i].Ref], tx, refIdx, memo)
+		}
+
+		if len(orphanRefs) > 0 {
+			report.Anomalies = append(report.Anomalies, model.RebuildAnomaly{
+				Kind:     model.AnomalyOrphanTx,
+				TxHashes: []string{tx.Hash},
+				Detail:   fmt.Sprintf("transaction at height %d references payments %v that are not in the rebuilt blocks", tx.Height, orphanRefs),
+			})
+		}
+	}
+
+	for i := range entries {
+		report.Anomalies = append(report.Anomalies, ledgerAnomalies(&entries[i], outgoingByHash)...)
+
+		if entries[i].Status == model.LedgerStatusUnprocessed && report.ResumeHeight == toHeight {
+			report.ResumeHeight = entries[i].Height - 1
+		}
+	}
+
+	if err := ledger.WriteLedger(entries); err != nil {
+		return model.RebuildReport{}, fmt.Errorf("writing ledger failed: %s", err)
+	}
+
+	if !writeState {
+		rm.logger.Infof("rebuilt %d payments with %d anomalies, the state would resume after %d, it is left unchanged", report.Payments, len(report.Anomalies), report.ResumeHeight)
+		return report, nil
+	}
+
+	rm.logger.Info(fmt.Sprintf("update state to %d", report.ResumeHeight))
+	if err := rm.stateStorage.UpdateState(model.State{Height: report.ResumeHeight}); err != nil {
+		return model.RebuildReport{}, err
+	}
+	report.StateWritten = true
+
+	rm.logger.Infof("rebuilt %d payments with %d anomalies, resuming after %d", report.Payments, len(report.Anomalies), report.ResumeHeight)
+	return report, nil
+}
+
+// Calling the function for the windows of the configured size after the from height up to the to height.
+func (rm *relayMinter) forEachWindow(fromHeight, toHeight int64, fn func(from, to int64) error) error {
+	for from := fromHeight; from < toHeight; {
+		to := toHeight
+		if rm.config.QueryWindow > 0 && from+rm.config.QueryWindow < toHeight {
+			to = from + rm.config.QueryWindow
+		}
+
+		if err := fn(from, to); err != nil {
+			return err
+		}
+
+		from = to
+	}
+
+	return nil
+}
+
+// Checking whether the referenced payment is in a block up to the from height, so an outgoing transaction in the rebuilt blocks
+// that settles it is no orphan. The payment is looked up by the hash in the memo. The block scanner cannot look up a transaction
+// by its hash alone and a batch memo only holds the prefix of the hash, so such a transaction is reported as an orphan.
+func (rm *relayMinter) hasPaymentBefore(ctx context.Context, ref string, fromHeight int64) (bool, error) {
+	txHash := strings.SplitN(ref, "#", 2)[0]
+	if rm.config.ScansBlocks() || len(txHash) == shortRefHashCharacters {
+		return false, nil
+	}
+
+	results, err := rm.txQuerier.Query(ctx, fmt.Sprintf("tx.hash='%s'", txHash))
+	if err != nil {
+		return false, fmt.Errorf("looking up payment (%s) failed: %s", ref, err)
+	}
+
+	if results == nil {
+		return false, nil
+	}
+
+	for _, result := range results.Txs {
+		if result.Hash.String() != txHash || result.Height > fromHeight {
+			continue
+		}
+
+		sendInfos, err := rm.getReceivedBankSendInfos(result)
+		if err != nil {
+			continue
+		}
+
+		for i := range sendInfos {
+			if sendInfos[i].Ref() == ref {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func newLedgerEntry(sendInfo receivedBankSend, height int64) model.LedgerEntry {
+	uids := sendInfo.Memo.UIDs
+	if !sendInfo.Memo.IsCart() && sendInfo.Memo.UID != "" {
+		uids = []string{sendInfo.Memo.UID}
+	}
+
+	return model.LedgerEntry{
+		Ref:        sendInfo.Ref(),
+		Height:     height,
+		Sender:     sendInfo.FromAddress,
+		Receiver:   sendInfo.ToAddress,
+		Amount:     sendInfo.Amount,
+		Uids:       uids,
+		Status:     model.LedgerStatusUnprocessed,
+		MintTxs:    []string{},
+		MintedUids: []string{},
+		RefundTxs:  []string{},
+		Refunded:   sdk.ZeroInt(),
+	}
+}
+
+// Adding an outgoing transaction to the entry of the payment it references. The memo is the entry of the transaction memo for the payment
+// and refIdx is its position in the memo. The mints of the payment are the items listed in the memo if it lists them.
+// The refunds are the bank sends to the sender of the payment. A batch transaction mints or refunds for several payments
+// with one message per payment in the order of its memo, so only the message at the position of the payment is taken.
+// Otherwise only the mints to the recipient of the payment are taken.
+func matchOutgoingTx(entry *model.LedgerEntry, payment *receivedBankSend, tx model.IndexedTx, refIdx int, memo outgoingMemo) {
+	listed := map[string]bool{}
+	for _, uid := range memo.cartUIDs(payment.Memo.UIDs) {
+		listed[uid] = true
+	}
+
+	mints := tx.Mints
+	if len(tx.Refs) > 1 && len(mints) == len(tx.Refs) {
+		mints = mints[refIdx : refIdx+1]
+	}
+
+	minted := false
+	for _, mint := range mints {
+		if len(listed) > 0 && !listed[mint.Uid] {
+			continue
+		}
+
+		if len(tx.Refs) > 1 && mint.Recipient != payment.Memo.RecipientAddress {
+			continue
+		}
+
+		entry.MintedUids = append(entry.MintedUids, mint.Uid)
+		minted = true
+	}
+
+	if minted {
+		entry.MintTxs = append(entry.MintTxs, tx.Hash)
+	}
+
+	refunds := tx.Refunds
+	if len(tx.Refs) > 1 && len(refunds) == len(tx.Refs) {
+		refunds = refunds[refIdx : refIdx+1]
+	}
+
+	refunded := false
+	for _, refund := range refunds {
+		if refund.To != payment.FromAddress {
+			continue
+		}
+
+		entry.Refunded = entry.Refunded.Add(refund.Amount.AmountOf(payment.Amount.Denom))
+		refunded = true
+	}
+
+	if refunded {
+		entry.RefundTxs = append(entry.RefundTxs, tx.Hash)
+	}
+
+	switch {
+	case len(entry.MintTxs) > 0 && len(entry.RefundTxs) > 0:
+		entry.Status = model.LedgerStatusMintedAndRefunded
+	case len(entry.MintTxs) > 0:
+		entry.Status = model.LedgerStatusMinted
+	case len(entry.RefundTxs) > 0:
+		entry.Status = model.LedgerStatusRefunded
+	}
+}
+
+// Finding the anomalies of a payment. An NFT minted more than once, the whole payment refunded more than once
+// or an item of a cart refunded more than once or after the whole payment was refunded.
+func ledgerAnomalies(entry *model.LedgerEntry, outgoingByHash map[string]model.IndexedTx) []model.RebuildAnomaly {
+	if entry.Status == model.LedgerStatusUnprocessed {
+		return []model.RebuildAnomaly{{
+			Kind:   model.AnomalyUnprocessedPayment,
+			Ref:    entry.Ref,
+			Detail: fmt.Sprintf("payment at height %d from %s was neither minted nor refunded", entry.Height, entry.Sender),
+		}}
+	}
+
+	anomalies := []model.RebuildAnomaly{}
+
+	mints := map[string]int{}
+	for _, uid := range entry.MintedUids {
+		mints[uid]++
+	}
+
+	for _, uid := range entry.MintedUids {
+		if mints[uid] > 1 {
+			anomalies = append(anomalies, model.RebuildAnomaly{
+				Kind:     model.AnomalyDuplicateMint,
+				Ref:      entry.Ref,
+				TxHashes: entry.MintTxs,
+				Detail:   fmt.Sprintf("NFT (%s) was minted %d times", uid, mints[uid]),
+			})
+			// Reporting every NFT once
+			mints[uid] = 0
+		}
+	}
+
+	refundedAll := 0
+	refunds := map[string]int{}
+	for _, hash := range entry.RefundTxs {
+		memo, _ := findOutgoingMemo(outgoingByHash[hash].Memo, entry.Ref)
+		if memo.isForWholePayment() {
+			refundedAll++
+		}
+		for _, uid := range memo.cartUIDs(entry.Uids) {
+			refunds[uid]++
+		}
+	}
+
+	duplicateRefund := refundedAll > 1 || (refundedAll == 1 && len(refunds) > 0)
+	for _, count := range refunds {
+		duplicateRefund = duplicateRefund || count > 1
+	}
+
+	if duplicateRefund {
+		anomalies = append(anomalies, model.RebuildAnomaly{
+			Kind:     model.AnomalyDuplicateRefund,
+			Ref:      entry.Ref,
+			TxHashes: entry.RefundTxs,
+			Detail:   fmt.Sprintf("payment was refunded by %d transactions with %s in total", len(entry.RefundTxs), entry.Refunded.String()),
+		})
+	}
+
+	return anomalies
+}
+
+type ledgerWriter interface {
+	WriteLedger(entries []model.LedgerEntry) error
+}
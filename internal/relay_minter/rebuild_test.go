@@ -0,0 +1,197 @@
+package relayminter
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+func TestShouldRebuildLedgerAndReportAnomalies(t *testing.T) {
+	relayMinter, _, _, _ := newRebuildTestRelayMinter(t)
+	ledger := &mockLedger{}
+
+	report, err := relayMinter.rebuild(context.Background(), 0, 100, ledger, true)
+	require.NoError(t, err)
+	require.Equal(t, int64(29), report.ResumeHeight)
+	require.True(t, report.StateWritten)
+	require.Equal(t, 3, report.Payments)
+	require.Equal(t, int64(29), relayMinter.stateStorage.(*mockState).state.Height)
+
+	require.Len(t, ledger.entries, 3)
+	require.Equal(t, batchTxHash, ledger.entries[0].Ref)
+	require.Equal(t, model.LedgerStatusRefunded, ledger.entries[0].Status)
+	require.Equal(t, []string{"02", "03"}, ledger.entries[0].RefundTxs)
+	require.Equal(t, sdk.NewIntFromUint64(2000), ledger.entries[0].Refunded)
+	require.Equal(t, []string{"nftuid#1"}, ledger.entries[0].Uids)
+
+	require.Equal(t, "BB02", ledger.entries[1].Ref)
+	require.Equal(t, model.LedgerStatusUnprocessed, ledger.entries[1].Status)
+
+	require.Equal(t, "DD04", ledger.entries[2].Ref)
+	require.Equal(t, model.LedgerStatusMinted, ledger.entries[2].Status)
+	require.Equal(t, []string{"04"}, ledger.entries[2].MintTxs)
+	require.Equal(t, []string{"nftuid#1"}, ledger.entries[2].MintedUids)
+
+	kinds := []string{}
+	for _, anomaly := range report.Anomalies {
+		kinds = append(kinds, anomaly.Kind)
+	}
+	require.Equal(t, []string{model.AnomalyOrphanTx, model.AnomalyDuplicateRefund, model.AnomalyUnprocessedPayment}, kinds)
+	require.Equal(t, []string{"05"}, report.Anomalies[0].TxHashes)
+	require.Equal(t, batchTxHash, report.Anomalies[1].Ref)
+	require.Equal(t, "BB02", report.Anomalies[2].Ref)
+}
+
+func TestShouldResumeAtEndIfAllPaymentsAreProcessed(t *testing.T) {
+	relayMinter, _, _, _ := newRebuildTestRelayMinter(t)
+	// The mock returns the payments regardless of the queried heights
+	payments := relayMinter.txQuerier.(*outgoingTxQuerier).txQuerier.(*mockTxQuerier).bankSendQueryResults
+	payments.Txs = payments.Txs[2:]
+
+	report, err := relayMinter.rebuild(context.Background(), 30, 100, &mockLedger{}, true)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), report.ResumeHeight)
+	require.Equal(t, 1, report.Payments)
+}
+
+func TestShouldNotUpdateStateIfLedgerFails(t *testing.T) {
+	relayMinter, _, _, _ := newRebuildTestRelayMinter(t)
+	relayMinter.stateStorage.(*mockState).state.Height = 7
+
+	_, err := relayMinter.rebuild(context.Background(), 0, 100, &mockLedger{err: errors.New("failed to write")}, true)
+	require.Equal(t, errors.New("writing ledger failed: failed to write"), err)
+	require.Equal(t, int64(7), relayMinter.stateStorage.(*mockState).state.Height)
+}
+
+func TestShouldOnlyPlanStateWithoutWriteState(t *testing.T) {
+	relayMinter, _, _, _ := newRebuildTestRelayMinter(t)
+	relayMinter.stateStorage.(*mockState).state.Height = 7
+	ledger := &mockLedger{}
+
+	report, err := relayMinter.rebuild(context.Background(), 0, 100, ledger, false)
+	require.NoError(t, err)
+	require.Equal(t, int64(29), report.ResumeHeight)
+	require.False(t, report.StateWritten)
+	require.Len(t, ledger.entries, 3)
+	require.Equal(t, int64(7), relayMinter.stateStorage.(*mockState).state.Height)
+}
+
+func TestShouldNotReportTxOfPaymentBeforeRebuiltBlocksAsOrphan(t *testing.T) {
+	relayMinter, _, _, _ := newRebuildTestRelayMinter(t)
+	querier := relayMinter.txQuerier.(*outgoingTxQuerier)
+	payments := querier.txQuerier.(*mockTxQuerier).bankSendQueryResults
+	querier.incoming = []*ctypes.ResultTx{copyRebuildTestTx(t, payments.Txs[0], "CC03", 5)}
+
+	report, err := relayMinter.rebuild(context.Background(), 8, 100, &mockLedger{}, true)
+	require.NoError(t, err)
+	require.Contains(t, querier.queries, "tx.hash='CC03'")
+
+	kinds := []string{}
+	for _, anomaly := range report.Anomalies {
+		kinds = append(kinds, anomaly.Kind)
+	}
+	require.Equal(t, []string{model.AnomalyDuplicateRefund, model.AnomalyUnprocessedPayment}, kinds)
+}
+
+func TestShouldReportTxOfPaymentAfterFromHeightAsOrphan(t *testing.T) {
+	relayMinter, _, _, _ := newRebuildTestRelayMinter(t)
+	querier := relayMinter.txQuerier.(*outgoingTxQuerier)
+	payments := querier.txQuerier.(*mockTxQuerier).bankSendQueryResults
+	// The payment is found by its hash, but it is not before the rebuilt blocks, so it was not received by the wallet
+	querier.incoming = []*ctypes.ResultTx{copyRebuildTestTx(t, payments.Txs[0], "CC03", 9)}
+
+	report, err := relayMinter.rebuild(context.Background(), 8, 100, &mockLedger{}, true)
+	require.NoError(t, err)
+	require.Equal(t, model.AnomalyOrphanTx, report.Anomalies[0].Kind)
+	require.Equal(t, []string{"05"}, report.Anomalies[0].TxHashes)
+}
+
+func TestShouldSplitBatchRefundOfPaymentsOfSameSender(t *testing.T) {
+	relayMinter, _, _, _ := newRebuildTestRelayMinter(t)
+	wallet := relayMinter.walletAddress.String()
+	querier := relayMinter.txQuerier.(*outgoingTxQuerier)
+
+	memo, err := encodeBatchMemo([]outgoingMemo{{TxHash: batchTxHash}, {TxHash: "BB02"}})
+	require.NoError(t, err)
+	refunds := []sdk.Msg{}
+	for _, amount := range []uint64{1000, 3000} {
+		refunds = append(refunds, banktypes.NewMsgSend(indexTestAddress(t, wallet), indexTestAddress(t, refundReceiver), sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(amount)))))
+	}
+	querier.outgoing = buildIndexTestTxs(t, 40, refunds, memo)
+
+	ledger := &mockLedger{}
+	_, err = relayMinter.rebuild(context.Background(), 0, 100, ledger, true)
+	require.NoError(t, err)
+
+	require.Equal(t, model.LedgerStatusRefunded, ledger.entries[0].Status)
+	require.Equal(t, sdk.NewIntFromUint64(1000), ledger.entries[0].Refunded)
+	require.Equal(t, model.LedgerStatusRefunded, ledger.entries[1].Status)
+	require.Equal(t, sdk.NewIntFromUint64(3000), ledger.entries[1].Refunded)
+}
+
+func TestShouldFailRebuildOfEmptyRange(t *testing.T) {
+	relayMinter, _, _, _ := newRebuildTestRelayMinter(t)
+
+	_, err := relayMinter.rebuild(context.Background(), 100, 100, &mockLedger{}, true)
+	require.Error(t, err)
+}
+
+// Building a relay minter with three payments. The first one at height 10 is refunded twice, the second one at height 30 is not processed
+// and the third one at height 50 is minted. There is a mint at height 60 of a payment before the rebuilt blocks.
+func newRebuildTestRelayMinter(t *testing.T) (*relayMinter, *mockTxSender, *mockEmailService, *mockBlockQuerier) {
+	relayMinter, mts, emailService, blockQuerier := newNodeStatusTestRelayMinter(t)
+	wallet := relayMinter.walletAddress.String()
+
+	payments := relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults
+	payments.Txs[0].Height = 10
+	payments.Txs = append(payments.Txs, copyRebuildTestTx(t, payments.Txs[0], "BB02", 30), copyRebuildTestTx(t, payments.Txs[0], "DD04", 50))
+
+	mint := marketplacetypes.NewMsgMintNft(wallet, "testdenom", "", "", "", "", "nftuid#1", sdk.NewCoin("acudos", sdk.NewIntFromUint64(100)))
+	outgoing := &ctypes.ResultTxSearch{}
+	for i, tx := range []struct {
+		height int64
+		msg    sdk.Msg
+		memo   string
+	}{
+		{20, newIndexTestRefund(t, wallet), batchTxHash},
+		{25, newIndexTestRefund(t, wallet), batchTxHash},
+		{55, mint, "DD04"},
+		{60, mint, "CC03"},
+	} {
+		result := buildIndexTestTxs(t, tx.height, []sdk.Msg{tx.msg}, tx.memo).Txs[0]
+		result.Hash = []byte{byte(i + 2)}
+		outgoing.Txs = append(outgoing.Txs, result)
+	}
+
+	relayMinter.txQuerier = &outgoingTxQuerier{txQuerier: relayMinter.txQuerier, sender: wallet, outgoing: outgoing}
+	return relayMinter, mts, emailService, blockQuerier
+}
+
+func copyRebuildTestTx(t *testing.T, result *ctypes.ResultTx, hash string, height int64) *ctypes.ResultTx {
+	hashBytes, err := hex.DecodeString(hash)
+	require.NoError(t, err)
+
+	return &ctypes.ResultTx{Hash: hashBytes, Height: height, Tx: result.Tx}
+}
+
+func (ml *mockLedger) WriteLedger(entries []model.LedgerEntry) error {
+	if ml.err != nil {
+		return ml.err
+	}
+
+	ml.entries = entries
+	return nil
+}
+
+type mockLedger struct {
+	entries []model.LedgerEntry
+	err     error
+}
@@ -2,21 +2,29 @@ package relayminter
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
 	queryacc "github.com/CudoVentures/cudos-ondemand-minting-service/internal/query/account"
+	queryallowance "github.com/CudoVentures/cudos-ondemand-minting-service/internal/query/allowance"
+	querybalance "github.com/CudoVentures/cudos-ondemand-minting-service/internal/query/balance"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/state"
 	relaytx "github.com/CudoVentures/cudos-ondemand-minting-service/internal/tx"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
 	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	"github.com/cosmos/cosmos-sdk/simapp/params"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/authz"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
@@ -26,20 +34,25 @@ import (
 
 // The RelayerMinter is responsible for cudos chain monitoring and minting of the NFTs to its owner.
 func NewRelayMinter(logger relayLogger, encodingConfig *params.EncodingConfig, cfg config.Config, stateStorage stateStorage,
-	nftDataClient nftDataClient, privKey *secp256k1.PrivKey, grpcConnector grpcConnector, rpcConnector rpcConnector, txCoder txCoder, emailService emailService) *relayMinter {
+	nftDataClient nftDataClient, walletKey walletKey, minterPrivKeys, retiredPrivKeys []*secp256k1.PrivKey, grpcConnector grpcConnector, rpcConnector rpcConnector, txCoder txCoder, emailService emailService,
+	quoteKey ed25519.PrivateKey, quoteStore quoteStore) *relayMinter {
 	return &relayMinter{
 		encodingConfig: encodingConfig,
 		config:         cfg,
 		stateStorage:   stateStorage,
-		privKey:        privKey,
+		walletKey:      walletKey,
 		nftDataClient:  nftDataClient,
 		logger:         logger,
-		walletAddress:  sdk.AccAddress(privKey.PubKey().Address()),
+		walletAddress:  sdk.AccAddress(walletKey.PubKey().Address()),
+		minters:        newMinterWallets(minterPrivKeys),
+		retired:        newMinterWallets(retiredPrivKeys),
 		grpcConnector:  grpcConnector,
 		rpcConnector:   rpcConnector,
 		txCoder:        txCoder,
 		retries:        0,
 		emailService:   emailService,
+		quoteKey:       quoteKey,
+		quoteStore:     quoteStore,
 	}
 }
 
@@ -73,26 +86,35 @@ func (rm *relayMinter) Start(ctx context.Context) {
 		}
 		defer grpcConn.Close()
 
-		node, err := rm.rpcConnector.MakeRPCClient(rm.config.ChainRPC)
+		closeQueriers, err := rm.connectQueriers(grpcConn)
 		if err != nil {
-			retry(fmt.Errorf("connecting (%s) failed: %s", rm.config.ChainRPC, err))
+			retry(err)
 			continue
 		}
-		defer node.Stop()
-
-		rm.txSender = relaytx.NewTxSender(
-			txtypes.NewServiceClient(grpcConn),
-			queryacc.NewAccountInfoClient(grpcConn, rm.encodingConfig),
-			rm.encodingConfig,
-			rm.privKey,
-			rm.config.ChainID,
-			rm.config.PaymentDenom,
-			gasPrice, gasAdjustment,
-			relaytx.NewTxSigner(rm.encodingConfig, rm.privKey),
-		)
-		rm.txQuerier = relaytx.NewTxQuerier(node)
-
-		rm.logger.Info("starting relayer loop")
+		defer closeQueriers()
+
+		if err := rm.connectWallets(ctx, grpcConn); err != nil {
+			retry(err)
+			continue
+		}
+		rm.setUpDecisions()
+
+		if rm.config.HasTxIndex() && rm.txIndex == nil {
+			txIndex, err := state.NewFileTxIndex(rm.config.TxIndexFile)
+			if err != nil {
+				retry(fmt.Errorf("loading tx index (%s) failed: %s", rm.config.TxIndexFile, err))
+				continue
+			}
+			rm.txIndex = txIndex
+		}
+
+		if rm.config.HasShadowMode() {
+			rm.logger.Info("running in shadow mode, nothing is broadcasted")
+		}
+		rm.logger.Infof("starting relayer loop of wallet %s with wallet pool %v", rm.walletAddress.String(), rm.walletAddresses())
+		if len(rm.retired) > 0 || len(rm.config.RetiredWalletAddresses) > 0 {
+			rm.logger.Infof("receiving payments of retired wallets %v and recognising transactions of retired wallets %v", rm.retiredAddresses(), rm.config.RetiredWalletAddresses)
+		}
 		err = rm.startRelaying(ctx)
 
 		if err == contextDone {
@@ -106,12 +128,97 @@ func (rm *relayMinter) Start(ctx context.Context) {
 	rm.logger.Info("stopping relayer")
 }
 
+// Creating the queriers of the transactions and the blocks. When querying by gRPC only, the RPC of the node is not dialed.
+// The returned function stops the RPC client.
+func (rm *relayMinter) connectQueriers(grpcConn *ggrpc.ClientConn) (func(), error) {
+	if rm.config.QueriesGRPC() {
+		rm.txQuerier = relaytx.NewGRPCTxQuerier(txtypes.NewServiceClient(grpcConn), rm.config.QueryPageRetries)
+		rm.blockQuerier = relaytx.NewGRPCBlockQuerier(tmservice.NewServiceClient(grpcConn))
+		return func() {}, nil
+	}
+
+	node, err := rm.rpcConnector.MakeRPCClient(rm.config.ChainRPC)
+	if err != nil {
+		return nil, fmt.Errorf("connecting (%s) failed: %s", rm.config.ChainRPC, err)
+	}
+
+	rm.txQuerier = rm.newTxQuerier(node)
+	rm.blockQuerier = relaytx.NewBlockQuerier(node)
+	return func() { node.Stop() }, nil
+}
+
+// Creating the tx senders of the wallets and the queriers of their balances and fee allowances.
+// The fee granter and the authz grant are validated and the fee allowances are checked before anything is sent.
+func (rm *relayMinter) connectWallets(ctx context.Context, grpcConn *ggrpc.ClientConn) error {
+	feeGranter, err := rm.feeGranterAddress()
+	if err != nil {
+		return err
+	}
+	rm.feeGranter = feeGranter
+
+	if err := rm.validateAuthzMinter(); err != nil {
+		return err
+	}
+
+	rm.sendersMu.Lock()
+	rm.txSender = rm.newTxSender(grpcConn, rm.walletKey)
+	for _, minter := range rm.minters {
+		minter.txSender = rm.newTxSender(grpcConn, minter.key)
+	}
+	for _, retired := range rm.retired {
+		retired.txSender = rm.newTxSender(grpcConn, retired.key)
+	}
+	rm.sendersMu.Unlock()
+	rm.balanceQuerier = querybalance.NewBalanceClient(grpcConn)
+	rm.sweepLog = state.NewFileSweepLog(rm.config.SweepLogFile)
+	rm.allowanceQuerier = queryallowance.NewAllowanceClient(grpcConn, rm.encodingConfig)
+
+	return rm.checkFeeAllowances(ctx)
+}
+
+func (rm *relayMinter) newTxSender(grpcConn *ggrpc.ClientConn, key walletKey) txSender {
+	return rm.shadowed(relaytx.NewTxSender(
+		txtypes.NewServiceClient(grpcConn),
+		queryacc.NewAccountInfoClient(grpcConn, rm.encodingConfig),
+		rm.encodingConfig,
+		key.PubKey(),
+		rm.config.ChainID,
+		rm.config.PaymentDenom,
+		rm.feeGranter,
+		gasPrice, gasAdjustment,
+		relaytx.NewTxSigner(rm.encodingConfig, key),
+	), sdk.AccAddress(key.PubKey().Address()).String())
+}
+
+// Creating the source of the payments and of the transactions of the idempotency checks.
+// The block scanner tracks all wallets the service has ever signed with and the authz minter, so it finds the same transactions as tx_search.
+func (rm *relayMinter) newTxQuerier(node *rpchttp.HTTP) txQuerier {
+	if !rm.config.ScansBlocks() {
+		return relaytx.NewTxQuerier(node, rm.config.QueryPageRetries)
+	}
+
+	return relaytx.NewBlockScanner(node, rm.trackedAddresses())
+}
+
 // Creating a ticker. It invokes the relayer function once per tick.
+// With a treasury configured a second ticker invokes the sweep. It runs between the relay ticks, so it never sees half processed payments.
 func (rm *relayMinter) startRelaying(ctx context.Context) error {
 	ticker := time.NewTicker(rm.config.RelayInterval)
 
+	// A shadow instance does not sweep, its sweeps would not be sent and only fill the sweep log
+	var sweeps <-chan time.Time
+	if rm.config.HasTreasurySweep() && !rm.config.HasShadowMode() {
+		sweepTicker := time.NewTicker(rm.config.SweepInterval)
+		defer sweepTicker.Stop()
+		sweeps = sweepTicker.C
+	}
+
 	for {
 		select {
+		case <-sweeps:
+			if err := rm.sweep(ctx); err != nil {
+				rm.reportSweepError(err)
+			}
 		case <-ticker.C:
 			if err := rm.relay(ctx); err != nil {
 				return err
@@ -128,9 +235,11 @@ func (rm *relayMinter) startRelaying(ctx context.Context) error {
 // Processing a single relay tick.
 //
 // Getting the transactions from last know processed block stored in the state up to latest block.
+// The blocks are queried and processed in windows of the configured size, see relayWindow. The state is updated after every window.
 // Processing transactions one by one. If there is an error in some of the steps in the following the algorithm then the relay stops:
 //
 // 1. Find the corresponding information in the memo of a transaction. If no such information is available then no futher processing is required and moves to next transaction.
+// Every transfer to the wallet inside the transaction is a separate payment and the following steps are done for each of them.
 //
 // 2. Checking if the transaction is a "minting transaction", which means whether this transaction resulted in a minted nft.
 // If so then no futher processsing is required because the NFT that is supposed to be minted by this transaction has already been minted. Proceed with next transaction.
@@ -145,108 +254,350 @@ func (rm *relayMinter) startRelaying(ctx context.Context) error {
 // 6. Checking if the NFT has ready been minted. If it is then the transaction is refunded. After the refund no further processing is required.
 //
 // 7. Trying to mint the NFT and refunding the transaction if minting is not successful.
+//
+// Nothing is processed while the node is catching up or its latest block is stale, see checkNode. Only the payments in blocks at least the confirmation depth
+// behind the latest block are processed.
+//
+// With the payments of every window the outgoing transactions of the service are added to the tx index, see indexWindow. The idempotency checks search the chain
+// only for the blocks after the index.
+//
+// Nothing is processed while a wallet is below the critical balance, see checkBalances. The state is not updated, so the payments are processed once it is topped up.
+//
+// When batching is enabled the mints and refunds of single NFT payments are collected during a window and sent in batch transactions at its end.
+// The payments are processed by a pool of workers, see processPayments.
 func (rm *relayMinter) relay(ctx context.Context) error {
 	rm.logger.Info("relay tick")
 	s, err := rm.stateStorage.GetState()
 	if err != nil {
 		return err
 	}
+	defer func() { rm.pruneTxs(s.Height) }()
 
-	rm.logger.Infof("check events after %d of wallet %s", s.Height, rm.walletAddress.String())
-	results, err := rm.txQuerier.Query(ctx, fmt.Sprintf("tx.height>%d AND transfer.recipient='%s'", s.Height, rm.walletAddress.String()))
+	confirmedHeight, ready, err := rm.checkNode(ctx)
 	if err != nil {
 		return err
 	}
 
-	if results == nil || len(results.Txs) == 0 {
+	if !ready {
+		rm.logger.Info("relaying is paused because the node is not synced")
+		return nil
+	}
+
+	if confirmedHeight <= s.Height {
 		rm.logger.Info("there is nothing to process")
 		return nil
 	}
 
-	rm.logger.Infof("successfully got %d events", len(results.Txs))
-	sort.Slice(results.Txs, func(i, j int) bool {
-		return results.Txs[i].Height < results.Txs[j].Height
-	})
+	paused, err := rm.checkBalances(ctx)
+	if err != nil {
+		return err
+	}
 
-	for i, result := range results.Txs {
-		incomingPaymentTxHash := result.Hash.String()
-		incomingPaymentTxHeight := result.Height
-		sendInfo, err := rm.getReceivedBankSendInfo(result)
-		if err != nil {
-			rm.logger.Warnf("getting received bank send info for tx(%s) failed: %s", incomingPaymentTxHash, err)
-			continue
+	if paused {
+		rm.logger.Info("relaying is paused because of low balance")
+		return nil
+	}
+
+	// Processing the blocks window by window, so a large backlog is not loaded at once and the progress is kept if a later window fails
+	for s.Height < confirmedHeight {
+		windowEnd := confirmedHeight
+		if rm.config.QueryWindow > 0 && s.Height+rm.config.QueryWindow < confirmedHeight {
+			windowEnd = s.Height + rm.config.QueryWindow
 		}
-		rm.logger.Infof("%d: processing incomingPaymentTxHash(%s) at height(%d) with payment(%s)", i+1, incomingPaymentTxHash, result.Height, sendInfo.String())
 
-		isMintingTransaction, err := rm.isMintingTransaction(ctx, sendInfo.Memo.RecipientAddress, incomingPaymentTxHash, incomingPaymentTxHeight)
-		if err != nil {
+		// The indexer of the node may lag behind its latest block, so a window reaching it is settled only up to its last payment
+		indexed := rm.config.ConfirmationDepth > 0 || windowEnd < confirmedHeight
+		if s, err = rm.relayWindow(ctx, s, windowEnd, indexed); err != nil {
 			return err
 		}
 
-		if isMintingTransaction {
-			rm.logger.Infof("transaction(%s) has already been successfully processed and it results to a minted nft to a buyer (%s)", incomingPaymentTxHash, sendInfo.Memo.RecipientAddress)
-			continue
+		if s.Height < windowEnd {
+			break
 		}
+	}
 
-		isRefunded, err := rm.isRefunded(ctx, incomingPaymentTxHash, incomingPaymentTxHeight, sendInfo.FromAddress)
-		if err != nil {
-			return err
+	return nil
+}
+
+// Dropping the scanned transactions up to the settled height. The next tick and the sweep only query the blocks after it.
+func (rm *relayMinter) pruneTxs(settledHeight int64) {
+	if pruner, ok := rm.txQuerier.(txPruner); ok {
+		pruner.Prune(settledHeight)
+	}
+}
+
+// Processing the payments after the height in the state up to the end of the window and updating the state.
+// If all transactions of the window are indexed then the state is advanced to the end of the window, otherwise to the last payment in it.
+// The outgoing transactions of the service up to that height are added to the tx index before the payments are processed, see indexWindow.
+func (rm *relayMinter) relayWindow(ctx context.Context, s model.State, windowEnd int64, indexed bool) (model.State, error) {
+	txs, err := rm.queryPaymentTransactions(ctx, s.Height, windowEnd)
+	if err != nil {
+		return s, err
+	}
+
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].Height < txs[j].Height
+	})
+
+	// The height the state is advanced to once all payments of the window are processed
+	windowSettledHeight := windowEnd
+	if !indexed {
+		windowSettledHeight = s.Height
+		if len(txs) > 0 {
+			windowSettledHeight = txs[len(txs)-1].Height
 		}
+	}
 
-		if isRefunded {
-			rm.logger.Infof("transaction(%s) has already been refunded to buyer(%s)", incomingPaymentTxHash, sendInfo.FromAddress)
-			continue
+	if err := rm.indexWindow(ctx, s.Height, windowSettledHeight); err != nil {
+		return s, err
+	}
+
+	if len(txs) == 0 {
+		if !indexed {
+			rm.logger.Info("there is nothing to process")
+			return s, nil
 		}
 
-		onCudos, _ := sdk.NewIntFromString("1000000000000000000")
+		s.Height = windowEnd
+		rm.logger.Info(fmt.Sprintf("there is nothing to process, update state to %d", s.Height))
+		return s, rm.stateStorage.UpdateState(s)
+	}
 
-		nftData, err := rm.GetNFTData(ctx, rm.config, sendInfo.Memo.UID, sendInfo.Memo.RecipientAddress, sendInfo.Amount.Sub(sdk.NewCoin("acudos", onCudos)))
-		if err != nil {
-			return err
+	rm.logger.Infof("successfully got %d events", len(txs))
+
+	if rm.config.HasBatchTxs() {
+		rm.batch = newTxBatch()
+		defer func() { rm.batch = nil }()
+	}
+
+	settledHeight, err := rm.processPayments(ctx, rm.pendingPayments(txs))
+	if err != nil {
+		// The queued mints and refunds of the batch are not sent, so none of the payments is settled
+		if rm.batch == nil && settledHeight > s.Height {
+			s.Height = settledHeight
+			rm.logger.Info(fmt.Sprintf("update state to settled height %d", s.Height))
+			if errState := rm.stateStorage.UpdateState(s); errState != nil {
+				return s, fmt.Errorf("%s, failed to update state: %s", err, errState)
+			}
+		}
+
+		return s, err
+	}
+
+	if rm.batch != nil {
+		if err := rm.flushBatch(ctx); err != nil {
+			return s, err
 		}
-		rm.logger.Infof("NFT Data(%s)", nftData.String())
+	}
+
+	// Update the height in state with the latest one from results because there will be no txs with lower height since blocks are finalized
+	s.Height = windowSettledHeight
+
+	rm.logger.Info(fmt.Sprintf("update state to %d", s.Height))
+	return s, rm.stateStorage.UpdateState(s)
+}
 
-		isMintedNft, err := rm.isMintedNft(ctx, nftData.Id, incomingPaymentTxHeight)
+// Extracting the payments of the transactions. Transactions whose transfers cannot be read are skipped.
+func (rm *relayMinter) pendingPayments(txs []*ctypes.ResultTx) []pendingPayment {
+	payments := []pendingPayment{}
+	for i, result := range txs {
+		sendInfos, err := rm.getReceivedBankSendInfos(result)
 		if err != nil {
-			return err
+			rm.logger.Warnf("getting received bank send info for tx(%s) failed: %s", result.Hash.String(), err)
+			continue
 		}
 
-		if isMintedNft {
-			if err := rm.refund(ctx, incomingPaymentTxHash, sendInfo.FromAddress, sendInfo.Amount); err != nil {
-				return fmt.Errorf("%s, failed to refund as it was already minted", err)
-			}
+		for _, sendInfo := range sendInfos {
+			payments = append(payments, pendingPayment{sendInfo: sendInfo, height: result.Height, txIndex: i})
+		}
+	}
 
+	return payments
+}
+
+// Fetching the transactions after the given height up to the confirmed height that transfer funds to the payment wallet or to any retired wallet still receiving payments.
+// A transaction transferring to several of these wallets is returned once, by the query of the first of them.
+func (rm *relayMinter) queryPaymentTransactions(ctx context.Context, height, confirmedHeight int64) ([]*ctypes.ResultTx, error) {
+	txs := []*ctypes.ResultTx{}
+	seen := map[string]bool{}
+
+	for _, address := range rm.receivingAddresses() {
+		rm.logger.Infof("check events after %d up to %d of wallet %s", height, confirmedHeight, address)
+		results, err := rm.txQuerier.Query(ctx, fmt.Sprintf("tx.height>%d AND tx.height<=%d AND transfer.recipient='%s'", height, confirmedHeight, address))
+		if err != nil {
+			return nil, err
+		}
+
+		if results == nil {
 			continue
 		}
 
-		if errMint := rm.mint(ctx, incomingPaymentTxHash, nftData.Id, sendInfo.Memo.RecipientAddress, nftData, sendInfo.Amount); errMint != nil {
-			errMint = fmt.Errorf("failed to mint: %s", errMint)
-			rm.logger.Warnf("minting of NFT(%s) failed from address(%s) with tx incomingPaymentTxHash(%s) with error[%v]", nftData.Id, sendInfo.FromAddress, incomingPaymentTxHash, errMint)
-			if errRefund := rm.refund(ctx, incomingPaymentTxHash, sendInfo.FromAddress, sendInfo.Amount); errRefund != nil {
-				return fmt.Errorf("%s, failed to refund after unsuccessful minting: %s", errMint, errRefund)
+		for _, result := range results.Txs {
+			if !seen[result.Hash.String()] {
+				txs = append(txs, result)
 			}
 		}
+
+		// Only the transactions already returned for another wallet are skipped
+		for _, result := range results.Txs {
+			seen[result.Hash.String()] = true
+		}
 	}
 
-	// Update the height in state with the latest one from results because there will be no txs with lower height since blocks are finalized
-	s.Height = results.Txs[len(results.Txs)-1].Height
+	return txs, nil
+}
 
-	rm.logger.Info(fmt.Sprintf("update state to %d", s.Height))
-	return rm.stateStorage.UpdateState(s)
+// Processing a single payment extracted from an incoming transaction.
+// The reference of the payment is used as memo of the mint and refund transactions, so it is used by the idempotency checks as well.
+func (rm *relayMinter) processPayment(ctx context.Context, sendInfo receivedBankSend, incomingPaymentTxHeight int64) error {
+	if sendInfo.Memo.IsCart() {
+		return rm.processCart(ctx, sendInfo, incomingPaymentTxHeight)
+	}
+
+	incomingPaymentTxHash := sendInfo.Ref()
+
+	isMintingTransaction, err := rm.isMintingTransaction(ctx, sendInfo.Memo.RecipientAddress, incomingPaymentTxHash, incomingPaymentTxHeight)
+	if err != nil {
+		return err
+	}
+
+	if isMintingTransaction {
+		rm.logger.Infof("transaction(%s) has already been successfully processed and it results to a minted nft to a buyer (%s)", incomingPaymentTxHash, sendInfo.Memo.RecipientAddress)
+		rm.recordDecision(newDecision(sendInfo, model.ReasonAlreadyMinted, nil), model.DecisionSkip)
+		return nil
+	}
+
+	isRefunded, err := rm.isRefunded(ctx, incomingPaymentTxHash, incomingPaymentTxHeight, sendInfo.FromAddress)
+	if err != nil {
+		return err
+	}
+
+	if isRefunded {
+		rm.logger.Infof("transaction(%s) has already been refunded to buyer(%s)", incomingPaymentTxHash, sendInfo.FromAddress)
+		rm.recordDecision(newDecision(sendInfo, model.ReasonAlreadyRefunded, nil), model.DecisionSkip)
+		return nil
+	}
+
+	if rm.refundsRetiredPayment(sendInfo) {
+		rm.logger.Infof("refunding transaction(%s) received by retired wallet (%s)", incomingPaymentTxHash, sendInfo.ToAddress)
+		return rm.refundPayment(ctx, sendInfo, model.ReasonRetiredWallet, "", nil)
+	}
+
+	var quote model.Quote
+	if sendInfo.Memo.IsQuoted() {
+		if quote, err = rm.resolveQuote(sendInfo.Memo.QuoteID); err != nil {
+			rm.logger.Warnf("refunding transaction(%s) with invalid quote: %s", incomingPaymentTxHash, err)
+			return rm.refundPayment(ctx, sendInfo, model.ReasonInvalidQuote, err.Error(), nil)
+		}
+	}
+
+	paidAt, err := rm.blockQuerier.BlockTime(ctx, incomingPaymentTxHeight)
+	if err != nil {
+		return err
+	}
+	sendInfo.PaidAt = paidAt
+
+	onCudos, _ := sdk.NewIntFromString("1000000000000000000")
+
+	nftData, err := rm.GetNFTData(ctx, rm.config, sendInfo.Memo.UID, sendInfo.Memo.RecipientAddress, sendInfo.Amount.Sub(sdk.NewCoin("acudos", onCudos)), paidAt)
+	var untrustedErr *model.UntrustedQuoteError
+	if errors.As(err, &untrustedErr) {
+		rm.logger.Warnf("refunding transaction(%s) with untrusted quote of the AuraPool: %s", incomingPaymentTxHash, err)
+		return rm.refundPayment(ctx, sendInfo, model.ReasonUntrustedAuraPoolQuote, err.Error(), nil)
+	}
+	if err != nil {
+		return err
+	}
+	rm.logger.Infof("NFT Data(%s)", nftData.String())
+
+	validAt := rm.priceValidAt(paidAt)
+	if sendInfo.Memo.IsQuoted() {
+		nftData = rm.applyQuote(quote, nftData, validAt)
+	}
+
+	isMintedNft, err := rm.isMintedNft(ctx, nftData.Id, incomingPaymentTxHeight)
+	if err != nil {
+		return err
+	}
+
+	if isMintedNft {
+		if err := rm.refundPayment(ctx, sendInfo, model.ReasonNftMinted, "", []model.NFTData{nftData}); err != nil {
+			return fmt.Errorf("%s, failed to refund as it was already minted", err)
+		}
+
+		return nil
+	}
+
+	if errMint := rm.mintPayment(ctx, sendInfo, nftData, validAt); errMint != nil {
+		errMint = fmt.Errorf("failed to mint: %s", errMint)
+		rm.logger.Warnf("minting of NFT(%s) failed from address(%s) with tx incomingPaymentTxHash(%s) with error[%v]", nftData.Id, sendInfo.FromAddress, incomingPaymentTxHash, errMint)
+		if errRefund := rm.refundPayment(ctx, sendInfo, model.ReasonMintFailed, errMint.Error(), []model.NFTData{nftData}); errRefund != nil {
+			return fmt.Errorf("%s, failed to refund after unsuccessful minting: %s", errMint, errRefund)
+		}
+	}
+
+	return nil
 }
 
 // Mints the NFT
+// The NFT data is expected to be validated by validateNftData.
+// The hash of incoming transaction is added as memo of the mint transaction. The decision is logged once the transaction is sent.
+func (rm *relayMinter) mint(ctx context.Context, incomingPaymentTxHash string, uid, recipient string, nftData model.NFTData, amount sdk.Coin, decision model.Decision) error {
+	wallet := rm.nextWallet()
+	msgMintNft, gasResult, err := rm.prepareMint(ctx, wallet, uid, recipient, nftData, amount)
+	if err != nil {
+		return err
+	}
+
+	txHash, err := wallet.txSender.SendTx(ctx, []sdk.Msg{msgMintNft}, incomingPaymentTxHash, gasResult)
+	if err != nil {
+		return err
+	}
+
+	rm.logger.Infof("success mint tx %s", txHash)
+	rm.recordDecision(sentDecision(decision, txHash, gasResult), model.DecisionMint)
+	return nil
+}
+
+// Building the mint message of the NFT signed by the given wallet and estimating its gas.
+// The received amount must cover the price of the NFT and the gas. In authz mode the returned message is the MsgExec wrapping the mint.
+func (rm *relayMinter) prepareMint(ctx context.Context, wallet *minterWallet, uid, recipient string, nftData model.NFTData, amount sdk.Coin) (sdk.Msg, model.GasResult, error) {
+	msgMintNft := rm.wrapMints(wallet, []sdk.Msg{rm.newMintMsg(wallet, uid, recipient, nftData)})[0]
+	gasResult, err := wallet.txSender.EstimateGas(ctx, []sdk.Msg{msgMintNft}, "")
+	if err != nil {
+		return nil, model.GasResult{}, err
+	}
+
+	gas := sdk.NewIntFromUint64(gasResult.GasLimit).Mul(sdk.NewIntFromUint64(gasPrice))
+	if gas.GT(amount.Amount) {
+		return nil, model.GasResult{}, fmt.Errorf("during mint received amount (%s) is smaller than the gas (%s)", amount.Amount.String(), gas.String())
+	}
+
+	amountWithoutGas := amount.Amount.Sub(gas)
+	if amountWithoutGas.LT(nftData.Price) {
+		return nil, model.GasResult{}, fmt.Errorf("during mint received amount without gas (%s) is smaller than price (%s)", amountWithoutGas.String(), nftData.Price.String())
+	}
+
+	return msgMintNft, gasResult, nil
+}
+
+// The time the price of a payment has to be valid at. It is the time of the block of the payment less the grace window,
+// so a payment processed late is not refunded and a small clock skew between the chain and the AuraPool is absorbed.
+func (rm *relayMinter) priceValidAt(paidAt time.Time) time.Time {
+	return paidAt.Add(-rm.config.PriceValidityGrace)
+}
+
+// Checking whether the NFT data received by the AuraPool can be minted. The price has to be valid at the given time.
 // If nft data received by the AuraPool is empty then return an error which will lead to a refund.
-// The hash of incoming transaction is added as memo of the mint transaction
-func (rm *relayMinter) mint(ctx context.Context, incomingPaymentTxHash string, uid, recipient string, nftData model.NFTData, amount sdk.Coin) error {
+func validateNftData(uid string, nftData model.NFTData, validAt time.Time) error {
 	emptyNftData := model.NFTData{}
 
 	if nftData == emptyNftData {
 		return fmt.Errorf("nft (%s) was not found", uid)
 	}
 
-	// this check is in AuraPool, but it can stay here just in case
-	if nftData.PriceValidUntil < time.Now().UnixMilli() {
+	// this check is in AuraPool too, it is evaluated against the time of the payment there as well
+	if nftData.PriceValidUntil < validAt.UnixMilli() {
 		return fmt.Errorf("NftPrice valid time expired. Not minting it")
 	}
 
@@ -255,66 +606,64 @@ func (rm *relayMinter) mint(ctx context.Context, incomingPaymentTxHash string, u
 		return fmt.Errorf("nft (%s) has invalid status (%s)", uid, nftData.Status)
 	}
 
-	msgMintNft := marketplacetypes.NewMsgMintNft(rm.walletAddress.String(), nftData.DenomID, recipient, nftData.Name, nftData.Uri, nftData.Data, uid, sdk.NewCoin("acudos", nftData.Price))
-	gasResult, err := rm.txSender.EstimateGas(ctx, []sdk.Msg{msgMintNft}, "")
+	return nil
+}
+
+// Refunds the user.
+// The refunded amount is equal to incoming funds - refund transaction costs. This is so in order not to prevent draining of service's wallet funds.
+// The hash of incoming transaction is added as memo of the refund transaction. Refunds of cart items have memo that lists the refunded items as well.
+// The refund is sent by a wallet of the pool, unless the payment was received by a retired wallet which then refunds it from its own funds.
+// The decision is logged once the transaction is sent, or as a skip if the refund is below the minimum.
+func (rm *relayMinter) refund(ctx context.Context, incomingPaymentTxHash, receivedBy, refundReceiver string, amount sdk.Coin, decision model.Decision) error {
+	wallet := rm.refundWallet(receivedBy)
+	msgSend, gasResult, err := rm.prepareRefund(ctx, wallet, incomingPaymentTxHash, refundReceiver, amount)
 	if err != nil {
 		return err
 	}
 
-	gas := sdk.NewIntFromUint64(gasResult.GasLimit).Mul(sdk.NewIntFromUint64(gasPrice))
-	if gas.GT(amount.Amount) {
-		return fmt.Errorf("during mint received amount (%s) is smaller than the gas (%s)", amount.Amount.String(), gas.String())
-	}
-
-	amountWithoutGas := amount.Amount.Sub(gas)
-	if amountWithoutGas.LT(nftData.Price) {
-		return fmt.Errorf("during mint received amount without gas (%s) is smaller than price (%s)", amountWithoutGas.String(), nftData.Price.String())
+	if msgSend == nil {
+		rm.recordDecision(belowMinimumRefund(decision), model.DecisionSkip)
+		return nil
 	}
 
-	txHash, err := rm.txSender.SendTx(ctx, []sdk.Msg{msgMintNft}, incomingPaymentTxHash, gasResult)
+	refundTxHash, err := wallet.txSender.SendTx(ctx, []sdk.Msg{msgSend}, incomingPaymentTxHash, gasResult)
 	if err != nil {
 		return err
 	}
 
-	rm.logger.Infof("success mint tx %s", txHash)
+	rm.logger.Info(fmt.Sprintf("successfull refund incomingPaymentTxHash(%s) to address(%s) with refund tx hash(%s)", incomingPaymentTxHash, refundReceiver, refundTxHash))
+	decision.Refunded = msgSend.Amount
+	rm.recordDecision(sentDecision(decision, refundTxHash, gasResult), model.DecisionRefund)
 	return nil
 }
 
-// Refunds the user.
-// The refunded amount is equal to incoming funds - refund transaction costs. This is so in order not to prevent draining of service's wallet funds.
-// The hash of incoming transaction is added as memo of the refund transaction
-func (rm *relayMinter) refund(ctx context.Context, incomingPaymentTxHash, refundReceiver string, amount sdk.Coin) error {
-	walletAddress, err := sdk.AccAddressFromBech32(rm.walletAddress.String())
+// Building the refund message sent by the given wallet and estimating its gas.
+// No message is returned if the refunded amount without the gas is smaller than the minimum refund amount.
+func (rm *relayMinter) prepareRefund(ctx context.Context, wallet *minterWallet, incomingPaymentTxHash, refundReceiver string, amount sdk.Coin) (*banktypes.MsgSend, model.GasResult, error) {
+	walletAddress, err := sdk.AccAddressFromBech32(wallet.address.String())
 	if err != nil {
-		return fmt.Errorf("invalid wallet address (%s) during refund: %s", rm.walletAddress, err)
+		return nil, model.GasResult{}, fmt.Errorf("invalid wallet address (%s) during refund: %s", wallet.address, err)
 	}
 
 	refundAddress, err := sdk.AccAddressFromBech32(refundReceiver)
 	if err != nil {
-		return fmt.Errorf("invalid refund receiver address (%s) during refund: %s", refundReceiver, err)
+		return nil, model.GasResult{}, fmt.Errorf("invalid refund receiver address (%s) during refund: %s", refundReceiver, err)
 	}
 
 	msgSend := banktypes.NewMsgSend(walletAddress, refundAddress, sdk.NewCoins(amount))
-	gasResult, err := rm.txSender.EstimateGas(ctx, []sdk.Msg{msgSend}, incomingPaymentTxHash)
+	gasResult, err := wallet.txSender.EstimateGas(ctx, []sdk.Msg{msgSend}, incomingPaymentTxHash)
 	if err != nil {
-		return err
+		return nil, model.GasResult{}, err
 	}
 
 	amountWithoutGas := amount.Amount.Sub(sdk.NewIntFromUint64(gasResult.GasLimit).Mul(sdk.NewIntFromUint64(gasPrice)))
 	// We want to have some min refund amount to prevent DoS
 	if amountWithoutGas.LT(sdk.NewIntFromUint64(minRefundAmount)) {
 		rm.logger.Error(fmt.Errorf("during refund received amount without gas (%d) is smaller than minimum refund amount (%d)", amountWithoutGas.Int64(), minRefundAmount))
-		return nil
-	}
-
-	msgSend = banktypes.NewMsgSend(walletAddress, refundAddress, sdk.NewCoins(sdk.NewCoin(rm.config.PaymentDenom, amountWithoutGas)))
-	refundTxHash, err := rm.txSender.SendTx(ctx, []sdk.Msg{msgSend}, incomingPaymentTxHash, gasResult)
-	if err != nil {
-		return err
+		return nil, model.GasResult{}, nil
 	}
 
-	rm.logger.Info(fmt.Sprintf("successfull refund incomingPaymentTxHash(%s) to address(%s) with refund tx hash(%s)", incomingPaymentTxHash, refundReceiver, refundTxHash))
-	return nil
+	return banktypes.NewMsgSend(walletAddress, refundAddress, sdk.NewCoins(sdk.NewCoin(rm.config.PaymentDenom, amountWithoutGas))), gasResult, nil
 }
 
 // Checking if an NFT has already been minted.
@@ -326,6 +675,11 @@ func (rm *relayMinter) isMintedNft(ctx context.Context, uid string, incomingPaym
 		return false, nil
 	}
 
+	if rm.batch != nil && rm.batch.hasMint(uid) {
+		rm.logger.Infof("Minted NFT(%s): true [queued in batch]", uid)
+		return true, nil
+	}
+
 	results, err := rm.queryNftMintTransactionByUid(ctx, uid, incomingPaymentTxHashHeight, "minted")
 	if err != nil {
 		return false, err
@@ -346,14 +700,13 @@ func (rm *relayMinter) isMintedNft(ctx context.Context, uid string, incomingPaym
 // This is TRUE because a mint transaction has a memo = incoming transaction's hash
 func (rm *relayMinter) isMintingTransaction(ctx context.Context, buyerAddress, incomingPaymentTxHash string, incomingPaymentTxHashHeight int64) (bool, error) {
 	rm.logger.Infof("checking whether %s is minting transaction", incomingPaymentTxHash)
-	results, err := rm.queryNftMintTransactionByBuyer(ctx, buyerAddress, incomingPaymentTxHashHeight, "minting transaction")
+	results, err := rm.queryNftMintTransactionByBuyer(ctx, buyerAddress, incomingPaymentTxHash, incomingPaymentTxHashHeight, "minting transaction")
 	if err != nil {
 		return false, err
 	}
 
 	for _, result := range results {
-		tx := result.TxWithMemo
-		if tx.GetMemo() == incomingPaymentTxHash {
+		if _, ok := findOutgoingMemo(result.Memo, incomingPaymentTxHash); ok {
 			rm.logger.Infof("%s is minting tx: true [%s]", incomingPaymentTxHash, result.Hash)
 			return true, nil
 		}
@@ -369,91 +722,99 @@ func (rm *relayMinter) isMintingTransaction(ctx context.Context, buyerAddress, i
 // This is TRUE because a refund transaction has a memo = incoming transaction's hash
 func (rm *relayMinter) isRefunded(ctx context.Context, incomingPaymentTxHash string, incomingPaymentTxHeight int64, refundReceiver string) (bool, error) {
 	rm.logger.Infof("checking whether %s is refunded", incomingPaymentTxHash)
-	results, err := rm.txQuerier.Query(ctx, fmt.Sprintf("tx.height>=%d AND transfer.sender='%s' AND transfer.recipient='%s'", incomingPaymentTxHeight, rm.walletAddress, refundReceiver))
+	results, err := rm.queryRefundTransactions(ctx, incomingPaymentTxHash, incomingPaymentTxHeight, refundReceiver)
 	if err != nil {
 		return false, err
 	}
 
-	if results != nil && len(results.Txs) > 0 {
-		// Errors won't propagandate to the callers, because we don't wanna retry on errors related to parsing the tx
-		// The only case we would have errors here is if some attacker manages to generate tx that is returned by above query
-		for _, result := range results.Txs {
-			txWithMemo, err := rm.decodeTx(result)
-			if err != nil {
-				rm.logger.Warnf("during check if refunded, decoding tx (%s) failed: %s", result.Hash.String(), err)
-				continue
-			}
-
-			msgs := txWithMemo.GetMsgs()
-			if len(msgs) != 1 {
-				rm.logger.Warnf("during check if refunded for tx(%s), refund bank send should contain exactly one message but instead it contains %d", result.Hash.String(), len(msgs))
-				continue
-			}
+	if len(results) > 0 {
+		rm.logger.Infof("%s refunded: true [%s]", incomingPaymentTxHash, results[0].Hash)
+		return true, nil
+	}
 
-			bankSendMsg, ok := msgs[0].(*banktypes.MsgSend)
-			if !ok {
-				rm.logger.Warnf("during check if refunded for tx(%s), refund bank send not valid bank send", result.Hash.String())
-				continue
-			}
+	rm.logger.Infof("%s refunded: false", incomingPaymentTxHash)
+	return false, nil
+}
 
-			if bankSendMsg.FromAddress != rm.walletAddress.String() {
-				rm.logger.Warnf("during check if refunded for tx(%s), refund bank send from expected %s but actual is %s", result.Hash.String(), rm.walletAddress.String(), bankSendMsg.FromAddress)
-				continue
-			}
+// Fetching the refund transactions of an incoming transaction.
+// These are the bank sends from any wallet of the pool or retired wallet to the refund receiver with memo that references the incoming transaction.
+// The refunds in the blocks covered by the tx index are taken from it, see outgoingSearchHeight.
+func (rm *relayMinter) queryRefundTransactions(ctx context.Context, incomingPaymentTxHash string, incomingPaymentTxHeight int64, refundReceiver string) ([]*decodedTxWithMemo, error) {
+	resultingArray := make([](*decodedTxWithMemo), 0)
 
-			if bankSendMsg.ToAddress != refundReceiver {
-				rm.logger.Warnf("during check if refunded for tx(%s), refund bank send to expected %s but actual is %s", result.Hash.String(), refundReceiver, bankSendMsg.ToAddress)
-				continue
-			}
+	searchHeight, indexed := rm.outgoingSearchHeight(incomingPaymentTxHeight)
+	if indexed {
+		resultingArray = append(resultingArray, rm.indexedRefundTxs(incomingPaymentTxHash, incomingPaymentTxHeight, refundReceiver)...)
+	}
 
-			if txWithMemo.GetMemo() == incomingPaymentTxHash {
-				rm.logger.Infof("%s refunded: true [%s]", incomingPaymentTxHash, result.Hash.String())
-				return true, nil
-			}
+	for _, walletAddress := range rm.knownWalletAddresses() {
+		results, err := rm.queryWalletRefundTransactions(ctx, walletAddress, incomingPaymentTxHash, searchHeight, refundReceiver)
+		if err != nil {
+			return resultingArray, err
 		}
+
+		resultingArray = append(resultingArray, results...)
 	}
 
-	rm.logger.Infof("%s refunded: false", incomingPaymentTxHash)
-	return false, nil
+	return resultingArray, nil
 }
 
-// Fetching marketplace transactions from the chain by nft's id
-func (rm *relayMinter) queryNftMintTransactionByUid(ctx context.Context, uid string, incomingPaymentTxHeight int64, logInfo string) ([]*decodedTxWithMemo, error) {
+// Fetching the refund transactions of an incoming transaction sent by a single wallet.
+func (rm *relayMinter) queryWalletRefundTransactions(ctx context.Context, walletAddress, incomingPaymentTxHash string, incomingPaymentTxHeight int64, refundReceiver string) ([]*decodedTxWithMemo, error) {
 	resultingArray := make([](*decodedTxWithMemo), 0)
 
-	results, err := rm.txQuerier.Query(ctx, fmt.Sprintf("tx.height>=%d AND marketplace_mint_nft.uid='%s'", incomingPaymentTxHeight, uid))
+	results, err := rm.txQuerier.Query(ctx, fmt.Sprintf("tx.height>=%d AND transfer.sender='%s' AND transfer.recipient='%s'", incomingPaymentTxHeight, walletAddress, refundReceiver))
 	if err != nil {
 		return resultingArray, err
 	}
 
 	if results != nil && len(results.Txs) > 0 {
+		// Errors won't propagandate to the callers, because we don't wanna retry on errors related to parsing the tx
+		// The only case we would have errors here is if some attacker manages to generate tx that is returned by above query
 		for _, result := range results.Txs {
-			tx, err := rm.decodeTx(result)
+			txWithMemo, err := rm.decodeTx(result)
 			if err != nil {
-				rm.logger.Warnf("during check if %s, decoding tx (%s) failed: %s", logInfo, result.Hash.String(), err)
+				rm.logger.Warnf("during check if refunded, decoding tx (%s) failed: %s", result.Hash.String(), err)
 				continue
 			}
 
-			msgs := tx.GetMsgs()
-			if len(msgs) != 1 {
-				rm.logger.Warnf("during check if %s for tx(%s), expected one message but got %d", logInfo, result.Hash.String(), len(msgs))
+			// Sweeps are sent by the payment wallet as well, but they never refund a payment
+			if isSweepMemo(txWithMemo.GetMemo()) {
 				continue
 			}
 
-			mintMsg, ok := msgs[0].(*marketplacetypes.MsgMintNft)
-			if !ok {
-				rm.logger.Warnf("during check if %s for tx(%s), message was not mint msg", logInfo, result.Hash.String())
-				continue
+			// Batch refunds have a bank send per refunded payment, so every message to the refund receiver is matched
+			msgs := flattenExecMsgs(txWithMemo.GetMsgs())
+			decodedTx := NewDecodedTxWithMemo(result.Hash.String(), txWithMemo)
+			for _, msg := range msgs {
+				bankSendMsg, ok := msg.(*banktypes.MsgSend)
+				if !ok {
+					rm.logger.Warnf("during check if refunded for tx(%s), refund bank send not valid bank send", result.Hash.String())
+					continue
+				}
+
+				if bankSendMsg.FromAddress != walletAddress {
+					rm.logger.Warnf("during check if refunded for tx(%s), refund bank send from expected %s but actual is %s", result.Hash.String(), walletAddress, bankSendMsg.FromAddress)
+					continue
+				}
+
+				if bankSendMsg.ToAddress != refundReceiver {
+					// The other bank sends of a batch refund are to other receivers
+					if len(msgs) == 1 {
+						rm.logger.Warnf("during check if refunded for tx(%s), refund bank send to expected %s but actual is %s", result.Hash.String(), refundReceiver, bankSendMsg.ToAddress)
+					}
+					continue
+				}
+
+				decodedTx.BankSendMsgs = append(decodedTx.BankSendMsgs, bankSendMsg)
 			}
 
-			// TO DO: Why we are checking the creator?
-			if mintMsg.Creator != rm.walletAddress.String() {
-				rm.logger.Warnf("during check if %s for tx(%s), creator (%s) of the mint msg is not equal to wallet (%s)", logInfo, result.Hash.String(), mintMsg.Creator, rm.walletAddress.String())
+			if len(decodedTx.BankSendMsgs) == 0 {
 				continue
 			}
 
-			if mintMsg.Uid == uid {
-				resultingArray = append(resultingArray, NewDecodedTxWithMemo(result.Hash.String(), tx))
+			if _, ok := findOutgoingMemo(txWithMemo.GetMemo(), incomingPaymentTxHash); ok {
+				resultingArray = append(resultingArray, decodedTx)
 			}
 		}
 	}
@@ -461,11 +822,51 @@ func (rm *relayMinter) queryNftMintTransactionByUid(ctx context.Context, uid str
 	return resultingArray, nil
 }
 
+// Fetching marketplace transactions from the chain by nft's id
+// The mints in the blocks covered by the tx index are taken from it, see outgoingSearchHeight.
+func (rm *relayMinter) queryNftMintTransactionByUid(ctx context.Context, uid string, incomingPaymentTxHeight int64, logInfo string) ([]*decodedTxWithMemo, error) {
+	indexedResults := []*decodedTxWithMemo{}
+	searchHeight, indexed := rm.outgoingSearchHeight(incomingPaymentTxHeight)
+	if indexed {
+		indexedResults = indexedMintTxs(rm.txIndex.TxsByUid(uid), incomingPaymentTxHeight, func(mint model.IndexedMint) bool {
+			return mint.Uid == uid
+		})
+	}
+
+	query := fmt.Sprintf("tx.height>=%d AND marketplace_mint_nft.uid='%s'", searchHeight, uid)
+	results, err := rm.queryNftMintTransactions(ctx, query, logInfo, func(mintMsg *marketplacetypes.MsgMintNft) bool {
+		return mintMsg.Uid == uid
+	})
+
+	return append(indexedResults, results...), err
+}
+
 // Fetching marketplace transactions from the chain by buyer's address
-func (rm *relayMinter) queryNftMintTransactionByBuyer(ctx context.Context, buyerAddress string, incomingPaymentTxHeight int64, logInfo string) ([]*decodedTxWithMemo, error) {
+// The tx index holds the transactions by the payments they reference, so only the indexed mints of the given payment are returned.
+func (rm *relayMinter) queryNftMintTransactionByBuyer(ctx context.Context, buyerAddress, incomingPaymentTxHash string, incomingPaymentTxHeight int64, logInfo string) ([]*decodedTxWithMemo, error) {
+	indexedResults := []*decodedTxWithMemo{}
+	searchHeight, indexed := rm.outgoingSearchHeight(incomingPaymentTxHeight)
+	if indexed {
+		indexedResults = indexedMintTxs(rm.indexedTxsByRef(incomingPaymentTxHash), incomingPaymentTxHeight, func(mint model.IndexedMint) bool {
+			return mint.Recipient == buyerAddress
+		})
+	}
+
+	query := fmt.Sprintf("tx.height>=%d AND marketplace_mint_nft.buyer='%s'", searchHeight, buyerAddress)
+	results, err := rm.queryNftMintTransactions(ctx, query, logInfo, func(mintMsg *marketplacetypes.MsgMintNft) bool {
+		return mintMsg.Recipient == buyerAddress
+	})
+
+	return append(indexedResults, results...), err
+}
+
+// Fetching marketplace transactions by the given query and keeping the ones that contain mint messages of the service's wallet accepted by the filter.
+// A transaction may contain several mint messages, e.g. when it mints the items of a cart, so every message is matched individually.
+// In authz mode the mint messages are wrapped in MsgExec, see serviceMintMsgs.
+func (rm *relayMinter) queryNftMintTransactions(ctx context.Context, query, logInfo string, filter func(mintMsg *marketplacetypes.MsgMintNft) bool) ([]*decodedTxWithMemo, error) {
 	resultingArray := make([](*decodedTxWithMemo), 0)
 
-	results, err := rm.txQuerier.Query(ctx, fmt.Sprintf("tx.height>=%d AND marketplace_mint_nft.buyer='%s'", incomingPaymentTxHeight, buyerAddress))
+	results, err := rm.txQuerier.Query(ctx, query)
 	if err != nil {
 		return resultingArray, err
 	}
@@ -478,26 +879,15 @@ func (rm *relayMinter) queryNftMintTransactionByBuyer(ctx context.Context, buyer
 				continue
 			}
 
-			msgs := tx.GetMsgs()
-			if len(msgs) != 1 {
-				rm.logger.Warnf("during check if %s for tx(%s), expected one message but got %d", logInfo, result.Hash.String(), len(msgs))
-				continue
-			}
-
-			mintMsg, ok := msgs[0].(*marketplacetypes.MsgMintNft)
-			if !ok {
-				rm.logger.Warnf("during check if %s for tx(%s), message was not mint msg", logInfo, result.Hash.String())
-				continue
-			}
-
-			// TO DO: Why we are checking the creator?
-			if mintMsg.Creator != rm.walletAddress.String() {
-				rm.logger.Warnf("during check if %s for tx(%s), creator (%s) of the mint msg is not equal to wallet (%s)", logInfo, result.Hash.String(), mintMsg.Creator, rm.walletAddress.String())
-				continue
+			decodedTx := NewDecodedTxWithMemo(result.Hash.String(), tx)
+			for _, mintMsg := range rm.serviceMintMsgs(result.Hash.String(), logInfo, tx.GetMsgs()) {
+				if filter(mintMsg) {
+					decodedTx.MintMsgs = append(decodedTx.MintMsgs, mintMsg)
+				}
 			}
 
-			if mintMsg.Recipient == buyerAddress {
-				resultingArray = append(resultingArray, NewDecodedTxWithMemo(result.Hash.String(), tx))
+			if len(decodedTx.MintMsgs) > 0 {
+				resultingArray = append(resultingArray, decodedTx)
 			}
 		}
 	}
@@ -509,8 +899,8 @@ func (rm *relayMinter) EstimateGas(ctx context.Context, msgs []sdk.Msg, memo str
 	return rm.txSender.EstimateGas(ctx, msgs, memo)
 }
 
-func (rm *relayMinter) GetNFTData(ctx context.Context, cfg config.Config, uid, recipientCudosAddress string, paidAmount sdk.Coin) (model.NFTData, error) {
-	return rm.nftDataClient.GetNFTData(ctx, rm.config, uid, recipientCudosAddress, paidAmount)
+func (rm *relayMinter) GetNFTData(ctx context.Context, cfg config.Config, uid, recipientCudosAddress string, paidAmount sdk.Coin, paidAt time.Time) (model.NFTData, error) {
+	return rm.nftDataClient.GetNFTData(ctx, rm.config, uid, recipientCudosAddress, paidAmount, paidAt)
 }
 
 func (rm *relayMinter) decodeTx(resultTx *ctypes.ResultTx) (sdk.TxWithMemo, error) {
@@ -527,60 +917,154 @@ func (rm *relayMinter) decodeTx(resultTx *ctypes.ResultTx) (sdk.TxWithMemo, erro
 	return txWithMemo, nil
 }
 
-// Parsing a transaction's memo.
+// Parsing a transaction's memo and extracting every transfer to the service's wallet from its messages.
+// Transfers are looked up in bank MsgSend, in the outputs of bank MsgMultiSend and recursively in the messages wrapped by authz MsgExec.
+// Every transfer is a separate payment attributed to its real sender, while all of them share the memo of the transaction.
 // If any error is returned here, it means that the transaction or message are invalid, so in the processing loop we skip this tx
-func (rm *relayMinter) getReceivedBankSendInfo(resultTx *ctypes.ResultTx) (receivedBankSend, error) {
+func (rm *relayMinter) getReceivedBankSendInfos(resultTx *ctypes.ResultTx) ([]receivedBankSend, error) {
 	txWithMemo, err := rm.decodeTx(resultTx)
 	if err != nil {
-		return receivedBankSend{}, fmt.Errorf("getting received bank info: %s", err)
+		return nil, fmt.Errorf("getting received bank info: %s", err)
 	}
 
 	var memo mintMemo
 	memoStr := txWithMemo.GetMemo()
 	if memoStr == "" {
-		return receivedBankSend{}, fmt.Errorf("memo not set in transaction (%s)", resultTx.Hash.String())
+		return nil, fmt.Errorf("memo not set in transaction (%s)", resultTx.Hash.String())
+	}
+
+	if isSweepMemo(memoStr) {
+		return nil, fmt.Errorf("transaction (%s) is a treasury sweep", resultTx.Hash.String())
 	}
 
 	if err := json.Unmarshal([]byte(memoStr), &memo); err != nil {
-		return receivedBankSend{}, fmt.Errorf("unmarshaling memo (%s) failed: %s", memoStr, err)
+		return nil, fmt.Errorf("unmarshaling memo (%s) failed: %s", memoStr, err)
 	}
 
-	if memo.UID == "" {
-		return receivedBankSend{}, fmt.Errorf("empty memo UID in transaction (%s)", resultTx.Hash.String())
+	if memo.UID != "" && len(memo.UIDs) > 0 {
+		return nil, fmt.Errorf("memo in transaction (%s) should contain either uuid or uuids", resultTx.Hash.String())
 	}
 
-	msgs := txWithMemo.GetMsgs()
-	if len(msgs) != 1 {
-		return receivedBankSend{}, fmt.Errorf("received bank send tx should contain exactly one message but instead it contains %d", len(msgs))
+	if memo.IsQuoted() && (memo.UID != "" || len(memo.UIDs) > 0) {
+		return nil, fmt.Errorf("memo in transaction (%s) should contain either quote or uuids", resultTx.Hash.String())
 	}
 
-	bankSendMsg, ok := msgs[0].(*banktypes.MsgSend)
-	if !ok {
-		return receivedBankSend{}, errors.New("not valid bank send")
+	if memo.UID == "" && len(memo.UIDs) == 0 && !memo.IsQuoted() {
+		return nil, fmt.Errorf("empty memo UID in transaction (%s)", resultTx.Hash.String())
 	}
 
-	if bankSendMsg.ToAddress != rm.walletAddress.String() {
-		return receivedBankSend{}, fmt.Errorf("bank send receiver (%s) is not the wallet (%s)", bankSendMsg.ToAddress, rm.walletAddress.String())
+	// The NFT and the recipient of a quoted payment are the ones of the quote. A payment with an invalid quote is refunded in processPayment.
+	if memo.IsQuoted() {
+		if quote, err := rm.resolveQuote(memo.QuoteID); err == nil {
+			memo.UID = quote.Uid
+			memo.RecipientAddress = quote.Recipient
+		}
 	}
 
-	if len(bankSendMsg.Amount) != 1 {
-		return receivedBankSend{}, fmt.Errorf("bank send should have single coin sent instead got %+v", bankSendMsg.Amount)
+	for _, uid := range memo.UIDs {
+		if uid == "" {
+			return nil, fmt.Errorf("empty memo UID in transaction (%s)", resultTx.Hash.String())
+		}
+	}
+
+	transfers, err := rm.collectTransfers(resultTx.Hash.String(), txWithMemo.GetMsgs())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(transfers) == 0 {
+		return nil, fmt.Errorf("no bank transfers to the wallet (%s) found in transaction (%s)", rm.walletAddress.String(), resultTx.Hash.String())
+	}
+
+	payments := []receivedBankSend{}
+	var firstErr error
+	for i, transfer := range transfers {
+		if err := rm.validateTransfer(transfer); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if len(transfers) > 1 {
+				rm.logger.Warnf("skipping transfer %d from (%s) in tx(%s): %s", i, transfer.FromAddress, resultTx.Hash.String(), err)
+			}
+			continue
+		}
+
+		paymentMemo := memo
+		if paymentMemo.RecipientAddress == "" {
+			paymentMemo.RecipientAddress = transfer.FromAddress
+		}
+
+		payments = append(payments, receivedBankSend{
+			Memo:        paymentMemo,
+			FromAddress: transfer.FromAddress,
+			ToAddress:   transfer.ToAddress,
+			Amount:      transfer.Amount[0],
+			TxHash:      resultTx.Hash.String(),
+			Index:       i,
+			Height:      resultTx.Height,
+		})
+	}
+
+	if len(payments) == 0 {
+		return nil, firstErr
+	}
+
+	return payments, nil
+}
+
+// Walking the messages of a transaction and collecting the transfers to the service's wallet or a retired wallet still receiving payments in the order of their appearance.
+// The messages wrapped by authz MsgExec are attributed to the granter, because it is the address in the wrapped message.
+// Outputs of MsgMultiSend with more than one input can not be attributed to a single sender so they are skipped.
+func (rm *relayMinter) collectTransfers(txHash string, msgs []sdk.Msg) ([]bankTransfer, error) {
+	transfers := []bankTransfer{}
+
+	for _, msg := range msgs {
+		switch m := msg.(type) {
+		case *banktypes.MsgSend:
+			if rm.isReceivingWallet(m.ToAddress) {
+				transfers = append(transfers, bankTransfer{FromAddress: m.FromAddress, ToAddress: m.ToAddress, Amount: m.Amount})
+			}
+		case *banktypes.MsgMultiSend:
+			for _, output := range m.Outputs {
+				if !rm.isReceivingWallet(output.Address) {
+					continue
+				}
+
+				if len(m.Inputs) != 1 {
+					rm.logger.Warnf("skipping multi send output in tx(%s), it has %d inputs and can not be attributed to a single sender", txHash, len(m.Inputs))
+					continue
+				}
+
+				transfers = append(transfers, bankTransfer{FromAddress: m.Inputs[0].Address, ToAddress: output.Address, Amount: output.Coins})
+			}
+		case *authz.MsgExec:
+			nestedMsgs, err := m.GetMessages()
+			if err != nil {
+				return nil, fmt.Errorf("unpacking authz exec messages failed: %s", err)
+			}
+
+			nestedTransfers, err := rm.collectTransfers(txHash, nestedMsgs)
+			if err != nil {
+				return nil, err
+			}
+
+			transfers = append(transfers, nestedTransfers...)
+		}
 	}
 
-	if bankSendMsg.Amount[0].Denom != rm.config.PaymentDenom {
-		return receivedBankSend{}, fmt.Errorf("bank send invalid payment denom, expected %s but got %s", rm.config.PaymentDenom, bankSendMsg.Amount[0].Denom)
+	return transfers, nil
+}
+
+func (rm *relayMinter) validateTransfer(transfer bankTransfer) error {
+	if len(transfer.Amount) != 1 {
+		return fmt.Errorf("bank send should have single coin sent instead got %+v", transfer.Amount)
 	}
 
-	if memo.RecipientAddress == "" {
-		memo.RecipientAddress = bankSendMsg.FromAddress
+	if transfer.Amount[0].Denom != rm.config.PaymentDenom {
+		return fmt.Errorf("bank send invalid payment denom, expected %s but got %s", rm.config.PaymentDenom, transfer.Amount[0].Denom)
 	}
 
-	return receivedBankSend{
-		Memo:        memo,
-		FromAddress: bankSendMsg.FromAddress,
-		ToAddress:   bankSendMsg.ToAddress,
-		Amount:      bankSendMsg.Amount[0],
-	}, nil
+	return nil
 }
 
 const (
@@ -592,36 +1076,63 @@ const (
 var contextDone = errors.New("context done")
 
 type relayMinter struct {
-	encodingConfig *params.EncodingConfig
-	errored        chan error
-	config         config.Config
-	stateStorage   stateStorage
-	privKey        *secp256k1.PrivKey
-	walletAddress  sdk.AccAddress
-	txSender       txSender
-	txQuerier      txQuerier
-	nftDataClient  nftDataClient
-	logger         relayLogger
-	grpcConnector  grpcConnector
-	rpcConnector   rpcConnector
-	txCoder        txCoder
-	retries        int
-	emailService   emailService
+	encodingConfig   *params.EncodingConfig
+	errored          chan error
+	config           config.Config
+	stateStorage     stateStorage
+	walletKey        walletKey
+	walletAddress    sdk.AccAddress
+	txSender         txSender
+	txQuerier        txQuerier
+	nftDataClient    nftDataClient
+	logger           relayLogger
+	grpcConnector    grpcConnector
+	rpcConnector     rpcConnector
+	txCoder          txCoder
+	retries          int
+	emailService     emailService
+	batch            *txBatch
+	minters          []*minterWallet
+	retired          []*minterWallet
+	balanceQuerier   balanceQuerier
+	balanceLevels    map[string]balanceLevel
+	paused           bool
+	sweepLog         sweepLog
+	txIndex          txIndex
+	decisionLog      decisionLog
+	feeGranter       sdk.AccAddress
+	allowanceQuerier allowanceQuerier
+	blockQuerier     blockQuerier
+	quoteKey         ed25519.PrivateKey
+	quoteStore       quoteStore
+	health           model.Health
+	healthMu         sync.Mutex
+	walletMu         sync.Mutex
+	nextWalletIdx    int
+	// Guarding the tx senders, they are replaced on every connect while the quote endpoint reads them
+	sendersMu sync.RWMutex
 }
 
+// The memo of an incoming payment. It contains either the UID of a single NFT, the UIDs of all NFTs in a cart or the id of a quote issued by the service.
 type mintMemo struct {
-	UID               string `json:"uuid"`
-	RecipientAddress  string `json:"recipientAddress"`
-	ContractPaymentId string `json:"contractPaymentId"`
-	EthTxHash         string `json:"ethTxHash"`
+	UID               string   `json:"uuid"`
+	UIDs              []string `json:"uuids"`
+	QuoteID           string   `json:"quote"`
+	RecipientAddress  string   `json:"recipientAddress"`
+	ContractPaymentId string   `json:"contractPaymentId"`
+	EthTxHash         string   `json:"ethTxHash"`
 }
 
-func (t *mintMemo) String() string {
-	return fmt.Sprintf("MintMemo { UID(%s) RecipientAddress(%s) ContractPaymentId(%s) }", t.UID, t.RecipientAddress, t.ContractPaymentId)
+func (t *mintMemo) IsCart() bool {
+	return len(t.UIDs) > 0
+}
+
+func (t *mintMemo) IsQuoted() bool {
+	return t.QuoteID != ""
 }
 
-type refundMemo struct {
-	TxHash string `json:"tx_hash"`
+func (t *mintMemo) String() string {
+	return fmt.Sprintf("MintMemo { UID(%s) UIDs(%v) QuoteID(%s) RecipientAddress(%s) ContractPaymentId(%s) }", t.UID, t.UIDs, t.QuoteID, t.RecipientAddress, t.ContractPaymentId)
 }
 
 type receivedBankSend struct {
@@ -629,6 +1140,27 @@ type receivedBankSend struct {
 	FromAddress string
 	ToAddress   string
 	Amount      sdk.Coin
+	TxHash      string
+	Index       int
+	Height      int64
+	// The time of the block of the payment, set once it is queried for the NFT data
+	PaidAt time.Time
+}
+
+// The reference of a payment is the hash of the incoming transaction.
+// A transaction with multiple transfers to the wallet holds multiple payments, so each one after the first is suffixed by its index.
+func (t *receivedBankSend) Ref() string {
+	if t.Index == 0 {
+		return t.TxHash
+	}
+
+	return fmt.Sprintf("%s#%d", t.TxHash, t.Index)
+}
+
+type bankTransfer struct {
+	FromAddress string
+	ToAddress   string
+	Amount      sdk.Coins
 }
 
 func (t *receivedBankSend) String() string {
@@ -640,6 +1172,12 @@ type stateStorage interface {
 	UpdateState(state model.State) error
 }
 
+// The key of a wallet. It is either held in memory or by a remote signing daemon, which exposes only the public key.
+type walletKey interface {
+	PubKey() cryptotypes.PubKey
+	Sign(msg []byte) ([]byte, error)
+}
+
 type txCoder interface {
 	Decode(tx tmtypes.Tx) (sdk.Tx, error)
 }
@@ -649,12 +1187,50 @@ type txSender interface {
 	SendTx(ctx context.Context, msgs []sdk.Msg, memo string, gasResult model.GasResult) (string, error)
 }
 
+type balanceQuerier interface {
+	QueryBalance(ctx context.Context, address, denom string) (sdk.Int, error)
+}
+
+type allowanceQuerier interface {
+	QueryAllowance(ctx context.Context, granter, grantee string) (model.FeeAllowance, error)
+}
+
+type blockQuerier interface {
+	BlockTime(ctx context.Context, height int64) (time.Time, error)
+	NodeStatus(ctx context.Context) (model.NodeStatus, error)
+}
+
+type quoteStore interface {
+	SaveQuote(quote model.Quote) error
+	GetQuote(id string) (model.Quote, bool)
+}
+
+type sweepLog interface {
+	RecordSweep(record model.SweepRecord) error
+}
+
+type decisionLog interface {
+	RecordDecision(decision model.Decision) error
+}
+
+type txIndex interface {
+	Coverage() (model.TxIndexRange, bool)
+	AddEntry(entry model.TxIndexEntry) error
+	Reset() error
+	TxsByRef(ref string) []model.IndexedTx
+	TxsByUid(uid string) []model.IndexedTx
+}
+
 type txQuerier interface {
 	Query(ctx context.Context, query string) (*ctypes.ResultTxSearch, error)
 }
 
+type txPruner interface {
+	Prune(height int64)
+}
+
 type nftDataClient interface {
-	GetNFTData(ctx context.Context, cfg config.Config, uid, recipientCudosAddress string, amountPaid sdk.Coin) (model.NFTData, error)
+	GetNFTData(ctx context.Context, cfg config.Config, uid, recipientCudosAddress string, amountPaid sdk.Coin, paidAt time.Time) (model.NFTData, error)
 }
 
 type relayLogger interface {
@@ -678,13 +1254,27 @@ type emailService interface {
 }
 
 type decodedTxWithMemo struct {
-	Hash       string
-	TxWithMemo sdk.TxWithMemo
+	Hash         string
+	Memo         string
+	Fees         sdk.Coins
+	MintMsgs     []*marketplacetypes.MsgMintNft
+	BankSendMsgs []*banktypes.MsgSend
+}
+
+// The fee paid by the transaction in the given denom.
+func (t *decodedTxWithMemo) Fee(denom string) sdk.Int {
+	return t.Fees.AmountOf(denom)
 }
 
 func NewDecodedTxWithMemo(hash string, txWithMemo sdk.TxWithMemo) *decodedTxWithMemo {
-	return &decodedTxWithMemo{
-		Hash:       hash,
-		TxWithMemo: txWithMemo,
+	decodedTx := &decodedTxWithMemo{
+		Hash: hash,
+		Memo: txWithMemo.GetMemo(),
 	}
+
+	if feeTx, ok := txWithMemo.(sdk.FeeTx); ok {
+		decodedTx.Fees = feeTx.GetFee()
+	}
+
+	return decodedTx
 }
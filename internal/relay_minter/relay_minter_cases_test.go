@@ -8,6 +8,7 @@ import (
 	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
 	"github.com/cosmos/cosmos-sdk/simapp/params"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	"github.com/stretchr/testify/require"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
@@ -17,6 +18,12 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 	buyer1, err := sdk.AccAddressFromBech32("cudos1vz78ezuzskf9fgnjkmeks75xum49hug6l2wgeg")
 	require.NoError(t, err)
 
+	buyer2 := sdk.AccAddress([]byte("buyer2______________"))
+
+	execMsg := authz.NewMsgExec(buyer2, []sdk.Msg{
+		banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
+	})
+
 	return []testCase{
 		{
 			name:                "ShouldReturnNoErrorWhenNilTxsResult",
@@ -75,22 +82,110 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 			expectedOutputMsgs:  []sdk.Msg{},
 		},
 		{
-			name: "ShouldSkipIfTransactionContainsMoreThanOneMessage",
+			name: "ShouldProcessEveryTransferInMultiMessageTransaction",
 
 			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
 				{
-					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(100)))),
-					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(100)))),
+					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
+					banktypes.NewMsgSend(buyer2, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000)))),
+				},
+			}, []string{
+				"{\"uuid\":\"notfoundnftuid\"}",
+			}, encodingConfig, ""),
+
+			expectedError:       nil,
+			expectedLogOutput:   "1: processing incomingPaymentTxHash(#1) at height(0)",
+			expectedOutputMemos: []string{"", "#1"},
+			expectedOutputMsgs: []sdk.Msg{
+				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+				banktypes.NewMsgSend(wallet, buyer2, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+			},
+		},
+		{
+			name: "ShouldAttributeMultiSendOutputToItsInput",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgMultiSend(
+						[]banktypes.Input{banktypes.NewInput(buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000))))},
+						[]banktypes.Output{
+							banktypes.NewOutput(buyer2, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(1000000000000000000)))),
+							banktypes.NewOutput(wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
+						},
+					),
+				},
+			}, []string{
+				"{\"uuid\":\"notfoundnftuid\"}",
+			}, encodingConfig, ""),
+
+			expectedError:       nil,
+			expectedLogOutput:   "failed to mint: nft () was not found",
+			expectedOutputMemos: []string{""},
+			expectedOutputMsgs: []sdk.Msg{
+				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+			},
+		},
+		{
+			name: "ShouldSkipMultiSendOutputWithMultipleInputs",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgMultiSend(
+						[]banktypes.Input{
+							banktypes.NewInput(buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(4000000000000000000)))),
+							banktypes.NewInput(buyer2, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(4000000000000000000)))),
+						},
+						[]banktypes.Output{
+							banktypes.NewOutput(wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
+						},
+					),
 				},
 			}, []string{
 				"{\"uuid\":\"nftuid#1\"}",
 			}, encodingConfig, ""),
 
 			expectedError:       nil,
-			expectedLogOutput:   "getting received bank send info for tx() failed: received bank send tx should contain exactly one message but instead it contains 2",
+			expectedLogOutput:   "skipping multi send output in tx(), it has 2 inputs and can not be attributed to a single sender",
 			expectedOutputMemos: []string{},
 			expectedOutputMsgs:  []sdk.Msg{},
 		},
+		{
+			name: "ShouldAttributeTransferWrappedInAuthzExecToGranter",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					&execMsg,
+				},
+			}, []string{
+				"{\"uuid\":\"notfoundnftuid\"}",
+			}, encodingConfig, ""),
+
+			expectedError:       nil,
+			expectedLogOutput:   "failed to mint: nft () was not found",
+			expectedOutputMemos: []string{""},
+			expectedOutputMsgs: []sdk.Msg{
+				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+			},
+		},
+		{
+			name: "ShouldSkipInvalidTransferAndProcessTheRestInTransaction",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgSend(buyer2, wallet, sdk.NewCoins(sdk.NewCoin("ucudos", sdk.NewIntFromUint64(100)))),
+					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
+				},
+			}, []string{
+				"{\"uuid\":\"notfoundnftuid\"}",
+			}, encodingConfig, ""),
+
+			expectedError:       nil,
+			expectedLogOutput:   "skipping transfer 0 from (" + buyer2.String() + ") in tx(): bank send invalid payment denom, expected acudos but got ucudos",
+			expectedOutputMemos: []string{"#1"},
+			expectedOutputMsgs: []sdk.Msg{
+				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+			},
+		},
 		{
 			name: "ShouldSkipIfMessageIsNotMsgSend",
 
@@ -103,7 +198,7 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 			}, encodingConfig, ""),
 
 			expectedError:       nil,
-			expectedLogOutput:   "getting received bank send info for tx() failed: not valid bank send",
+			expectedLogOutput:   "getting received bank send info for tx() failed: no bank transfers to the wallet (cudos1a326k254fukx9jlp0h3fwcr2ymjgludzum67dv) found in transaction ()",
 			expectedOutputMemos: []string{},
 			expectedOutputMsgs:  []sdk.Msg{},
 		},
@@ -119,7 +214,7 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 			}, encodingConfig, ""),
 
 			expectedError:       nil,
-			expectedLogOutput:   "getting received bank send info for tx() failed: bank send receiver (cudos1vz78ezuzskf9fgnjkmeks75xum49hug6l2wgeg) is not the wallet (cudos1a326k254fukx9jlp0h3fwcr2ymjgludzum67dv)",
+			expectedLogOutput:   "getting received bank send info for tx() failed: no bank transfers to the wallet (cudos1a326k254fukx9jlp0h3fwcr2ymjgludzum67dv) found in transaction ()",
 			expectedOutputMemos: []string{},
 			expectedOutputMsgs:  []sdk.Msg{},
 		},
@@ -171,7 +266,7 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 			expectedOutputMsgs:  []sdk.Msg{},
 		},
 		{
-			name: "ShouldHaveSingleMessageInMintTransaction",
+			name: "ShouldMatchEveryMintMessageInMultiMessageMintTransaction",
 
 			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
 				{
@@ -190,7 +285,7 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 			}, encodingConfig, ""),
 
 			expectedError:       nil,
-			expectedLogOutput:   "during check if minted for tx(), expected one message but got 2",
+			expectedLogOutput:   "checking whether NFT(nftuid#1) is minted\r\nMinted NFT(nftuid#1): true []",
 			expectedOutputMemos: []string{""},
 			expectedOutputMsgs: []sdk.Msg{
 				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
@@ -215,7 +310,7 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 			}, encodingConfig, ""),
 
 			expectedError:       nil,
-			expectedLogOutput:   "during check if minted for tx(), message was not mint",
+			expectedLogOutput:   "during check if minted for tx(), message 0 was not mint msg",
 			expectedOutputMemos: []string{""},
 			expectedOutputMsgs: []sdk.Msg{
 				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
@@ -240,7 +335,7 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 			}, encodingConfig, ""),
 
 			expectedError:       nil,
-			expectedLogOutput:   "during check if minted for tx(), creator (cudos1vz78ezuzskf9fgnjkmeks75xum49hug6l2wgeg) of the mint msg is not equal to wallet (cudos1a326k254fukx9jlp0h3fwcr2ymjgludzum67dv)",
+			expectedLogOutput:   "during check if minted for tx(), creator (cudos1vz78ezuzskf9fgnjkmeks75xum49hug6l2wgeg) of the mint msg is not a wallet of the pool [cudos1a326k254fukx9jlp0h3fwcr2ymjgludzum67dv]",
 			expectedOutputMemos: []string{""},
 			expectedOutputMsgs: []sdk.Msg{
 				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
@@ -304,7 +399,7 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 			},
 		},
 		{
-			name: "ShouldHaveSingleMsgInRefundTx",
+			name: "ShouldMatchRefundBankSendInMultiMessageTx",
 
 			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
 				{
@@ -312,22 +407,44 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 				},
 			}, []string{
 				"{\"uuid\":\"nftuid#1\"}",
-			}, encodingConfig, ""),
+			}, encodingConfig, batchTxHash),
 			sentBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
 				{
-					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
+					banktypes.NewMsgSend(wallet, buyer2, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
+					banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
+				},
+			}, []string{
+				"[{\"tx_hash\":\"0123\"},{\"tx_hash\":\"" + batchTxHash + "\"}]",
+			}, encodingConfig, ""),
+
+			expectedError:       nil,
+			expectedLogOutput:   batchTxHash + " refunded: true",
+			expectedOutputMemos: []string{},
+			expectedOutputMsgs:  []sdk.Msg{},
+		},
+		{
+			name: "ShouldMatchRefundInBatchTxByShortReference",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
 					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
 				},
 			}, []string{
-				"",
+				"{\"uuid\":\"nftuid#1\"}",
+			}, encodingConfig, longBatchTxHash),
+			sentBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgSend(wallet, buyer2, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
+					banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000)))),
+				},
+			}, []string{
+				"[\"0123\",\"" + longBatchTxHash[:shortRefHashCharacters] + "\"]",
 			}, encodingConfig, ""),
 
 			expectedError:       nil,
-			expectedLogOutput:   "during check if refunded for tx(), refund bank send should contain exactly one message but instead it contains 2",
-			expectedOutputMemos: []string{""},
-			expectedOutputMsgs: []sdk.Msg{
-				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
-			},
+			expectedLogOutput:   longBatchTxHash + " refunded: true",
+			expectedOutputMemos: []string{},
+			expectedOutputMsgs:  []sdk.Msg{},
 		},
 		{
 			name: "ShouldHaveSingleMsgInRefundTxWhichlShouldBeMsgSend",
@@ -556,6 +673,132 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 			expectedOutputMsgs:  []sdk.Msg{},
 			failAllSendTx:       true,
 		},
+		{
+			name: "ShouldSkipWhenMemoHasBothUIDAndUIDs",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(100)))),
+				},
+			}, []string{
+				"{\"uuid\":\"nftuid#1\",\"uuids\":[\"nftuid#5\"]}",
+			}, encodingConfig, ""),
+
+			expectedError:       nil,
+			expectedLogOutput:   "getting received bank send info for tx() failed: memo in transaction () should contain either uuid or uuids",
+			expectedOutputMemos: []string{},
+			expectedOutputMsgs:  []sdk.Msg{},
+		},
+		{
+			name: "ShouldMintAllCartItemsInSingleTransaction",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(16000000000000000000).Add(sdk.NewIntFromUint64(5005000000000000))))),
+				},
+			}, []string{
+				"{\"uuids\":[\"nftuid#1\",\"nftuid#5\"]}",
+			}, encodingConfig, cartTxHash),
+
+			expectedError:       nil,
+			expectedLogOutput:   "success cart mint tx  of items [nftuid#1 nftuid#5]",
+			expectedOutputMemos: []string{"{\"tx_hash\":\"" + cartTxHash + "\",\"items\":[0,1]}"},
+			expectedOutputMsgs: []sdk.Msg{
+				marketplacetypes.NewMsgMintNft(wallet.String(), "testdenom", buyer1.String(), "test nft name", "test nft uri", "test nft data", "nftuid#1",
+					sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000))),
+				marketplacetypes.NewMsgMintNft(wallet.String(), "testdenom", buyer1.String(), "test nft name", "test nft uri", "test nft data", "nftuid#5",
+					sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000))),
+			},
+		},
+		{
+			name: "ShouldMintAvailableCartItemsAndRefundTheRest",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(16000000000000000000)))),
+				},
+			}, []string{
+				"{\"uuids\":[\"nftuid#1\",\"nftuid#2\"]}",
+			}, encodingConfig, cartTxHash),
+
+			expectedError:     nil,
+			expectedLogOutput: "cart item (nftuid#2) of transaction(" + cartTxHash + ") is not available: nft (nftuid#2) has invalid status (rejected)",
+			expectedOutputMemos: []string{
+				"{\"tx_hash\":\"" + cartTxHash + "\",\"items\":[0]}",
+				"{\"tx_hash\":\"" + cartTxHash + "\",\"items\":[1]}",
+			},
+			expectedOutputMsgs: []sdk.Msg{
+				marketplacetypes.NewMsgMintNft(wallet.String(), "testdenom", buyer1.String(), "test nft name", "test nft uri", "test nft data", "nftuid#1",
+					sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000))),
+				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000).Sub(sdk.NewIntFromUint64(10010000000000000))))),
+			},
+		},
+		{
+			name: "ShouldResumePartiallyProcessedCart",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(16000000000000000000)))),
+				},
+			}, []string{
+				"{\"uuids\":[\"nftuid#1\",\"nftuid#2\"]}",
+			}, encodingConfig, cartTxHash),
+			mintTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					marketplacetypes.NewMsgMintNft(wallet.String(), "", buyer1.String(), "", "", "", "nftuid#1", sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000))),
+				},
+			}, []string{
+				"{\"tx_hash\":\"" + cartTxHash + "\",\"uuids\":[\"nftuid#1\"]}",
+			}, encodingConfig, ""),
+
+			expectedError:       nil,
+			expectedLogOutput:   "cart progress of " + cartTxHash + ": minted 1, refunded 0, spent 8000000000000000000",
+			expectedOutputMemos: []string{"{\"tx_hash\":\"" + cartTxHash + "\",\"items\":[1]}"},
+			expectedOutputMsgs: []sdk.Msg{
+				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+			},
+		},
+		{
+			name: "ShouldSkipFullyRefundedCart",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(16000000000000000000)))),
+				},
+			}, []string{
+				"{\"uuids\":[\"nftuid#1\",\"nftuid#2\"]}",
+			}, encodingConfig, cartTxHash),
+			sentBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(16000000000000000000)))),
+				},
+			}, []string{
+				cartTxHash,
+			}, encodingConfig, ""),
+
+			expectedError:       nil,
+			expectedLogOutput:   "transaction(" + cartTxHash + ") has already been refunded to buyer(cudos1vz78ezuzskf9fgnjkmeks75xum49hug6l2wgeg)",
+			expectedOutputMemos: []string{},
+			expectedOutputMsgs:  []sdk.Msg{},
+		},
+		{
+			name: "ShouldRefundWholeCartWithDuplicatedItems",
+
+			receivedBankSendTxs: buildTestResultTxSearch(t, [][]sdk.Msg{
+				{
+					banktypes.NewMsgSend(buyer1, wallet, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(16000000000000000000)))),
+				},
+			}, []string{
+				"{\"uuids\":[\"nftuid#1\",\"nftuid#1\"]}",
+			}, encodingConfig, cartTxHash),
+
+			expectedError:       nil,
+			expectedLogOutput:   "cart of transaction(" + cartTxHash + ") has duplicated items",
+			expectedOutputMemos: []string{cartTxHash},
+			expectedOutputMsgs: []sdk.Msg{
+				banktypes.NewMsgSend(wallet, buyer1, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(16000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+			},
+		},
 		{
 			name: "ShouldSuccessfullyMintNft",
 
@@ -578,6 +821,8 @@ func buildTestCases(t *testing.T, encodingConfig *params.EncodingConfig, wallet
 	}
 }
 
+const cartTxHash = "ABCDEF0123"
+
 type testCase struct {
 	name                string
 	receivedBankSendTxs *ctypes.ResultTxSearch
@@ -591,6 +836,10 @@ type testCase struct {
 	failAllSendTx       bool
 }
 
+const batchTxHash = "0123ABCDEF"
+
+const longBatchTxHash = "0123ABCDEF0123ABCDEF0123ABCDEF0123ABCDEF0123ABCDEF0123ABCDEF0123"
+
 func buildTestResultTxSearch(t *testing.T, msgs [][]sdk.Msg, memos []string, encodingConfig *params.EncodingConfig, txHash string) *ctypes.ResultTxSearch {
 	require.Len(t, msgs, len(memos))
 
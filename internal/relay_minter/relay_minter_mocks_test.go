@@ -2,16 +2,142 @@ package relayminter
 
 import (
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"testing"
+	"time"
 
+	cudosapp "github.com/CudoVentures/cudos-node/app"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/key"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/tx"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 )
 
+// The sdk config can be sealed only once, so every test that needs the cudos address prefixes shares it.
+func setCudosConfig() {
+	setCudosConfigOnce.Do(cudosapp.SetConfig)
+}
+
+var setCudosConfigOnce sync.Once
+
+// Building a relay minter with mock dependencies for the tests of a feature. Without options it is paid in acudos and has a single
+// incoming payment of 9 CUDOS from the buyer for the available NFT nftuid#1 priced at 8 CUDOS.
+func newTestRelayMinter(t *testing.T, opts testRelayMinterOptions) *testRelayMinter {
+	setCudosConfig()
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	privKey, err := key.PrivKeyFromMnemonic(walletMnemonic)
+	require.NoError(t, err)
+
+	cfg := opts.cfg
+	if cfg.PaymentDenom == "" {
+		cfg.PaymentDenom = "acudos"
+	}
+
+	nftDataClient := opts.nftDataClient
+	if nftDataClient == nil {
+		nfts := opts.nfts
+		if nfts == nil {
+			nfts = map[string]model.NFTData{"nftuid#1": newTestNftData("nftuid#1", 8000000000000000000, tomorrow)}
+		}
+		nftDataClient = newTokenisedInfraClient(nfts, nil, nil)
+	}
+
+	built := &testRelayMinter{txSender: newMockTxSender(false), emailService: &mockEmailService{}, blockQuerier: newMockBlockQuerier(time.Now())}
+	built.relayMinter = NewRelayMinter(newMockLogger(), &encodingConfig, cfg, newMockState(),
+		nftDataClient, privKey, nil, opts.retiredPrivKeys, nil, nil, tx.NewTxCoder(&encodingConfig), built.emailService, opts.quoteKey, opts.quoteStore)
+
+	built.buyer, err = sdk.AccAddressFromBech32(refundReceiver)
+	require.NoError(t, err)
+
+	paymentTxs := opts.paymentTxs
+	if paymentTxs == nil {
+		paymentTxs = []testPaymentTx{{memo: "{\"uuid\":\"nftuid#1\"}", amounts: []uint64{9000000000000000000}}}
+	}
+
+	msgs := [][]sdk.Msg{}
+	memos := []string{}
+	for _, paymentTx := range paymentTxs {
+		to := paymentTx.to
+		if to == nil {
+			to = built.relayMinter.walletAddress
+		}
+
+		sends := []sdk.Msg{}
+		for _, amount := range paymentTx.amounts {
+			sends = append(sends, banktypes.NewMsgSend(built.buyer, to, sdk.NewCoins(sdk.NewCoin(cfg.PaymentDenom, sdk.NewIntFromUint64(amount)))))
+		}
+		msgs = append(msgs, sends)
+		memos = append(memos, paymentTx.memo)
+	}
+
+	txHash := opts.txHash
+	if txHash == "" {
+		txHash = batchTxHash
+	}
+
+	built.relayMinter.blockQuerier = built.blockQuerier
+	built.relayMinter.txQuerier = newMockTxQuerier(buildTestResultTxSearch(t, msgs, memos, &encodingConfig, txHash), nil, nil, false)
+	built.relayMinter.txSender = built.txSender
+	if !opts.balance.IsNil() {
+		built.relayMinter.balanceQuerier = newMockBalanceQuerier(opts.balance)
+	}
+
+	return built
+}
+
+// The NFT data of an NFT queued for minting at the given price, valid until the given unix milliseconds.
+func newTestNftData(uid string, price uint64, priceValidUntil int64) model.NFTData {
+	return model.NFTData{
+		Id:              uid,
+		Price:           sdk.NewIntFromUint64(price),
+		DenomID:         "testdenom",
+		Status:          model.QueuedNFTStatus,
+		PriceValidUntil: priceValidUntil,
+	}
+}
+
+// The options of newTestRelayMinter. The zero value of every option selects the default.
+type testRelayMinterOptions struct {
+	cfg config.Config
+	// The NFTs of the AuraPool, unless an nft data client is given
+	nfts          map[string]model.NFTData
+	nftDataClient nftDataClient
+	// The incoming transactions at consecutive heights from 0, an empty slice for none
+	paymentTxs      []testPaymentTx
+	txHash          string
+	retiredPrivKeys []*secp256k1.PrivKey
+	// The balance of the wallets, without it there is no balance querier
+	balance    sdk.Int
+	quoteKey   ed25519.PrivateKey
+	quoteStore quoteStore
+}
+
+// An incoming transaction of the buyer with a bank send of every amount to the given address, by default the payment wallet.
+type testPaymentTx struct {
+	memo    string
+	amounts []uint64
+	to      sdk.AccAddress
+}
+
+type testRelayMinter struct {
+	relayMinter  *relayMinter
+	txSender     *mockTxSender
+	emailService *mockEmailService
+	blockQuerier *mockBlockQuerier
+	buyer        sdk.AccAddress
+}
+
 func newMockState() *mockState {
 	return &mockState{}
 }
@@ -37,7 +163,7 @@ func newTokenisedInfraClient(nftDataEntires map[string]model.NFTData, getNftData
 	}
 }
 
-func (mtic *mockTokenisedInfraClient) GetNFTData(ctx context.Context, cfg config.Config, uid, recipientCudosAddress string, amountPaid sdk.Coin) (model.NFTData, error) {
+func (mtic *mockTokenisedInfraClient) GetNFTData(ctx context.Context, cfg config.Config, uid, recipientCudosAddress string, amountPaid sdk.Coin, paidAt time.Time) (model.NFTData, error) {
 	if err, ok := mtic.getNftDataErrors[uid]; ok {
 		return model.NFTData{}, err
 	}
@@ -79,11 +205,16 @@ func (mq *mockTxQuerier) Query(ctx context.Context, query string) (*ctypes.Resul
 	panic("invalid query")
 }
 
+func (mtq *mockTxQuerier) Prune(height int64) {
+	mtq.prunedHeights = append(mtq.prunedHeights, height)
+}
+
 type mockTxQuerier struct {
 	bankSendQueryResults *ctypes.ResultTxSearch
 	mintQueryResults     *ctypes.ResultTxSearch
 	refundQueryResults   *ctypes.ResultTxSearch
 	failMintTxsQuery     bool
+	prunedHeights        []int64
 }
 
 func newMockTxSender(failAllSendTx bool) *mockTxSender {
@@ -95,6 +226,10 @@ func newMockTxSender(failAllSendTx bool) *mockTxSender {
 }
 
 func (mts *mockTxSender) EstimateGas(ctx context.Context, msgs []sdk.Msg, memo string) (model.GasResult, error) {
+	if mts.failBatchEstimateGas && len(msgs) > 1 {
+		return model.GasResult{}, errors.New("failed to simulate batch")
+	}
+
 	return model.GasResult{
 		FeeAmount: mockFeeAmount,
 		GasLimit:  mockGasLimit,
@@ -106,15 +241,40 @@ func (mts *mockTxSender) SendTx(ctx context.Context, msgs []sdk.Msg, memo string
 		return "", errors.New("failed to send tx")
 	}
 
+	mts.mu.Lock()
+	defer mts.mu.Unlock()
+
 	mts.outputMsgs = append(mts.outputMsgs, msgs...)
 	mts.outputMemos = append(mts.outputMemos, memo)
 	return "", nil
 }
 
 type mockTxSender struct {
+	mu            sync.Mutex
 	outputMemos   []string
 	outputMsgs    []sdk.Msg
 	failAllSendTx bool
+	// Failing the gas estimation of multi message transactions only
+	failBatchEstimateGas bool
+}
+
+func newMockBlockQuerier(blockTime time.Time) *mockBlockQuerier {
+	return &mockBlockQuerier{blockTime: blockTime, status: model.NodeStatus{LatestHeight: 100, LatestBlockTime: time.Now()}}
+}
+
+func (m *mockBlockQuerier) NodeStatus(ctx context.Context) (model.NodeStatus, error) {
+	return m.status, m.statusErr
+}
+
+func (m *mockBlockQuerier) BlockTime(ctx context.Context, height int64) (time.Time, error) {
+	return m.blockTime, m.err
+}
+
+type mockBlockQuerier struct {
+	blockTime time.Time
+	err       error
+	status    model.NodeStatus
+	statusErr error
 }
 
 func newMockLogger() *mockLogger {
@@ -122,6 +282,9 @@ func newMockLogger() *mockLogger {
 }
 
 func (ml *mockLogger) Error(err error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
 	if len(ml.output) > 0 {
 		ml.output += "\r\n"
 	}
@@ -129,6 +292,9 @@ func (ml *mockLogger) Error(err error) {
 }
 
 func (ml *mockLogger) Info(msg string) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
 	if len(ml.output) > 0 {
 		ml.output += "\r\n"
 	}
@@ -136,6 +302,9 @@ func (ml *mockLogger) Info(msg string) {
 }
 
 func (ml *mockLogger) Infof(format string, v ...interface{}) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
 	if len(ml.output) > 0 {
 		ml.output += "\r\n"
 	}
@@ -143,6 +312,9 @@ func (ml *mockLogger) Infof(format string, v ...interface{}) {
 }
 
 func (ml *mockLogger) Warn(msg string) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
 	if len(ml.output) > 0 {
 		ml.output += "\r\n"
 	}
@@ -150,6 +322,9 @@ func (ml *mockLogger) Warn(msg string) {
 }
 
 func (ml *mockLogger) Warnf(format string, v ...interface{}) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
 	if len(ml.output) > 0 {
 		ml.output += "\r\n"
 	}
@@ -157,9 +332,56 @@ func (ml *mockLogger) Warnf(format string, v ...interface{}) {
 }
 
 type mockLogger struct {
+	mu     sync.Mutex
 	output string
 }
 
 const mockGasLimit uint64 = 1001
 
 var mockFeeAmount = sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(mockGasLimit)))
+
+func newMockBalanceQuerier(balance sdk.Int) *mockBalanceQuerier {
+	return &mockBalanceQuerier{balance: balance}
+}
+
+func (mbq *mockBalanceQuerier) QueryBalance(ctx context.Context, address, denom string) (sdk.Int, error) {
+	return mbq.balance, nil
+}
+
+type mockBalanceQuerier struct {
+	balance sdk.Int
+}
+
+func (mes *mockEmailService) SendEmail(content string) {
+	mes.mu.Lock()
+	defer mes.mu.Unlock()
+
+	mes.emails = append(mes.emails, content)
+}
+
+type mockEmailService struct {
+	mu     sync.Mutex
+	emails []string
+}
+
+func (msl *mockSweepLog) RecordSweep(record model.SweepRecord) error {
+	msl.records = append(msl.records, record)
+	return nil
+}
+
+type mockSweepLog struct {
+	records []model.SweepRecord
+}
+
+func (maq *mockAllowanceQuerier) QueryAllowance(ctx context.Context, granter, grantee string) (model.FeeAllowance, error) {
+	allowance, ok := maq.allowances[grantee]
+	if !ok {
+		return model.FeeAllowance{}, fmt.Errorf("no fee allowance from granter (%s) to grantee (%s)", granter, grantee)
+	}
+
+	return allowance, nil
+}
+
+type mockAllowanceQuerier struct {
+	allowances map[string]model.FeeAllowance
+}
@@ -2,12 +2,14 @@ package relayminter
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
-	cudosapp "github.com/CudoVentures/cudos-node/app"
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/email"
 	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
@@ -27,7 +29,7 @@ import (
 )
 
 func TestRelay(t *testing.T) {
-	cudosapp.SetConfig()
+	setCudosConfig()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
 
 	privKey, err := key.PrivKeyFromMnemonic(walletMnemonic)
@@ -65,6 +67,16 @@ func TestRelay(t *testing.T) {
 			Status:          model.ApprovedNFTStatus,
 			PriceValidUntil: tomorrow,
 		},
+		"nftuid#5": {
+			Id:              "nftuid#5",
+			Price:           sdk.NewIntFromUint64(8000000000000000000),
+			Name:            "test nft name",
+			Uri:             "test nft uri",
+			Data:            "test nft data",
+			DenomID:         "testdenom",
+			Status:          model.QueuedNFTStatus,
+			PriceValidUntil: tomorrow,
+		},
 	},
 		map[string]error{
 			"nftuid#4": errors.New("not found"),
@@ -75,12 +87,13 @@ func TestRelay(t *testing.T) {
 	mockLogger := newMockLogger()
 
 	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, mockStatesStorage,
-		mockTokenisedInfraClient, privKey, grpc.GRPCConnector{}, rpc.RPCConnector{}, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}))
+		mockTokenisedInfraClient, privKey, nil, nil, grpc.GRPCConnector{}, rpc.RPCConnector{}, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
 
 	testCases := buildTestCases(t, &encodingConfig, relayMinter.walletAddress)
 
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
+			relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
 			relayMinter.txQuerier = newMockTxQuerier(testCase.receivedBankSendTxs, testCase.mintTxs,
 				testCase.sentBankSendTxs, testCase.failMintTxsQuery)
 			mts := newMockTxSender(testCase.failAllSendTx)
@@ -101,6 +114,108 @@ func TestRelay(t *testing.T) {
 	}
 }
 
+func TestShouldPruneScannedTxsUpToSettledHeight(t *testing.T) {
+	relayMinter, mts, _, blockQuerier := newNodeStatusTestRelayMinter(t)
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Len(t, mts.outputMsgs, 1)
+
+	state, err := relayMinter.stateStorage.GetState()
+	require.NoError(t, err)
+	require.Equal(t, blockQuerier.status.LatestHeight-3, state.Height)
+	require.Equal(t, []int64{state.Height}, relayMinter.txQuerier.(*mockTxQuerier).prunedHeights)
+}
+
+func TestShouldRefundWholeCartIfAnyItemIsUnavailableWithAllOrNothingPolicy(t *testing.T) {
+	setCudosConfig()
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	privKey, err := key.PrivKeyFromMnemonic(walletMnemonic)
+	require.NoError(t, err)
+
+	mockTokenisedInfraClient := newTokenisedInfraClient(map[string]model.NFTData{
+		"nftuid#1": {
+			Id:              "nftuid#1",
+			Price:           sdk.NewIntFromUint64(8000000000000000000),
+			DenomID:         "testdenom",
+			Status:          model.QueuedNFTStatus,
+			PriceValidUntil: tomorrow,
+		},
+	}, nil, nil)
+
+	cfg := config.Config{PaymentDenom: "acudos", CartFailurePolicy: config.CartPolicyAllOrNothing}
+	relayMinter := NewRelayMinter(newMockLogger(), &encodingConfig, cfg, newMockState(),
+		mockTokenisedInfraClient, privKey, nil, nil, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
+
+	buyer, err := sdk.AccAddressFromBech32(refundReceiver)
+	require.NoError(t, err)
+
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
+	relayMinter.txQuerier = newMockTxQuerier(buildTestResultTxSearch(t, [][]sdk.Msg{
+		{
+			banktypes.NewMsgSend(buyer, relayMinter.walletAddress, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(16000000000000000000)))),
+		},
+	}, []string{
+		"{\"uuids\":[\"nftuid#1\",\"notfoundnftuid\"]}",
+	}, &encodingConfig, cartTxHash), nil, nil, false)
+	mts := newMockTxSender(false)
+	relayMinter.txSender = mts
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Equal(t, []string{"{\"tx_hash\":\"" + cartTxHash + "\",\"items\":[0,1]}"}, mts.outputMemos)
+	require.Equal(t, []sdk.Msg{
+		banktypes.NewMsgSend(relayMinter.walletAddress, buyer, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(16000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+	}, mts.outputMsgs)
+}
+
+func TestShouldMintCartOfFiveNFTsInSingleTransaction(t *testing.T) {
+	setCudosConfig()
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	privKey, err := key.PrivKeyFromMnemonic(walletMnemonic)
+	require.NoError(t, err)
+
+	uids := []string{}
+	nftsData := map[string]model.NFTData{}
+	for i := 0; i < 5; i++ {
+		uid := fmt.Sprintf("1b4e28ba-2fa1-11d2-883f-0016d3cca4%02d", i)
+		uids = append(uids, uid)
+		nftsData[uid] = model.NFTData{
+			Id:              uid,
+			Price:           sdk.NewIntFromUint64(1000000000000000000),
+			DenomID:         "testdenom",
+			Status:          model.QueuedNFTStatus,
+			PriceValidUntil: tomorrow,
+		}
+	}
+
+	relayMinter := NewRelayMinter(newMockLogger(), &encodingConfig, config.Config{PaymentDenom: "acudos"}, newMockState(),
+		newTokenisedInfraClient(nftsData, nil, nil), privKey, nil, nil, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
+
+	buyer, err := sdk.AccAddressFromBech32(refundReceiver)
+	require.NoError(t, err)
+
+	incomingMemo, err := json.Marshal(map[string][]string{"uuids": uids})
+	require.NoError(t, err)
+
+	paymentTxHash := strings.Repeat("AB", 32)
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
+	relayMinter.txQuerier = newMockTxQuerier(buildTestResultTxSearch(t, [][]sdk.Msg{
+		{
+			banktypes.NewMsgSend(buyer, relayMinter.walletAddress, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(6000000000000000000)))),
+		},
+	}, []string{string(incomingMemo)}, &encodingConfig, paymentTxHash), nil, nil, false)
+	mts := newMockTxSender(false)
+	relayMinter.txSender = mts
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Equal(t, []string{"{\"tx_hash\":\"" + paymentTxHash + "\",\"items\":[0,1,2,3,4]}"}, mts.outputMemos)
+	require.Len(t, mts.outputMsgs, 5)
+	for i, msg := range mts.outputMsgs {
+		require.Equal(t, uids[i], msg.(*marketplacetypes.MsgMintNft).Uid)
+	}
+}
+
 func TestShouldRetryIfGRPCConnectionFails(t *testing.T) {
 	privKey, err := key.PrivKeyFromMnemonic(walletMnemonic)
 	require.NoError(t, err)
@@ -113,7 +228,7 @@ func TestShouldRetryIfGRPCConnectionFails(t *testing.T) {
 		MaxRetries:    10,
 	}
 	grpcConnector := mockGRPCConnector{}
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, cfg, nil, nil, privKey, &grpcConnector, rpc.RPCConnector{}, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, cfg, nil, nil, privKey, nil, nil, &grpcConnector, rpc.RPCConnector{}, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	go relayMinter.Start(ctx)
@@ -133,7 +248,7 @@ func TestShouldRetryIfRPCConnectionFails(t *testing.T) {
 		MaxRetries:    10,
 	}
 	rpcConnector := mockRPCConnector{}
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, cfg, nil, nil, privKey, grpc.GRPCConnector{}, &rpcConnector, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, cfg, nil, nil, privKey, nil, nil, grpc.GRPCConnector{}, &rpcConnector, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	go relayMinter.Start(ctx)
@@ -147,7 +262,7 @@ func TestShouldFailMintIfEstimateGasFails(t *testing.T) {
 
 	mockLogger := newMockLogger()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
 
 	gasEstimateFail := errors.New("failed to estimate gas")
 	mcts := mockCallsTxSender{}
@@ -155,7 +270,7 @@ func TestShouldFailMintIfEstimateGasFails(t *testing.T) {
 	relayMinter.txSender = &mcts
 
 	err = relayMinter.mint(context.Background(), "txHash", "uid", refundReceiver,
-		model.NFTData{Status: model.QueuedNFTStatus, PriceValidUntil: tomorrow, Price: sdk.OneInt()}, sdk.NewCoin("acudos", sdk.NewIntFromUint64(100)))
+		model.NFTData{Status: model.QueuedNFTStatus, PriceValidUntil: tomorrow, Price: sdk.OneInt()}, sdk.NewCoin("acudos", sdk.NewIntFromUint64(100)), model.Decision{})
 	require.Equal(t, gasEstimateFail, err)
 }
 
@@ -165,7 +280,7 @@ func TestShouldFailMintIfReceivedAmountIsSmallerThanTheGasFails(t *testing.T) {
 
 	mockLogger := newMockLogger()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
 
 	mcts := mockCallsTxSender{}
 	mcts.On("EstimateGas", mock.Anything, mock.Anything, mock.Anything).Return(model.GasResult{GasLimit: 1}, nil)
@@ -177,7 +292,7 @@ func TestShouldFailMintIfReceivedAmountIsSmallerThanTheGasFails(t *testing.T) {
 		PriceValidUntil: tomorrow,
 	}
 	err = relayMinter.mint(context.Background(), "txHash", "uid", refundReceiver,
-		nftData, sdk.NewCoin("acudos", sdk.NewIntFromUint64(100)))
+		nftData, sdk.NewCoin("acudos", sdk.NewIntFromUint64(100)), model.Decision{})
 	require.Equal(t, errors.New("during mint received amount (100) is smaller than the gas (5000000000000)"), err)
 }
 
@@ -187,7 +302,7 @@ func TestShouldFailMintIfSendTxFails(t *testing.T) {
 
 	mockLogger := newMockLogger()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
 
 	sendTxFail := errors.New("failed to send tx")
 	mcts := mockCallsTxSender{}
@@ -202,7 +317,7 @@ func TestShouldFailMintIfSendTxFails(t *testing.T) {
 	}
 
 	err = relayMinter.mint(context.Background(), "txHash", "uid", refundReceiver,
-		nftData, sdk.NewCoin("acudos", sdk.NewIntFromUint64(10000000000000000)))
+		nftData, sdk.NewCoin("acudos", sdk.NewIntFromUint64(10000000000000000)), model.Decision{})
 	require.Equal(t, sendTxFail, err)
 }
 
@@ -212,11 +327,12 @@ func TestShouldFailRefundIfParsingWalletAddressFails(t *testing.T) {
 
 	mockLogger := newMockLogger()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, nil, nil, email.NewSendgridEmailService(config.Config{}), nil, nil)
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
 	relayMinter.txQuerier = newMockTxQuerier(nil, nil, nil, false)
 	relayMinter.walletAddress = sdk.AccAddress{}
 
-	err = relayMinter.refund(context.Background(), "txHash", "refundReceiver", sdk.NewCoin("acudos", sdk.NewInt(0)))
+	err = relayMinter.refund(context.Background(), "txHash", relayMinter.walletAddress.String(), "refundReceiver", sdk.NewCoin("acudos", sdk.NewInt(0)), model.Decision{})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "invalid wallet address")
 }
@@ -227,10 +343,11 @@ func TestShouldFailRefundIfParsingRefundReceiverAddressFails(t *testing.T) {
 
 	mockLogger := newMockLogger()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, nil, nil, email.NewSendgridEmailService(config.Config{}), nil, nil)
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
 	relayMinter.txQuerier = newMockTxQuerier(nil, nil, nil, false)
 
-	err = relayMinter.refund(context.Background(), "txHash", "refundReceiver", sdk.NewCoin("acudos", sdk.NewInt(0)))
+	err = relayMinter.refund(context.Background(), "txHash", relayMinter.walletAddress.String(), "refundReceiver", sdk.NewCoin("acudos", sdk.NewInt(0)), model.Decision{})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "invalid refund receiver address")
 }
@@ -241,7 +358,8 @@ func TestShouldFailRefundIfEstimateGasFails(t *testing.T) {
 
 	mockLogger := newMockLogger()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, nil, nil, email.NewSendgridEmailService(config.Config{}), nil, nil)
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
 	relayMinter.txQuerier = newMockTxQuerier(nil, nil, nil, false)
 
 	gasEstimateFail := errors.New("failed to estimate gas")
@@ -249,7 +367,7 @@ func TestShouldFailRefundIfEstimateGasFails(t *testing.T) {
 	mcts.On("EstimateGas", mock.Anything, mock.Anything, mock.Anything).Return(model.GasResult{}, gasEstimateFail)
 	relayMinter.txSender = &mcts
 
-	err = relayMinter.refund(context.Background(), "txHash", refundReceiver, sdk.NewCoin("acudos", sdk.NewInt(0)))
+	err = relayMinter.refund(context.Background(), "txHash", relayMinter.walletAddress.String(), refundReceiver, sdk.NewCoin("acudos", sdk.NewInt(0)), model.Decision{})
 	require.Equal(t, gasEstimateFail, err)
 }
 
@@ -259,19 +377,20 @@ func TestShouldFailRefundIfIsRefundFails(t *testing.T) {
 
 	mockLogger := newMockLogger()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, newMockState(), nil, privKey, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, newMockState(), nil, privKey, nil, nil, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
 
 	txQuerier := mockCallsTxQuerier{}
 	failedQuery := errors.New("failed query")
 	txQuerier.On("Query", mock.Anything, fmt.Sprintf("tx.height>=0 AND transfer.sender='%s' AND transfer.recipient='%s'", relayMinter.walletAddress.String(), relayMinter.walletAddress.String())).Return(&ctypes.ResultTxSearch{}, failedQuery)
 	txQuerier.On("Query", mock.Anything, fmt.Sprintf("tx.height>=0 AND marketplace_mint_nft.buyer='%s'", relayMinter.walletAddress.String())).Return(&ctypes.ResultTxSearch{}, nil)
-	txQuerier.On("Query", mock.Anything, fmt.Sprintf("tx.height>0 AND transfer.recipient='%s'", relayMinter.walletAddress.String())).Return(buildTestResultTxSearch(t, [][]sdk.Msg{
+	txQuerier.On("Query", mock.Anything, fmt.Sprintf("tx.height>0 AND tx.height<=100 AND transfer.recipient='%s'", relayMinter.walletAddress.String())).Return(buildTestResultTxSearch(t, [][]sdk.Msg{
 		{
 			banktypes.NewMsgSend(relayMinter.walletAddress, relayMinter.walletAddress, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(100)))),
 		},
 	}, []string{
 		"{\"uuid\":\"nftuid#1\"}",
 	}, &encodingConfig, ""), nil)
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
 	relayMinter.txQuerier = &txQuerier
 
 	mcts := mockCallsTxSender{}
@@ -288,11 +407,12 @@ func TestShouldFailIsMintedIfQueryFails(t *testing.T) {
 
 	mockLogger := newMockLogger()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, nil, nil, email.NewSendgridEmailService(config.Config{}), nil, nil)
 
 	txQuerier := mockCallsTxQuerier{}
 	failedQuery := errors.New("failed query")
 	txQuerier.On("Query", mock.Anything, mock.Anything).Return(&ctypes.ResultTxSearch{}, failedQuery)
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
 	relayMinter.txQuerier = &txQuerier
 
 	isMinted, err := relayMinter.isMintedNft(context.Background(), "testuid", 0)
@@ -306,7 +426,7 @@ func TestIsMintedShouldLogErrorIfDecodingTxFails(t *testing.T) {
 
 	mockLogger := newMockLogger()
 	encodingConfig := encodingconfig.MakeEncodingConfig()
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, config.Config{PaymentDenom: "acudos"}, nil, nil, privKey, nil, nil, nil, nil, nil, email.NewSendgridEmailService(config.Config{}), nil, nil)
 
 	txQuerier := mockCallsTxQuerier{}
 	txQuerier.On("Query", mock.Anything, mock.Anything).Return(&ctypes.ResultTxSearch{
@@ -314,6 +434,7 @@ func TestIsMintedShouldLogErrorIfDecodingTxFails(t *testing.T) {
 			{},
 		},
 	}, nil)
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
 	relayMinter.txQuerier = &txQuerier
 
 	mctc := mockTxCoder{}
@@ -347,7 +468,7 @@ func TestShouldRetryRelayingIfRelayFails(t *testing.T) {
 	mcss := mockCallsStateStorage{}
 	mcss.On("GetState").Return(model.State{}, failedGettingState)
 
-	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, cfg, &mcss, nil, privKey, grpc.GRPCConnector{}, rpc.RPCConnector{}, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(mockLogger, &encodingConfig, cfg, &mcss, nil, privKey, nil, nil, grpc.GRPCConnector{}, rpc.RPCConnector{}, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	go relayMinter.Start(ctx)
@@ -362,10 +483,11 @@ func TestShouldFailRelayIfQueryFails(t *testing.T) {
 
 	mockStatesStorage := newMockState()
 
-	relayMinter := NewRelayMinter(newMockLogger(), nil, config.Config{}, mockStatesStorage, nil, privKey, nil, nil, nil, email.NewSendgridEmailService(config.Config{}))
+	relayMinter := NewRelayMinter(newMockLogger(), nil, config.Config{}, mockStatesStorage, nil, privKey, nil, nil, nil, nil, nil, email.NewSendgridEmailService(config.Config{}), nil, nil)
 	txQuerier := mockCallsTxQuerier{}
 	failedQuery := errors.New("failed query")
 	txQuerier.On("Query", mock.Anything, mock.Anything).Return(&ctypes.ResultTxSearch{}, failedQuery)
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
 	relayMinter.txQuerier = &txQuerier
 
 	mcts := mockCallsTxSender{}
@@ -0,0 +1,295 @@
+package relayminter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/state"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// Reprocessing the payments of a single incoming transaction through the same pipeline as a relay tick, including all idempotency checks,
+// so a payment that was already minted or refunded is skipped. The block scanner finds the transaction only by the height of its block,
+// the tx indexer of the node by its hash alone. With dry run the outgoing transactions are built and their gas is estimated but they are not sent.
+// A shadow instance always runs dry. The state is not changed.
+func (rm *relayMinter) ReprocessTx(ctx context.Context, txHash string, height int64, dryRun bool) (model.ReprocessReport, error) {
+	disconnect, err := rm.connectForReprocess(ctx)
+	if err != nil {
+		return model.ReprocessReport{}, err
+	}
+	defer disconnect()
+
+	return rm.reprocessTx(ctx, txHash, height, dryRun || rm.config.HasShadowMode())
+}
+
+// Reprocessing the payments after the from height up to the to height through the same pipeline as a relay tick, see ReprocessTx.
+// Without a to height the payments up to the confirmed height are reprocessed.
+func (rm *relayMinter) ReprocessRange(ctx context.Context, fromHeight, toHeight int64, dryRun bool) (model.ReprocessReport, error) {
+	disconnect, err := rm.connectForReprocess(ctx)
+	if err != nil {
+		return model.ReprocessReport{}, err
+	}
+	defer disconnect()
+
+	return rm.reprocessRange(ctx, fromHeight, toHeight, dryRun || rm.config.HasShadowMode())
+}
+
+// Connecting the queriers and the wallets as the relayer does, except the tx index. Without it the idempotency checks search the chain only,
+// so they do not depend on an index that may be behind the reprocessed payments. The returned function closes the connections.
+func (rm *relayMinter) connectForReprocess(ctx context.Context) (func(), error) {
+	grpcConn, err := rm.grpcConnector.MakeGRPCClient(rm.config.ChainGRPC)
+	if err != nil {
+		return nil, fmt.Errorf("dialing GRPC url (%s) failed: %s", rm.config.ChainGRPC, err)
+	}
+
+	closeQueriers, err := rm.connectQueriers(grpcConn)
+	if err != nil {
+		grpcConn.Close()
+		return nil, err
+	}
+
+	disconnect := func() {
+		closeQueriers()
+		grpcConn.Close()
+	}
+
+	if err := rm.connectWallets(ctx, grpcConn); err != nil {
+		disconnect()
+		return nil, err
+	}
+
+	return disconnect, nil
+}
+
+func (rm *relayMinter) reprocessTx(ctx context.Context, txHash string, height int64, dryRun bool) (model.ReprocessReport, error) {
+	confirmedHeight, err := rm.checkNodeForReprocess(ctx)
+	if err != nil {
+		return model.ReprocessReport{}, err
+	}
+
+	result, err := rm.queryIncomingTransaction(ctx, strings.ToUpper(txHash), height)
+	if err != nil {
+		return model.ReprocessReport{}, err
+	}
+
+	if result.Height > confirmedHeight {
+		return model.ReprocessReport{}, fmt.Errorf("transaction (%s) at height %d is not confirmed yet, the confirmed height is %d", result.Hash.String(), result.Height, confirmedHeight)
+	}
+
+	// Reporting why the transaction holds no payment, the relay tick only logs it
+	if _, err := rm.getReceivedBankSendInfos(result); err != nil {
+		return model.ReprocessReport{}, err
+	}
+
+	report := model.ReprocessReport{TxHash: result.Hash.String(), FromHeight: result.Height - 1, ToHeight: result.Height, DryRun: dryRun}
+	recorder := rm.recordTxs(dryRun)
+
+	report.Payments, err = rm.reprocessPayments(ctx, []*ctypes.ResultTx{result})
+	report.Txs = recorder.recorded()
+	return report, err
+}
+
+func (rm *relayMinter) reprocessRange(ctx context.Context, fromHeight, toHeight int64, dryRun bool) (model.ReprocessReport, error) {
+	confirmedHeight, err := rm.checkNodeForReprocess(ctx)
+	if err != nil {
+		return model.ReprocessReport{}, err
+	}
+
+	if toHeight == 0 {
+		toHeight = confirmedHeight
+	}
+
+	if toHeight > confirmedHeight {
+		return model.ReprocessReport{}, fmt.Errorf("height %d is not confirmed yet, the confirmed height is %d", toHeight, confirmedHeight)
+	}
+
+	if toHeight <= fromHeight {
+		return model.ReprocessReport{}, fmt.Errorf("nothing to reprocess after %d up to %d", fromHeight, toHeight)
+	}
+
+	report := model.ReprocessReport{FromHeight: fromHeight, ToHeight: toHeight, DryRun: dryRun}
+	recorder := rm.recordTxs(dryRun)
+
+	err = rm.forEachWindow(fromHeight, toHeight, func(from, to int64) error {
+		txs, err := rm.queryPaymentTransactions(ctx, from, to)
+		if err != nil {
+			return err
+		}
+
+		payments, err := rm.reprocessPayments(ctx, txs)
+		report.Payments += payments
+		return err
+	})
+
+	report.Txs = recorder.recorded()
+	if err != nil {
+		return report, err
+	}
+
+	rm.logger.Infof("reprocessed %d payments after %d up to %d with %d outgoing transactions", report.Payments, fromHeight, toHeight, len(report.Txs))
+	return report, nil
+}
+
+// Nothing is reprocessed while the node is not synced, the idempotency checks could miss transactions then. Returning the confirmed height.
+func (rm *relayMinter) checkNodeForReprocess(ctx context.Context) (int64, error) {
+	confirmedHeight, ready, err := rm.checkNode(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if !ready {
+		return 0, fmt.Errorf("the node is catching up or its latest block is stale")
+	}
+
+	return confirmedHeight, nil
+}
+
+// Finding the incoming transaction by its hash. With the height of its block only the payments of that block are searched,
+// which works with every payment source. Without it the transaction is searched by its hash, which needs the tx indexer of the node.
+func (rm *relayMinter) queryIncomingTransaction(ctx context.Context, txHash string, height int64) (*ctypes.ResultTx, error) {
+	var results []*ctypes.ResultTx
+	if height > 0 {
+		txs, err := rm.queryPaymentTransactions(ctx, height-1, height)
+		if err != nil {
+			return nil, err
+		}
+		results = txs
+	} else {
+		if rm.config.ScansBlocks() {
+			return nil, fmt.Errorf("the block scanner needs the height of transaction (%s)", txHash)
+		}
+
+		txs, err := rm.txQuerier.Query(ctx, fmt.Sprintf("tx.hash='%s'", txHash))
+		if err != nil {
+			return nil, err
+		}
+		if txs != nil {
+			results = txs.Txs
+		}
+	}
+
+	for _, result := range results {
+		if result.Hash.String() == txHash {
+			return result, nil
+		}
+	}
+
+	return nil, fmt.Errorf("transaction (%s) not found", txHash)
+}
+
+// Processing the payments of the transactions as relayWindow does, without updating the state. Returning the number of processed payments,
+// none if any of them failed.
+func (rm *relayMinter) reprocessPayments(ctx context.Context, txs []*ctypes.ResultTx) (int, error) {
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].Height < txs[j].Height
+	})
+
+	if rm.config.HasBatchTxs() {
+		rm.batch = newTxBatch()
+		defer func() { rm.batch = nil }()
+	}
+
+	payments := rm.pendingPayments(txs)
+	if _, err := rm.processPayments(ctx, payments); err != nil {
+		return 0, err
+	}
+
+	if rm.batch != nil {
+		if err := rm.flushBatch(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(payments), nil
+}
+
+// Replacing the tx senders of all wallets by ones recording the sent transactions. In a dry run they are recorded only,
+// otherwise the decisions are logged as by the relayer.
+func (rm *relayMinter) recordTxs(dryRun bool) *txRecorder {
+	recorder := newTxRecorder(rm.logger, rm.encodingConfig.Marshaler, dryRun)
+	rm.wrapTxSenders(recorder.wrap)
+
+	if !dryRun && rm.config.HasDecisionLog() {
+		rm.decisionLog = state.NewFileDecisionLog(rm.config.DecisionLogFile)
+	}
+
+	return recorder
+}
+
+func newTxRecorder(logger relayLogger, cdc codec.JSONCodec, dryRun bool) *txRecorder {
+	return &txRecorder{
+		logger: logger,
+		cdc:    cdc,
+		dryRun: dryRun,
+		txs:    []model.ReprocessTx{},
+	}
+}
+
+func (r *txRecorder) wrap(sender txSender, address string) txSender {
+	return &recordingTxSender{txSender: sender, recorder: r, address: address}
+}
+
+// Recording an outgoing transaction with its messages as JSON. It never fails, a sent transaction must not be taken for a failed one.
+func (r *txRecorder) record(address string, msgs []sdk.Msg, memo, txHash string) {
+	tx := model.ReprocessTx{Sender: address, Memo: memo, Msgs: []json.RawMessage{}, Hash: txHash}
+	for _, msg := range msgs {
+		msgJSON, err := r.cdc.MarshalInterfaceJSON(msg)
+		if err != nil {
+			r.logger.Warnf("marshaling message of transaction with memo (%s) failed: %s", memo, err)
+			continue
+		}
+		tx.Msgs = append(tx.Msgs, msgJSON)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.txs = append(r.txs, tx)
+}
+
+func (r *txRecorder) recorded() []model.ReprocessTx {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.ReprocessTx{}, r.txs...)
+}
+
+func (s *recordingTxSender) EstimateGas(ctx context.Context, msgs []sdk.Msg, memo string) (model.GasResult, error) {
+	return s.txSender.EstimateGas(ctx, msgs, memo)
+}
+
+// Sending the transaction and recording it. In a dry run the transaction is recorded only and an empty hash is returned.
+func (s *recordingTxSender) SendTx(ctx context.Context, msgs []sdk.Msg, memo string, gasResult model.GasResult) (string, error) {
+	if s.recorder.dryRun {
+		s.recorder.logger.Infof("dry run, not sending transaction of wallet %s with memo (%s)", s.address, memo)
+		s.recorder.record(s.address, msgs, memo, "")
+		return "", nil
+	}
+
+	txHash, err := s.txSender.SendTx(ctx, msgs, memo, gasResult)
+	if err != nil {
+		return "", err
+	}
+
+	s.recorder.record(s.address, msgs, memo, txHash)
+	return txHash, nil
+}
+
+type txRecorder struct {
+	logger relayLogger
+	cdc    codec.JSONCodec
+	dryRun bool
+	mu     sync.Mutex
+	txs    []model.ReprocessTx
+}
+
+type recordingTxSender struct {
+	txSender txSender
+	recorder *txRecorder
+	address  string
+}
@@ -0,0 +1,121 @@
+package relayminter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+func TestShouldReprocessPaymentsOfRange(t *testing.T) {
+	relayMinter, mts, _, _ := newReprocessTestRelayMinter(t)
+
+	report, err := relayMinter.reprocessRange(context.Background(), 0, 0, false)
+	require.NoError(t, err)
+	require.Equal(t, int64(97), report.ToHeight)
+	require.Equal(t, 1, report.Payments)
+	require.Len(t, mts.outputMsgs, 1)
+	require.Equal(t, []string{batchTxHash}, mts.outputMemos)
+
+	require.Len(t, report.Txs, 1)
+	require.Equal(t, batchTxHash, report.Txs[0].Memo)
+	require.Equal(t, relayMinter.walletAddress.String(), report.Txs[0].Sender)
+	require.Contains(t, string(report.Txs[0].Msgs[0]), "nftuid#1")
+
+	// The state is left as it is
+	require.Equal(t, int64(0), relayMinter.stateStorage.(*mockState).state.Height)
+}
+
+func TestShouldNotSendTxsInDryRun(t *testing.T) {
+	relayMinter, mts, _, _ := newReprocessTestRelayMinter(t)
+
+	report, err := relayMinter.reprocessRange(context.Background(), 0, 50, true)
+	require.NoError(t, err)
+	require.True(t, report.DryRun)
+	require.Empty(t, mts.outputMsgs)
+	require.Len(t, report.Txs, 1)
+	require.Equal(t, batchTxHash, report.Txs[0].Memo)
+	require.Empty(t, report.Txs[0].Hash)
+}
+
+func TestShouldSkipReprocessedPaymentAlreadyRefunded(t *testing.T) {
+	relayMinter, mts, _, _ := newReprocessTestRelayMinter(t)
+	relayMinter.txQuerier.(*mockTxQuerier).refundQueryResults = buildIndexTestTxs(t, 20, []sdk.Msg{newIndexTestRefund(t, relayMinter.walletAddress.String())}, batchTxHash)
+
+	report, err := relayMinter.reprocessRange(context.Background(), 0, 0, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Payments)
+	require.Empty(t, report.Txs)
+	require.Empty(t, mts.outputMsgs)
+}
+
+func TestShouldReprocessTxByHash(t *testing.T) {
+	relayMinter, mts, _, _ := newReprocessTestRelayMinter(t)
+	payments := relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults
+	relayMinter.txQuerier = &txByHashQuerier{txQuerier: relayMinter.txQuerier, results: payments}
+
+	report, err := relayMinter.reprocessTx(context.Background(), strings.ToLower(batchTxHash), 0, false)
+	require.NoError(t, err)
+	require.Equal(t, batchTxHash, report.TxHash)
+	require.Equal(t, int64(10), report.ToHeight)
+	require.Len(t, mts.outputMsgs, 1)
+
+	_, err = relayMinter.reprocessTx(context.Background(), "AA01", 0, false)
+	require.Equal(t, errors.New("transaction (AA01) not found"), err)
+}
+
+func TestShouldReprocessTxAtHeightWithBlockScanner(t *testing.T) {
+	relayMinter, mts, _, _ := newReprocessTestRelayMinter(t)
+	relayMinter.config.PaymentSource = config.PaymentSourceBlockScan
+
+	_, err := relayMinter.reprocessTx(context.Background(), batchTxHash, 0, false)
+	require.Equal(t, errors.New("the block scanner needs the height of transaction (0123ABCDEF)"), err)
+
+	report, err := relayMinter.reprocessTx(context.Background(), batchTxHash, 10, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Payments)
+	require.Len(t, mts.outputMsgs, 1)
+}
+
+func TestShouldNotReprocessUnconfirmedPayments(t *testing.T) {
+	relayMinter, mts, _, blockQuerier := newReprocessTestRelayMinter(t)
+
+	_, err := relayMinter.reprocessRange(context.Background(), 0, 99, false)
+	require.Equal(t, errors.New("height 99 is not confirmed yet, the confirmed height is 97"), err)
+
+	relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults.Txs[0].Height = 99
+	_, err = relayMinter.reprocessTx(context.Background(), batchTxHash, 99, false)
+	require.Equal(t, errors.New("transaction (0123ABCDEF) at height 99 is not confirmed yet, the confirmed height is 97"), err)
+
+	blockQuerier.status.CatchingUp = true
+	_, err = relayMinter.reprocessRange(context.Background(), 0, 0, false)
+	require.Equal(t, errors.New("the node is catching up or its latest block is stale"), err)
+	require.Empty(t, mts.outputMsgs)
+}
+
+// Building a relay minter with a single payment at height 10 and recording its transactions as the reprocess commands do.
+func newReprocessTestRelayMinter(t *testing.T) (*relayMinter, *mockTxSender, *mockEmailService, *mockBlockQuerier) {
+	relayMinter, mts, emailService, blockQuerier := newNodeStatusTestRelayMinter(t)
+	relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults.Txs[0].Height = 10
+
+	return relayMinter, mts, emailService, blockQuerier
+}
+
+// Returning the results for the queries by hash and delegating all other queries.
+func (q *txByHashQuerier) Query(ctx context.Context, query string) (*ctypes.ResultTxSearch, error) {
+	if strings.HasPrefix(query, "tx.hash=") {
+		return q.results, nil
+	}
+
+	return q.txQuerier.Query(ctx, query)
+}
+
+type txByHashQuerier struct {
+	txQuerier txQuerier
+	results   *ctypes.ResultTxSearch
+}
@@ -0,0 +1,145 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// Sweeping the balance of the payment wallet above the float to the treasury.
+// Besides the float the wallet keeps the fee of the sweep and the amounts of all payments that are received but not processed yet,
+// because each of them may still have to be refunded. The sweep has a sweep memo, so it is never taken for a payment or a refund.
+// Every sweep is recorded in the sweep log and reported.
+func (rm *relayMinter) sweep(ctx context.Context) error {
+	treasuryAddress, err := sdk.AccAddressFromBech32(rm.config.TreasuryAddress)
+	if err != nil {
+		return fmt.Errorf("invalid treasury address (%s): %s", rm.config.TreasuryAddress, err)
+	}
+
+	if rm.isOwnWallet(treasuryAddress.String()) {
+		return fmt.Errorf("treasury address (%s) is a wallet of the service", treasuryAddress.String())
+	}
+
+	float, ok := sdk.NewIntFromString(rm.config.SweepFloat)
+	if !ok || float.IsNegative() {
+		return fmt.Errorf("invalid sweep float (%s)", rm.config.SweepFloat)
+	}
+
+	s, err := rm.stateStorage.GetState()
+	if err != nil {
+		return err
+	}
+
+	status, err := rm.blockQuerier.NodeStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The payments of a node catching up are not known yet
+	if status.CatchingUp {
+		rm.logger.Info("not sweeping because the node is catching up")
+		return nil
+	}
+
+	pending, err := rm.pendingPaymentsAmount(ctx, s.Height, status.LatestHeight-rm.config.ConfirmationDepth)
+	if err != nil {
+		return err
+	}
+
+	balance, err := rm.balanceQuerier.QueryBalance(ctx, rm.walletAddress.String(), rm.config.PaymentDenom)
+	if err != nil {
+		return fmt.Errorf("querying balance of wallet (%s) failed: %s", rm.walletAddress.String(), err)
+	}
+
+	kept := float.Add(pending)
+	if balance.LTE(kept) {
+		rm.logger.Infof("nothing to sweep, balance (%s%s) does not exceed the float with pending payments (%s%s)", balance.String(), rm.config.PaymentDenom, kept.String(), rm.config.PaymentDenom)
+		return nil
+	}
+
+	sweepTime := time.Now()
+	memo, err := encodeSweepMemo(sweepTime)
+	if err != nil {
+		return err
+	}
+
+	gasResult, err := rm.txSender.EstimateGas(ctx, []sdk.Msg{
+		banktypes.NewMsgSend(rm.walletAddress, treasuryAddress, sdk.NewCoins(sdk.NewCoin(rm.config.PaymentDenom, balance.Sub(kept)))),
+	}, memo)
+	if err != nil {
+		return err
+	}
+
+	kept = kept.Add(gasResult.FeeAmount.AmountOf(rm.config.PaymentDenom))
+	if balance.LTE(kept) {
+		rm.logger.Infof("nothing to sweep, balance (%s%s) does not exceed the float with pending payments and gas (%s%s)", balance.String(), rm.config.PaymentDenom, kept.String(), rm.config.PaymentDenom)
+		return nil
+	}
+
+	amount := sdk.NewCoin(rm.config.PaymentDenom, balance.Sub(kept))
+	txHash, err := rm.txSender.SendTx(ctx, []sdk.Msg{banktypes.NewMsgSend(rm.walletAddress, treasuryAddress, sdk.NewCoins(amount))}, memo, gasResult)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("swept %s from wallet (%s) to treasury (%s) with tx %s, kept %s%s", amount.String(), rm.walletAddress.String(), treasuryAddress.String(), txHash, kept.String(), rm.config.PaymentDenom)
+	rm.logger.Info(message)
+	rm.emailService.SendEmail(message)
+
+	if err := rm.sweepLog.RecordSweep(model.SweepRecord{
+		Time:    sweepTime.UTC(),
+		TxHash:  txHash,
+		From:    rm.walletAddress.String(),
+		To:      treasuryAddress.String(),
+		Amount:  amount.String(),
+		Balance: sdk.NewCoin(rm.config.PaymentDenom, balance).String(),
+		Kept:    sdk.NewCoin(rm.config.PaymentDenom, kept).String(),
+	}); err != nil {
+		return fmt.Errorf("recording sweep tx %s failed: %s", txHash, err)
+	}
+
+	return nil
+}
+
+// The total amount of the payments to the payment wallet after the given height up to the confirmed height. They are not processed yet,
+// so any of them may be refunded. The payments are queried window by window like the relayer does.
+// Payments to retired wallets are not counted, because they are refunded from the funds of the retired wallet.
+func (rm *relayMinter) pendingPaymentsAmount(ctx context.Context, height, confirmedHeight int64) (sdk.Int, error) {
+	pending := sdk.ZeroInt()
+
+	err := rm.forEachWindow(height, confirmedHeight, func(from, to int64) error {
+		txs, err := rm.queryPaymentTransactions(ctx, from, to)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range txs {
+			// Transactions that are not payments are skipped by the relayer as well, so they are never refunded
+			sendInfos, err := rm.getReceivedBankSendInfos(result)
+			if err != nil {
+				continue
+			}
+
+			for _, sendInfo := range sendInfos {
+				if sendInfo.ToAddress == rm.walletAddress.String() {
+					pending = pending.Add(sendInfo.Amount.Amount)
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return pending, err
+}
+
+// Reporting a failed sweep. Sweeps do not affect the processing of payments, so relaying continues and the sweep is retried on its next tick.
+func (rm *relayMinter) reportSweepError(err error) {
+	message := fmt.Sprintf("sweeping to treasury failed: %s", err)
+	rm.logger.Warn(message)
+	rm.emailService.SendEmail(message)
+}
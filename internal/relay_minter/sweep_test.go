@@ -0,0 +1,130 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldSweepBalanceAboveFloatPendingPaymentsAndGas(t *testing.T) {
+	relayMinter, mts, emailService, sweepLog := newSweepTestRelayMinter(t, sdk.NewIntFromUint64(15000000000000000000))
+
+	require.NoError(t, relayMinter.sweep(context.Background()))
+
+	// The float of 1 CUDOS, the pending payment of 9 CUDOS and the fee of the sweep are kept
+	kept := sdk.NewIntFromUint64(10000000000000000000).Add(sdk.NewIntFromUint64(mockGasLimit))
+	swept := sdk.NewCoin("acudos", sdk.NewIntFromUint64(15000000000000000000).Sub(kept))
+	require.Equal(t, []sdk.Msg{banktypes.NewMsgSend(relayMinter.walletAddress, treasuryAccAddress(t), sdk.NewCoins(swept))}, mts.outputMsgs)
+	require.Len(t, mts.outputMemos, 1)
+	require.True(t, isSweepMemo(mts.outputMemos[0]))
+
+	require.Len(t, sweepLog.records, 1)
+	require.Equal(t, swept.String(), sweepLog.records[0].Amount)
+	require.Equal(t, sdk.NewCoin("acudos", kept).String(), sweepLog.records[0].Kept)
+	require.Equal(t, treasuryAddress, sweepLog.records[0].To)
+	require.Len(t, emailService.emails, 1)
+}
+
+func TestShouldNotSweepIfBalanceDoesNotExceedFloatAndPendingPayments(t *testing.T) {
+	relayMinter, mts, emailService, sweepLog := newSweepTestRelayMinter(t, sdk.NewIntFromUint64(10000000000000000000))
+
+	require.NoError(t, relayMinter.sweep(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+	require.Empty(t, sweepLog.records)
+	require.Empty(t, emailService.emails)
+}
+
+func TestShouldQueryPendingPaymentsWindowByWindowUpToConfirmedHeight(t *testing.T) {
+	relayMinter, mts, _, _ := newSweepTestRelayMinter(t, sdk.NewIntFromUint64(15000000000000000000))
+	relayMinter.config.QueryWindow = 60
+	relayMinter.config.ConfirmationDepth = 3
+	txQuerier := &recordingTxQuerier{txQuerier: relayMinter.txQuerier}
+	relayMinter.txQuerier = txQuerier
+
+	require.NoError(t, relayMinter.sweep(context.Background()))
+
+	wallet := relayMinter.walletAddress.String()
+	require.Equal(t, []string{
+		fmt.Sprintf("tx.height>0 AND tx.height<=60 AND transfer.recipient='%s'", wallet),
+		fmt.Sprintf("tx.height>60 AND tx.height<=97 AND transfer.recipient='%s'", wallet),
+	}, txQuerier.queries)
+
+	// The mock returns the payment of 9 CUDOS for both windows, so 18 CUDOS and the float exceed the balance
+	require.Empty(t, mts.outputMsgs)
+}
+
+func TestShouldNotSweepWhileNodeIsCatchingUp(t *testing.T) {
+	relayMinter, mts, _, _ := newSweepTestRelayMinter(t, sdk.NewIntFromUint64(15000000000000000000))
+	relayMinter.blockQuerier.(*mockBlockQuerier).status.CatchingUp = true
+
+	require.NoError(t, relayMinter.sweep(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+}
+
+func TestShouldFailSweepToOwnWallet(t *testing.T) {
+	relayMinter, mts, _, _ := newSweepTestRelayMinter(t, sdk.NewIntFromUint64(15000000000000000000))
+	relayMinter.config.TreasuryAddress = relayMinter.walletAddress.String()
+
+	require.Error(t, relayMinter.sweep(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+}
+
+func TestShouldFailSweepWithInvalidFloat(t *testing.T) {
+	relayMinter, mts, _, _ := newSweepTestRelayMinter(t, sdk.NewIntFromUint64(15000000000000000000))
+	relayMinter.config.SweepFloat = "-1"
+
+	require.Error(t, relayMinter.sweep(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+}
+
+func TestShouldNotTakeSweepForPaymentOrRefund(t *testing.T) {
+	relayMinter, _, _, _ := newSweepTestRelayMinter(t, sdk.NewIntFromUint64(15000000000000000000))
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	memo, err := encodeSweepMemo(time.Now())
+	require.NoError(t, err)
+
+	sweeps := buildTestResultTxSearch(t, [][]sdk.Msg{
+		{
+			banktypes.NewMsgSend(relayMinter.walletAddress, treasuryAccAddress(t), sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000)))),
+		},
+	}, []string{memo}, &encodingConfig, batchTxHash)
+
+	_, err = relayMinter.getReceivedBankSendInfos(sweeps.Txs[0])
+	require.Error(t, err)
+
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
+	relayMinter.txQuerier = newMockTxQuerier(nil, nil, sweeps, false)
+	refunded, err := relayMinter.isRefunded(context.Background(), memo, 0, treasuryAddress)
+	require.NoError(t, err)
+	require.False(t, refunded)
+}
+
+// Building a relay minter with a float of 1 CUDOS and a single pending payment of 9 CUDOS.
+func newSweepTestRelayMinter(t *testing.T, balance sdk.Int) (*relayMinter, *mockTxSender, *mockEmailService, *mockSweepLog) {
+	built := newTestRelayMinter(t, testRelayMinterOptions{
+		cfg:     config.Config{TreasuryAddress: treasuryAddress, SweepFloat: "1000000000000000000"},
+		nfts:    map[string]model.NFTData{},
+		balance: balance,
+	})
+	sweepLog := &mockSweepLog{}
+	built.relayMinter.sweepLog = sweepLog
+
+	return built.relayMinter, built.txSender, built.emailService, sweepLog
+}
+
+func treasuryAccAddress(t *testing.T) sdk.AccAddress {
+	address, err := sdk.AccAddressFromBech32(treasuryAddress)
+	require.NoError(t, err)
+	return address
+}
+
+const treasuryAddress = "cudos1w3ex2ctnw4e8jtt5wfjkzum4wfuj6vfj2j3zeg"
@@ -0,0 +1,245 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// Indexing the outgoing transactions of the service in the blocks of a window, up to the height the state is settled to once the window is processed.
+// It is called by relayWindow after the payments of the window are queried and before they are processed, so the checks of the window use it.
+// A block the indexer of the node may not have indexed yet is never covered: the settled height is the end of the window only if it is below the
+// confirmed height or confirmations are required, otherwise it is the block of the last payment, which was indexed together with all blocks before it.
+// An empty index starts at the state height. The index is rebuilt if the tracked wallets change, because the transactions of a new wallet are not in it.
+func (rm *relayMinter) indexWindow(ctx context.Context, stateHeight, settledHeight int64) error {
+	if rm.txIndex == nil {
+		return nil
+	}
+
+	wallets := rm.trackedAddresses()
+	coverage, indexed := rm.txIndex.Coverage()
+	if indexed && !equalAddresses(coverage.Wallets, wallets) {
+		rm.logger.Infof("tracked wallets changed from %v to %v, rebuilding the tx index from height %d", coverage.Wallets, wallets, stateHeight)
+		if err := rm.txIndex.Reset(); err != nil {
+			return fmt.Errorf("resetting tx index failed: %s", err)
+		}
+		indexed = false
+	}
+
+	from := stateHeight
+	if indexed {
+		from = coverage.To
+	}
+
+	if from >= settledHeight {
+		return nil
+	}
+
+	txs, err := rm.queryOutgoingTransactions(ctx, from, settledHeight, wallets)
+	if err != nil {
+		return err
+	}
+
+	entry := model.TxIndexEntry{TxIndexRange: model.TxIndexRange{From: from, To: settledHeight, Wallets: wallets}, Txs: txs}
+	if err := rm.txIndex.AddEntry(entry); err != nil {
+		return fmt.Errorf("adding blocks after %d up to %d to tx index failed: %s", from, settledHeight, err)
+	}
+
+	rm.logger.Infof("indexed %d outgoing transactions after %d up to %d", len(txs), from, settledHeight)
+	return nil
+}
+
+// Fetching the mint and refund transactions signed by any of the wallets after the given height up to the end height.
+func (rm *relayMinter) queryOutgoingTransactions(ctx context.Context, height, endHeight int64, wallets []string) ([]model.IndexedTx, error) {
+	txs := []model.IndexedTx{}
+	seen := map[string]bool{}
+
+	for _, address := range wallets {
+		results, err := rm.txQuerier.Query(ctx, fmt.Sprintf("tx.height>%d AND tx.height<=%d AND message.sender='%s'", height, endHeight, address))
+		if err != nil {
+			return nil, err
+		}
+
+		if results == nil {
+			continue
+		}
+
+		for _, result := range results.Txs {
+			if seen[result.Hash.String()] {
+				continue
+			}
+			seen[result.Hash.String()] = true
+
+			if indexedTx, ok := rm.indexTx(result); ok {
+				txs = append(txs, indexedTx)
+			}
+		}
+	}
+
+	return txs, nil
+}
+
+// Extracting the mints and refunds of a transaction the same way the idempotency checks do.
+// Transactions that reference no payment, such as sweeps, are not indexed.
+func (rm *relayMinter) indexTx(result *ctypes.ResultTx) (model.IndexedTx, bool) {
+	txWithMemo, err := rm.decodeTx(result)
+	if err != nil {
+		rm.logger.Warnf("during indexing, decoding tx (%s) failed: %s", result.Hash.String(), err)
+		return model.IndexedTx{}, false
+	}
+
+	if isSweepMemo(txWithMemo.GetMemo()) {
+		return model.IndexedTx{}, false
+	}
+
+	decodedTx := NewDecodedTxWithMemo(result.Hash.String(), txWithMemo)
+	indexedTx := model.IndexedTx{Hash: decodedTx.Hash, Height: result.Height, Memo: decodedTx.Memo, Fees: decodedTx.Fees}
+
+	for _, memo := range decodeOutgoingMemos(decodedTx.Memo) {
+		if memo.TxHash != "" {
+			indexedTx.Refs = append(indexedTx.Refs, memo.TxHash)
+		}
+	}
+
+	if len(indexedTx.Refs) == 0 {
+		return model.IndexedTx{}, false
+	}
+
+	msgs := flattenExecMsgs(txWithMemo.GetMsgs())
+	if hasMintMsgs(msgs) {
+		for _, mintMsg := range rm.serviceMintMsgs(decodedTx.Hash, "indexing", txWithMemo.GetMsgs()) {
+			indexedTx.Mints = append(indexedTx.Mints, model.IndexedMint{Uid: mintMsg.Uid, Recipient: mintMsg.Recipient, Price: mintMsg.Price})
+		}
+	} else {
+		for _, msg := range msgs {
+			bankSendMsg, ok := msg.(*banktypes.MsgSend)
+			if !ok || !rm.isOwnWallet(bankSendMsg.FromAddress) {
+				continue
+			}
+
+			indexedTx.Refunds = append(indexedTx.Refunds, model.IndexedRefund{From: bankSendMsg.FromAddress, To: bankSendMsg.ToAddress, Amount: bankSendMsg.Amount})
+		}
+	}
+
+	if len(indexedTx.Mints) == 0 && len(indexedTx.Refunds) == 0 {
+		return model.IndexedTx{}, false
+	}
+
+	return indexedTx, true
+}
+
+// The height the chain has to be searched from for the outgoing transactions of a payment at the given height.
+// If the tx index covers the blocks from the payment on, only the blocks after the index are searched and the index answers for the rest.
+func (rm *relayMinter) outgoingSearchHeight(incomingPaymentTxHeight int64) (int64, bool) {
+	if rm.txIndex == nil {
+		return incomingPaymentTxHeight, false
+	}
+
+	coverage, indexed := rm.txIndex.Coverage()
+	if !indexed || coverage.From >= incomingPaymentTxHeight {
+		return incomingPaymentTxHeight, false
+	}
+
+	if coverage.To < incomingPaymentTxHeight {
+		return incomingPaymentTxHeight, true
+	}
+
+	return coverage.To + 1, true
+}
+
+// The indexed transactions at or after the given height with their mints accepted by the filter.
+func indexedMintTxs(txs []model.IndexedTx, incomingPaymentTxHeight int64, filter func(mint model.IndexedMint) bool) []*decodedTxWithMemo {
+	results := []*decodedTxWithMemo{}
+
+	for _, tx := range txs {
+		if tx.Height < incomingPaymentTxHeight {
+			continue
+		}
+
+		decodedTx := &decodedTxWithMemo{Hash: tx.Hash, Memo: tx.Memo, Fees: tx.Fees}
+		for _, mint := range tx.Mints {
+			if filter(mint) {
+				decodedTx.MintMsgs = append(decodedTx.MintMsgs, &marketplacetypes.MsgMintNft{Uid: mint.Uid, Recipient: mint.Recipient, Price: mint.Price})
+			}
+		}
+
+		if len(decodedTx.MintMsgs) > 0 {
+			results = append(results, decodedTx)
+		}
+	}
+
+	return results
+}
+
+// The indexed refunds of a payment to the refund receiver at or after the given height.
+func (rm *relayMinter) indexedRefundTxs(incomingPaymentTxHash string, incomingPaymentTxHeight int64, refundReceiver string) []*decodedTxWithMemo {
+	results := []*decodedTxWithMemo{}
+
+	for _, tx := range rm.indexedTxsByRef(incomingPaymentTxHash) {
+		if tx.Height < incomingPaymentTxHeight {
+			continue
+		}
+
+		decodedTx := &decodedTxWithMemo{Hash: tx.Hash, Memo: tx.Memo, Fees: tx.Fees}
+		for _, refund := range tx.Refunds {
+			if refund.To == refundReceiver {
+				decodedTx.BankSendMsgs = append(decodedTx.BankSendMsgs, &banktypes.MsgSend{FromAddress: refund.From, ToAddress: refund.To, Amount: refund.Amount})
+			}
+		}
+
+		if len(decodedTx.BankSendMsgs) > 0 {
+			results = append(results, decodedTx)
+		}
+	}
+
+	return results
+}
+
+// The indexed transactions that reference the payment, by its full reference or by the short one of a batch memo.
+func (rm *relayMinter) indexedTxsByRef(ref string) []model.IndexedTx {
+	txs := rm.txIndex.TxsByRef(ref)
+	if shortRef(ref) != ref {
+		txs = append(append([]model.IndexedTx{}, txs...), rm.txIndex.TxsByRef(shortRef(ref))...)
+	}
+
+	return txs
+}
+
+// The addresses whose transactions are searched besides the payments. These are all wallets the service has ever signed with and the authz minter.
+func (rm *relayMinter) trackedAddresses() []string {
+	addresses := rm.knownWalletAddresses()
+	if rm.config.HasAuthzMinter() {
+		addresses = append(addresses, rm.config.AuthzMinter)
+	}
+
+	return addresses
+}
+
+func hasMintMsgs(msgs []sdk.Msg) bool {
+	for _, msg := range msgs {
+		if _, ok := msg.(*marketplacetypes.MsgMintNft); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func equalAddresses(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
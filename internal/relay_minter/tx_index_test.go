@@ -0,0 +1,222 @@
+package relayminter
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/state"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+func TestShouldSkipPaymentRefundedInTxIndex(t *testing.T) {
+	relayMinter, mts, _, _ := newNodeStatusTestRelayMinter(t)
+	txIndex, err := state.NewFileTxIndex(filepath.Join(t.TempDir(), "tx_index.jsonl"))
+	require.NoError(t, err)
+	relayMinter.txIndex = txIndex
+
+	wallet := relayMinter.walletAddress.String()
+	payments := relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults
+	payments.Txs[0].Height = 10
+
+	txQuerier := &outgoingTxQuerier{
+		txQuerier: relayMinter.txQuerier,
+		sender:    wallet,
+		outgoing:  buildIndexTestTxs(t, 20, []sdk.Msg{newIndexTestRefund(t, wallet)}, batchTxHash),
+	}
+	relayMinter.txQuerier = txQuerier
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Empty(t, mts.outputMsgs)
+
+	coverage, ok := txIndex.Coverage()
+	require.True(t, ok)
+	require.Equal(t, model.TxIndexRange{From: 0, To: 97, Wallets: []string{wallet}}, coverage)
+	require.Contains(t, txQuerier.queries, fmt.Sprintf("tx.height>=98 AND transfer.sender='%s' AND transfer.recipient='%s'", wallet, refundReceiver))
+	require.Equal(t, int64(97), relayMinter.stateStorage.(*mockState).state.Height)
+}
+
+func TestShouldSearchChainForPaymentsBeforeTxIndex(t *testing.T) {
+	relayMinter, _, _, _ := newNodeStatusTestRelayMinter(t)
+	txIndex, err := state.NewFileTxIndex(filepath.Join(t.TempDir(), "tx_index.jsonl"))
+	require.NoError(t, err)
+	relayMinter.txIndex = txIndex
+
+	searchHeight, indexed := relayMinter.outgoingSearchHeight(10)
+	require.False(t, indexed)
+	require.Equal(t, int64(10), searchHeight)
+
+	require.NoError(t, txIndex.AddEntry(model.TxIndexEntry{TxIndexRange: model.TxIndexRange{From: 20, To: 40}}))
+
+	searchHeight, indexed = relayMinter.outgoingSearchHeight(10)
+	require.False(t, indexed)
+	require.Equal(t, int64(10), searchHeight)
+
+	searchHeight, indexed = relayMinter.outgoingSearchHeight(30)
+	require.True(t, indexed)
+	require.Equal(t, int64(41), searchHeight)
+}
+
+func TestShouldIndexMintsAndRefundsWindowByWindow(t *testing.T) {
+	relayMinter, _, _, _ := newNodeStatusTestRelayMinter(t)
+	relayMinter.config.QueryWindow = 20
+	txIndex, err := state.NewFileTxIndex(filepath.Join(t.TempDir(), "tx_index.jsonl"))
+	require.NoError(t, err)
+	relayMinter.txIndex = txIndex
+
+	wallet := relayMinter.walletAddress.String()
+	price := sdk.NewCoin("acudos", sdk.NewIntFromUint64(100))
+	mint := marketplacetypes.NewMsgMintNft(wallet, "testdenom", refundReceiver, "", "", "", "nftuid#1", price)
+	foreignSend := banktypes.NewMsgSend(indexTestAddress(t, refundReceiver), relayMinter.walletAddress, sdk.NewCoins(price))
+
+	outgoing := buildIndexTestTxs(t, 5, []sdk.Msg{mint}, "AA01")
+	outgoing.Txs = append(outgoing.Txs, buildIndexTestTxs(t, 6, []sdk.Msg{newIndexTestRefund(t, wallet)}, "{\"sweep\":1}").Txs...)
+	outgoing.Txs = append(outgoing.Txs, buildIndexTestTxs(t, 7, []sdk.Msg{foreignSend}, "AA02").Txs...)
+	outgoing.Txs[1].Hash = []byte{0x02}
+	outgoing.Txs[2].Hash = []byte{0x03}
+
+	txQuerier := &outgoingTxQuerier{sender: wallet, outgoing: outgoing}
+	relayMinter.txQuerier = txQuerier
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Equal(t, []string{
+		fmt.Sprintf("tx.height>0 AND tx.height<=20 AND transfer.recipient='%s'", wallet),
+		fmt.Sprintf("tx.height>0 AND tx.height<=20 AND message.sender='%s'", wallet),
+		fmt.Sprintf("tx.height>20 AND tx.height<=40 AND transfer.recipient='%s'", wallet),
+		fmt.Sprintf("tx.height>20 AND tx.height<=40 AND message.sender='%s'", wallet),
+		fmt.Sprintf("tx.height>40 AND tx.height<=60 AND transfer.recipient='%s'", wallet),
+		fmt.Sprintf("tx.height>40 AND tx.height<=60 AND message.sender='%s'", wallet),
+		fmt.Sprintf("tx.height>60 AND tx.height<=80 AND transfer.recipient='%s'", wallet),
+		fmt.Sprintf("tx.height>60 AND tx.height<=80 AND message.sender='%s'", wallet),
+		fmt.Sprintf("tx.height>80 AND tx.height<=97 AND transfer.recipient='%s'", wallet),
+		fmt.Sprintf("tx.height>80 AND tx.height<=97 AND message.sender='%s'", wallet),
+	}, txQuerier.queries)
+
+	mintTxs := txIndex.TxsByUid("nftuid#1")
+	require.Len(t, mintTxs, 1)
+	require.Equal(t, []string{"AA01"}, mintTxs[0].Refs)
+	require.Equal(t, []model.IndexedMint{{Uid: "nftuid#1", Recipient: refundReceiver, Price: price}}, mintTxs[0].Mints)
+	require.Empty(t, txIndex.TxsByRef("AA02"))
+
+	minted, err := relayMinter.queryNftMintTransactionByUid(context.Background(), "nftuid#1", 3, "minted")
+	require.NoError(t, err)
+	require.Len(t, minted, 1)
+	require.Equal(t, fmt.Sprintf("tx.height>=98 AND marketplace_mint_nft.uid='%s'", "nftuid#1"), txQuerier.queries[10])
+}
+
+func TestShouldRebuildTxIndexIfWalletsChange(t *testing.T) {
+	relayMinter, _, _, _ := newNodeStatusTestRelayMinter(t)
+	txIndex, err := state.NewFileTxIndex(filepath.Join(t.TempDir(), "tx_index.jsonl"))
+	require.NoError(t, err)
+	relayMinter.txIndex = txIndex
+	relayMinter.txQuerier = &outgoingTxQuerier{}
+
+	require.NoError(t, txIndex.AddEntry(model.TxIndexEntry{TxIndexRange: model.TxIndexRange{From: 0, To: 40, Wallets: []string{"retired"}}}))
+	require.NoError(t, relayMinter.indexWindow(context.Background(), 30, 50))
+
+	coverage, ok := txIndex.Coverage()
+	require.True(t, ok)
+	require.Equal(t, model.TxIndexRange{From: 30, To: 50, Wallets: []string{relayMinter.walletAddress.String()}}, coverage)
+}
+
+func TestShouldIndexOnlyUpToLastPaymentWithoutConfirmationDepth(t *testing.T) {
+	relayMinter, _, _, _ := newNodeStatusTestRelayMinter(t)
+	relayMinter.config.ConfirmationDepth = 0
+	txIndex, err := state.NewFileTxIndex(filepath.Join(t.TempDir(), "tx_index.jsonl"))
+	require.NoError(t, err)
+	relayMinter.txIndex = txIndex
+
+	wallet := relayMinter.walletAddress.String()
+	relayMinter.txQuerier.(*mockTxQuerier).bankSendQueryResults.Txs[0].Height = 10
+
+	txQuerier := &outgoingTxQuerier{txQuerier: relayMinter.txQuerier, sender: wallet, outgoing: &ctypes.ResultTxSearch{}}
+	relayMinter.txQuerier = txQuerier
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+
+	// The blocks after the last payment up to the latest one may not be indexed by the node yet
+	coverage, ok := txIndex.Coverage()
+	require.True(t, ok)
+	require.Equal(t, model.TxIndexRange{From: 0, To: 10, Wallets: []string{wallet}}, coverage)
+	require.Equal(t, []string{
+		fmt.Sprintf("tx.height>0 AND tx.height<=100 AND transfer.recipient='%s'", wallet),
+		fmt.Sprintf("tx.height>0 AND tx.height<=10 AND message.sender='%s'", wallet),
+	}, txQuerier.queries[:2])
+	require.Equal(t, int64(10), relayMinter.stateStorage.(*mockState).state.Height)
+}
+
+// A refund of the payment of the node status test relay minter sent by the given wallet.
+func newIndexTestRefund(t *testing.T, wallet string) sdk.Msg {
+	return banktypes.NewMsgSend(indexTestAddress(t, wallet), indexTestAddress(t, refundReceiver), sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(1000))))
+}
+
+func indexTestAddress(t *testing.T, address string) sdk.AccAddress {
+	accAddress, err := sdk.AccAddressFromBech32(address)
+	require.NoError(t, err)
+	return accAddress
+}
+
+// Building a single transaction with the given memo at the given height.
+func buildIndexTestTxs(t *testing.T, height int64, msgs []sdk.Msg, memo string) *ctypes.ResultTxSearch {
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+	results := buildTestResultTxSearch(t, [][]sdk.Msg{msgs}, []string{memo}, &encodingConfig, "01")
+	results.Txs[0].Height = height
+	return results
+}
+
+// Returning the outgoing transactions for the queries of the transactions of the sender, the incoming transactions for the queries
+// by hash and delegating all other queries. Only the transactions within the queried heights are returned.
+func (q *outgoingTxQuerier) Query(ctx context.Context, query string) (*ctypes.ResultTxSearch, error) {
+	q.queries = append(q.queries, query)
+
+	if strings.HasPrefix(query, "tx.hash=") {
+		results := &ctypes.ResultTxSearch{}
+		for _, result := range q.incoming {
+			if query == fmt.Sprintf("tx.hash='%s'", result.Hash.String()) {
+				results.Txs = append(results.Txs, result)
+			}
+		}
+		return results, nil
+	}
+
+	if strings.Contains(query, "message.sender") {
+		if q.outgoing == nil || !strings.Contains(query, fmt.Sprintf("message.sender='%s'", q.sender)) {
+			return &ctypes.ResultTxSearch{}, nil
+		}
+
+		var from, to int64
+		if _, err := fmt.Sscanf(query, "tx.height>%d AND tx.height<=%d", &from, &to); err != nil {
+			return nil, err
+		}
+
+		results := &ctypes.ResultTxSearch{}
+		for _, result := range q.outgoing.Txs {
+			if result.Height > from && result.Height <= to {
+				results.Txs = append(results.Txs, result)
+			}
+		}
+		return results, nil
+	}
+
+	if q.txQuerier == nil {
+		return &ctypes.ResultTxSearch{}, nil
+	}
+
+	return q.txQuerier.Query(ctx, query)
+}
+
+type outgoingTxQuerier struct {
+	txQuerier txQuerier
+	sender    string
+	outgoing  *ctypes.ResultTxSearch
+	incoming  []*ctypes.ResultTx
+	queries   []string
+}
@@ -0,0 +1,123 @@
+package relayminter
+
+import (
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func newMinterWallets(privKeys []*secp256k1.PrivKey) []*minterWallet {
+	minters := []*minterWallet{}
+	for _, privKey := range privKeys {
+		minters = append(minters, &minterWallet{
+			key:     privKey,
+			address: sdk.AccAddress(privKey.PubKey().Address()),
+		})
+	}
+
+	return minters
+}
+
+// All wallets of the pool. The first one is the payment wallet, followed by the minter sub-accounts.
+// The tx senders are replaced on every connect, so the wallets are copied under the lock.
+func (rm *relayMinter) wallets() []*minterWallet {
+	rm.sendersMu.RLock()
+	defer rm.sendersMu.RUnlock()
+
+	wallets := []*minterWallet{{key: rm.walletKey, address: rm.walletAddress, txSender: rm.txSender}}
+	for _, minter := range rm.minters {
+		wallet := *minter
+		wallets = append(wallets, &wallet)
+	}
+
+	return wallets
+}
+
+// Picking the wallet that signs the next outgoing transaction. The wallets of the pool are used in turns.
+// Every wallet has its own tx sender, so transactions of different wallets are built and broadcasted in parallel.
+func (rm *relayMinter) nextWallet() *minterWallet {
+	wallets := rm.wallets()
+
+	rm.walletMu.Lock()
+	defer rm.walletMu.Unlock()
+
+	wallet := wallets[rm.nextWalletIdx%len(wallets)]
+	rm.nextWalletIdx++
+
+	return wallet
+}
+
+// Checking whether the address is a wallet of the pool or a retired wallet, so transactions signed by any of them are recognised by the idempotency checks.
+func (rm *relayMinter) isOwnWallet(address string) bool {
+	for _, knownAddress := range rm.knownWalletAddresses() {
+		if knownAddress == address {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The addresses of all wallets the service has ever signed with. These are the wallets of the pool followed by the retired wallets,
+// both the ones whose keys are still held and the ones known by address only.
+func (rm *relayMinter) knownWalletAddresses() []string {
+	addresses := append(rm.walletAddresses(), rm.retiredAddresses()...)
+	return append(addresses, rm.config.RetiredWalletAddresses...)
+}
+
+// The addresses payments are received by. These are the payment wallet and the retired wallets whose keys are still held,
+// because only they can refund the payments they received.
+func (rm *relayMinter) receivingAddresses() []string {
+	return append([]string{rm.walletAddress.String()}, rm.retiredAddresses()...)
+}
+
+func (rm *relayMinter) isReceivingWallet(address string) bool {
+	for _, receivingAddress := range rm.receivingAddresses() {
+		if receivingAddress == address {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (rm *relayMinter) retiredAddresses() []string {
+	addresses := []string{}
+	for _, wallet := range rm.retired {
+		addresses = append(addresses, wallet.address.String())
+	}
+
+	return addresses
+}
+
+// Picking the wallet that refunds a payment received by the given address.
+// A payment received by a retired wallet is refunded by it, because the funds of the payment are held by it.
+func (rm *relayMinter) refundWallet(receivedBy string) *minterWallet {
+	for _, wallet := range rm.retired {
+		if wallet.address.String() == receivedBy {
+			return wallet
+		}
+	}
+
+	return rm.nextWallet()
+}
+
+// Checking whether a payment is received by a retired wallet and has to be refunded instead of minted.
+func (rm *relayMinter) refundsRetiredPayment(sendInfo receivedBankSend) bool {
+	return sendInfo.ToAddress != rm.walletAddress.String() && !rm.config.MintsRetiredPayments()
+}
+
+func (rm *relayMinter) walletAddresses() []string {
+	addresses := []string{}
+	for _, wallet := range rm.wallets() {
+		addresses = append(addresses, wallet.address.String())
+	}
+
+	return addresses
+}
+
+// A wallet the relayer signs mints and refunds with. It must be whitelisted as a minter and hold funds for the fees.
+type minterWallet struct {
+	key      walletKey
+	address  sdk.AccAddress
+	txSender txSender
+}
@@ -0,0 +1,171 @@
+package relayminter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/email"
+	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/key"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/tx"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldSpreadRefundsAcrossWalletPool(t *testing.T) {
+	setCudosConfig()
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	privKey, err := key.PrivKeyFromMnemonic(walletMnemonic)
+	require.NoError(t, err)
+
+	minterPrivKey, err := key.PrivKeyFromMnemonicWithParams(walletMnemonic, key.HDParams{CoinType: sdk.CoinType, AddressIndex: 1})
+	require.NoError(t, err)
+
+	relayMinter := NewRelayMinter(newMockLogger(), &encodingConfig, config.Config{PaymentDenom: "acudos"}, newMockState(),
+		newTokenisedInfraClient(nil, nil, nil), privKey, []*secp256k1.PrivKey{minterPrivKey}, nil, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
+	minterAddress := sdk.AccAddress(minterPrivKey.PubKey().Address())
+
+	buyer, err := sdk.AccAddressFromBech32(refundReceiver)
+	require.NoError(t, err)
+
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
+	relayMinter.txQuerier = newMockTxQuerier(buildTestResultTxSearch(t, [][]sdk.Msg{
+		{
+			banktypes.NewMsgSend(buyer, relayMinter.walletAddress, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000)))),
+			banktypes.NewMsgSend(buyer, relayMinter.walletAddress, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000)))),
+		},
+	}, []string{
+		"{\"uuid\":\"notfoundnftuid\"}",
+	}, &encodingConfig, batchTxHash), nil, nil, false)
+	walletTxSender := newMockTxSender(false)
+	relayMinter.txSender = walletTxSender
+	minterTxSender := newMockTxSender(false)
+	relayMinter.minters[0].txSender = minterTxSender
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+
+	refundAmount := sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))
+	require.Equal(t, []string{batchTxHash}, walletTxSender.outputMemos)
+	require.Equal(t, []sdk.Msg{banktypes.NewMsgSend(relayMinter.walletAddress, buyer, refundAmount)}, walletTxSender.outputMsgs)
+	require.Equal(t, []string{batchTxHash + "#1"}, minterTxSender.outputMemos)
+	require.Equal(t, []sdk.Msg{banktypes.NewMsgSend(minterAddress, buyer, refundAmount)}, minterTxSender.outputMsgs)
+}
+
+func TestShouldReadWalletsWhileTxSendersAreReplaced(t *testing.T) {
+	setCudosConfig()
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	privKey, err := key.PrivKeyFromMnemonic(walletMnemonic)
+	require.NoError(t, err)
+
+	minterPrivKey, err := key.PrivKeyFromMnemonicWithParams(walletMnemonic, key.HDParams{CoinType: sdk.CoinType, AddressIndex: 1})
+	require.NoError(t, err)
+
+	relayMinter := NewRelayMinter(newMockLogger(), &encodingConfig, config.Config{PaymentDenom: "acudos"}, newMockState(),
+		nil, privKey, []*secp256k1.PrivKey{minterPrivKey}, nil, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			relayMinter.wrapTxSenders(func(sender txSender, address string) txSender {
+				return newMockTxSender(false)
+			})
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		require.Len(t, relayMinter.wallets(), 2)
+	}
+	<-done
+}
+
+func TestShouldRecognizeMintsOfAnyWalletInPool(t *testing.T) {
+	setCudosConfig()
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	privKey, err := key.PrivKeyFromMnemonic(walletMnemonic)
+	require.NoError(t, err)
+
+	minterPrivKey, err := key.PrivKeyFromMnemonicWithParams(walletMnemonic, key.HDParams{CoinType: sdk.CoinType, AddressIndex: 1})
+	require.NoError(t, err)
+
+	relayMinter := NewRelayMinter(newMockLogger(), &encodingConfig, config.Config{PaymentDenom: "acudos"}, newMockState(),
+		nil, privKey, []*secp256k1.PrivKey{minterPrivKey}, nil, nil, nil, tx.NewTxCoder(&encodingConfig), email.NewSendgridEmailService(config.Config{}), nil, nil)
+	minterAddress := sdk.AccAddress(minterPrivKey.PubKey().Address())
+
+	require.True(t, relayMinter.isOwnWallet(relayMinter.walletAddress.String()))
+	require.True(t, relayMinter.isOwnWallet(minterAddress.String()))
+	require.False(t, relayMinter.isOwnWallet(refundReceiver))
+
+	relayMinter.blockQuerier = newMockBlockQuerier(time.Now())
+	relayMinter.txQuerier = newMockTxQuerier(nil, buildTestResultTxSearch(t, [][]sdk.Msg{
+		{
+			marketplacetypes.NewMsgMintNft(minterAddress.String(), "testdenom", refundReceiver, "name", "uri", "data", "nftuid#1", sdk.NewCoin("acudos", sdk.NewInt(1))),
+		},
+	}, []string{
+		batchTxHash,
+	}, &encodingConfig, ""), nil, false)
+
+	isMinted, err := relayMinter.isMintedNft(context.Background(), "nftuid#1", 0)
+	require.NoError(t, err)
+	require.True(t, isMinted)
+}
+
+func TestShouldRefundPaymentsToRetiredWalletFromIt(t *testing.T) {
+	relayMinter, buyer, retiredAddress, walletTxSender, retiredTxSender := newRetiredWalletTestRelayMinter(t, config.RetiredPaymentPolicyRefund)
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Empty(t, walletTxSender.outputMsgs)
+	require.Equal(t, []string{batchTxHash}, retiredTxSender.outputMemos)
+	require.Equal(t, []sdk.Msg{
+		banktypes.NewMsgSend(retiredAddress, buyer, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000).Sub(sdk.NewIntFromUint64(5005000000000000))))),
+	}, retiredTxSender.outputMsgs)
+}
+
+func TestShouldMintPaymentsToRetiredWalletByWalletPool(t *testing.T) {
+	relayMinter, _, _, walletTxSender, retiredTxSender := newRetiredWalletTestRelayMinter(t, config.RetiredPaymentPolicyMint)
+
+	require.NoError(t, relayMinter.relay(context.Background()))
+	require.Empty(t, retiredTxSender.outputMsgs)
+	require.Equal(t, []string{batchTxHash}, walletTxSender.outputMemos)
+	require.Len(t, walletTxSender.outputMsgs, 1)
+	require.Equal(t, relayMinter.walletAddress.String(), walletTxSender.outputMsgs[0].(*marketplacetypes.MsgMintNft).Creator)
+}
+
+func TestShouldRecognizeTransactionsOfRetiredWallets(t *testing.T) {
+	relayMinter, _, retiredAddress, _, _ := newRetiredWalletTestRelayMinter(t, config.RetiredPaymentPolicyRefund)
+
+	require.True(t, relayMinter.isOwnWallet(retiredAddress.String()))
+	require.True(t, relayMinter.isOwnWallet(legacyAddress))
+	require.True(t, relayMinter.isReceivingWallet(retiredAddress.String()))
+	require.False(t, relayMinter.isReceivingWallet(legacyAddress))
+	require.Equal(t, []string{relayMinter.walletAddress.String(), retiredAddress.String()}, relayMinter.receivingAddresses())
+}
+
+// Building a relay minter with a retired wallet whose key is held and a legacy address whose key is not,
+// and a single incoming transaction paying the retired wallet for an available NFT.
+func newRetiredWalletTestRelayMinter(t *testing.T, policy string) (*relayMinter, sdk.AccAddress, sdk.AccAddress, *mockTxSender, *mockTxSender) {
+	setCudosConfig()
+	retiredPrivKey, err := key.PrivKeyFromMnemonicWithParams(walletMnemonic, key.HDParams{CoinType: sdk.CoinType, AddressIndex: 2})
+	require.NoError(t, err)
+	retiredAddress := sdk.AccAddress(retiredPrivKey.PubKey().Address())
+
+	built := newTestRelayMinter(t, testRelayMinterOptions{
+		cfg:             config.Config{RetiredWalletAddresses: []string{legacyAddress}, RetiredPaymentPolicy: policy},
+		paymentTxs:      []testPaymentTx{{memo: "{\"uuid\":\"nftuid#1\"}", amounts: []uint64{9000000000000000000}, to: retiredAddress}},
+		retiredPrivKeys: []*secp256k1.PrivKey{retiredPrivKey},
+	})
+	retiredTxSender := newMockTxSender(false)
+	built.relayMinter.retired[0].txSender = retiredTxSender
+
+	return built.relayMinter, built.buyer, retiredAddress, built.txSender, retiredTxSender
+}
+
+const legacyAddress = "cudos1d3jkwctr0ykk66tww3jhytthv9kxcet5gmuw0w"
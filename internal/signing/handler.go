@@ -0,0 +1,75 @@
+package signing
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/rs/zerolog/log"
+)
+
+// Handling the sign requests of the relayer.
+// The request must carry the auth token as bearer token and its sign bytes must pass the policy, otherwise nothing is signed.
+func NewSignHandler(key keySigner, policy policy, authToken string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAuthorized(r, authToken) {
+			log.Warn().Msg("unauthorized sign request")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+		if err != nil {
+			log.Error().Err(fmt.Errorf("error while reading body: %s", err)).Send()
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		signRequest := model.SignRequest{}
+		if err := json.Unmarshal(body, &signRequest); err != nil {
+			log.Error().Err(fmt.Errorf("error while unmarshalling body: %s", err)).Send()
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := policy.Check(signRequest.SignBytes); err != nil {
+			log.Warn().Msgf("refusing to sign: %s", err)
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		signature, err := key.Sign(signRequest.SignBytes)
+		if err != nil {
+			log.Error().Err(fmt.Errorf("error while signing: %s", err)).Send()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(model.SignResponse{Signature: signature}); err != nil {
+			log.Error().Err(err).Send()
+		}
+	}
+}
+
+func isAuthorized(r *http.Request, authToken string) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return authToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(authToken)) == 1
+}
+
+type keySigner interface {
+	Sign(msg []byte) ([]byte, error)
+}
+
+type policy interface {
+	Check(signBytes []byte) error
+}
+
+const maxRequestBytes = 1 << 20
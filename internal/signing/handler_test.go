@@ -0,0 +1,56 @@
+package signing
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignHandlerShouldSignAllowedTx(t *testing.T) {
+	privKey := secp256k1.GenPrivKey()
+	signBytes := buildSignBytes(t, testChainID, newMintMsg())
+
+	rec := serveSignRequest(t, NewSignHandler(privKey, newTestPolicy(), "token"), "Bearer token", signBytes)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	signResponse := model.SignResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &signResponse))
+	require.True(t, privKey.PubKey().VerifySignature(signBytes, signResponse.Signature))
+}
+
+func TestSignHandlerShouldRefuseUnauthorizedRequest(t *testing.T) {
+	handler := NewSignHandler(secp256k1.GenPrivKey(), newTestPolicy(), "token")
+	signBytes := buildSignBytes(t, testChainID, newMintMsg())
+
+	require.Equal(t, http.StatusUnauthorized, serveSignRequest(t, handler, "", signBytes).Code)
+	require.Equal(t, http.StatusUnauthorized, serveSignRequest(t, handler, "Bearer other", signBytes).Code)
+	require.Equal(t, http.StatusUnauthorized, serveSignRequest(t, NewSignHandler(secp256k1.GenPrivKey(), newTestPolicy(), ""), "Bearer ", signBytes).Code)
+}
+
+func TestSignHandlerShouldRefuseTxViolatingPolicy(t *testing.T) {
+	handler := NewSignHandler(secp256k1.GenPrivKey(), newTestPolicy(), "token")
+
+	rec := serveSignRequest(t, handler, "Bearer token", buildSignBytes(t, testChainID, newRefundMsg("acudos", 1001)))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), "exceeds max refund amount")
+}
+
+func serveSignRequest(t *testing.T, handler func(http.ResponseWriter, *http.Request), authorization string, signBytes []byte) *httptest.ResponseRecorder {
+	body, err := json.Marshal(model.SignRequest{SignBytes: signBytes})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/sign", bytes.NewReader(body))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
@@ -0,0 +1,173 @@
+package signing
+
+import (
+	"fmt"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// The policy of the signing daemon. Only transactions of the configured chain with allowed messages are signed
+// and every bank send, i.e. a refund, must be in the payment denom and must not exceed the max refund amount.
+// Only bank sends to the treasury address, i.e. sweeps, may exceed it. Messages wrapped by authz MsgExec are subject to the same rules.
+// The fee must be in the payment denom and must not exceed the max fee amount nor the gas the max gas. It is paid by the signing wallet
+// or by the configured fee granter, so the fee can not be used to drain the wallet nor another account.
+func NewSigningPolicy(chainID string, allowedMsgTypes []string, maxRefundAmount sdk.Int, paymentDenom string, maxFeeAmount sdk.Int, maxGas uint64,
+	signerAddress, feeGranter, treasuryAddress string) *signingPolicy {
+	allowed := map[string]bool{}
+	for _, msgType := range allowedMsgTypes {
+		allowed[msgType] = true
+	}
+
+	return &signingPolicy{
+		chainID:         chainID,
+		allowedMsgTypes: allowed,
+		maxRefundAmount: maxRefundAmount,
+		paymentDenom:    paymentDenom,
+		maxFeeAmount:    maxFeeAmount,
+		maxGas:          maxGas,
+		signerAddress:   signerAddress,
+		feeGranter:      feeGranter,
+		treasuryAddress: treasuryAddress,
+	}
+}
+
+// Checking the sign bytes against the policy. The sign bytes are expected to be a sign doc of the direct sign mode.
+func (p *signingPolicy) Check(signBytes []byte) error {
+	signDoc := txtypes.SignDoc{}
+	if err := signDoc.Unmarshal(signBytes); err != nil {
+		return fmt.Errorf("decoding sign doc failed: %s", err)
+	}
+
+	if signDoc.ChainId != p.chainID {
+		return fmt.Errorf("sign doc is for chain (%s) instead of (%s)", signDoc.ChainId, p.chainID)
+	}
+
+	body := txtypes.TxBody{}
+	if err := body.Unmarshal(signDoc.BodyBytes); err != nil {
+		return fmt.Errorf("decoding tx body failed: %s", err)
+	}
+
+	if len(body.Messages) == 0 {
+		return fmt.Errorf("tx has no messages")
+	}
+
+	if len(body.ExtensionOptions) > 0 || len(body.NonCriticalExtensionOptions) > 0 {
+		return fmt.Errorf("tx has extension options")
+	}
+
+	authInfo := txtypes.AuthInfo{}
+	if err := authInfo.Unmarshal(signDoc.AuthInfoBytes); err != nil {
+		return fmt.Errorf("decoding auth info failed: %s", err)
+	}
+
+	if err := p.checkFee(authInfo.Fee); err != nil {
+		return err
+	}
+
+	return p.checkMsgs(body.Messages, "")
+}
+
+// Checking the fee of a transaction. The simulations of the relayer have a zero fee, so they pass as well.
+func (p *signingPolicy) checkFee(fee *txtypes.Fee) error {
+	if fee == nil {
+		return fmt.Errorf("tx has no fee")
+	}
+
+	if fee.GasLimit > p.maxGas {
+		return fmt.Errorf("tx gas limit (%d) exceeds max gas (%d)", fee.GasLimit, p.maxGas)
+	}
+
+	for _, coin := range fee.Amount {
+		if coin.Denom != p.paymentDenom {
+			return fmt.Errorf("tx fee has invalid denom (%s)", coin.Denom)
+		}
+
+		if coin.Amount.GT(p.maxFeeAmount) {
+			return fmt.Errorf("tx fee (%s) exceeds max fee amount (%s)", coin.Amount.String(), p.maxFeeAmount.String())
+		}
+	}
+
+	if fee.Payer != "" && fee.Payer != p.signerAddress {
+		return fmt.Errorf("tx fee payer (%s) is not the signing wallet", fee.Payer)
+	}
+
+	if fee.Granter != "" && fee.Granter != p.feeGranter {
+		return fmt.Errorf("tx fee granter (%s) is not allowed", fee.Granter)
+	}
+
+	return nil
+}
+
+// Checking the messages of a transaction. The messages wrapped by authz MsgExec are checked as the plain ones,
+// so an allowed MsgExec can not be used to execute messages that are not allowed.
+func (p *signingPolicy) checkMsgs(msgs []*codectypes.Any, parent string) error {
+	for i, msg := range msgs {
+		index := fmt.Sprintf("%s%d", parent, i)
+		if !p.allowedMsgTypes[msg.TypeUrl] {
+			return fmt.Errorf("message %s of type (%s) is not allowed", index, msg.TypeUrl)
+		}
+
+		switch msg.TypeUrl {
+		case sdk.MsgTypeURL(&authz.MsgExec{}):
+			msgExec := authz.MsgExec{}
+			if err := msgExec.Unmarshal(msg.Value); err != nil {
+				return fmt.Errorf("decoding exec message %s failed: %s", index, err)
+			}
+
+			if len(msgExec.Msgs) == 0 {
+				return fmt.Errorf("exec message %s has no messages", index)
+			}
+
+			if err := p.checkMsgs(msgExec.Msgs, index+"."); err != nil {
+				return err
+			}
+		case sdk.MsgTypeURL(&banktypes.MsgSend{}):
+			if err := p.checkBankSend(msg, index); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *signingPolicy) checkBankSend(msg *codectypes.Any, index string) error {
+	msgSend := banktypes.MsgSend{}
+	if err := msgSend.Unmarshal(msg.Value); err != nil {
+		return fmt.Errorf("decoding bank send message %s failed: %s", index, err)
+	}
+
+	for _, coin := range msgSend.Amount {
+		if coin.Denom != p.paymentDenom {
+			return fmt.Errorf("bank send message %s has invalid denom (%s)", index, coin.Denom)
+		}
+
+		if coin.Amount.GT(p.maxRefundAmount) && !p.isSweep(msgSend) {
+			return fmt.Errorf("bank send message %s amount (%s) exceeds max refund amount (%s)", index, coin.Amount.String(), p.maxRefundAmount.String())
+		}
+	}
+
+	return nil
+}
+
+// A bank send to the treasury is a sweep of the balance above the float, which is not limited by the max refund amount.
+func (p *signingPolicy) isSweep(msgSend banktypes.MsgSend) bool {
+	return p.treasuryAddress != "" && msgSend.ToAddress == p.treasuryAddress
+}
+
+type signingPolicy struct {
+	chainID         string
+	allowedMsgTypes map[string]bool
+	maxRefundAmount sdk.Int
+	paymentDenom    string
+	maxFeeAmount    sdk.Int
+	maxGas          uint64
+	signerAddress   string
+	// The only account allowed to pay the fee besides the signing wallet, none if empty
+	feeGranter      string
+	treasuryAddress string
+}
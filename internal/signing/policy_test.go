@@ -0,0 +1,222 @@
+package signing
+
+import (
+	"testing"
+
+	marketplacetypes "github.com/CudoVentures/cudos-node/x/marketplace/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyShouldAllowMintsAndRefunds(t *testing.T) {
+	signBytes := buildSignBytes(t, testChainID, newMintMsg(), newRefundMsg("acudos", 1000))
+	require.NoError(t, newTestPolicy().Check(signBytes))
+}
+
+func TestPolicyShouldAllowFeeGrantAndSweep(t *testing.T) {
+	fee := newTestFee()
+	fee.Granter = testGranter
+	signBytes := buildSignBytesWithFee(t, testChainID, fee, newSendMsg(testTreasury, "acudos", 5000))
+	require.NoError(t, newTestPolicy().Check(signBytes))
+}
+
+func TestPolicyShouldAllowMintsWrappedInExec(t *testing.T) {
+	signBytes := buildSignBytes(t, testChainID, newExecMsg(newMintMsg(), newMintMsg()))
+	require.NoError(t, newTestPolicy().Check(signBytes))
+}
+
+func TestPolicyShouldRefuse(t *testing.T) {
+	tests := []struct {
+		name      string
+		signBytes func(t *testing.T) []byte
+	}{
+		{
+			name: "InvalidSignDoc",
+			signBytes: func(t *testing.T) []byte {
+				return []byte("invalid sign doc")
+			},
+		},
+		{
+			name: "OtherChain",
+			signBytes: func(t *testing.T) []byte {
+				return buildSignBytes(t, "other-chain", newMintMsg())
+			},
+		},
+		{
+			name: "NoMessages",
+			signBytes: func(t *testing.T) []byte {
+				return buildSignBytes(t, testChainID)
+			},
+		},
+		{
+			name: "NotAllowedMsgType",
+			signBytes: func(t *testing.T) []byte {
+				return buildSignBytes(t, testChainID, newMintMsg(), &banktypes.MsgMultiSend{})
+			},
+		},
+		{
+			name: "RefundAboveMaxAmount",
+			signBytes: func(t *testing.T) []byte {
+				return buildSignBytes(t, testChainID, newRefundMsg("acudos", 1000), newRefundMsg("acudos", 1001))
+			},
+		},
+		{
+			name: "NotAllowedMsgTypeInExec",
+			signBytes: func(t *testing.T) []byte {
+				return buildSignBytes(t, testChainID, newExecMsg(newMintMsg(), &banktypes.MsgMultiSend{}))
+			},
+		},
+		{
+			name: "RefundAboveMaxAmountInExec",
+			signBytes: func(t *testing.T) []byte {
+				return buildSignBytes(t, testChainID, newExecMsg(newRefundMsg("acudos", 1001)))
+			},
+		},
+		{
+			name: "EmptyExec",
+			signBytes: func(t *testing.T) []byte {
+				return buildSignBytes(t, testChainID, newExecMsg())
+			},
+		},
+		{
+			name: "HugeFee",
+			signBytes: func(t *testing.T) []byte {
+				fee := newTestFee()
+				fee.Amount = sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(9000000000000000000)))
+				return buildSignBytesWithFee(t, testChainID, fee, newMintMsg(), newRefundMsg("acudos", 1000))
+			},
+		},
+		{
+			name: "FeeInOtherDenom",
+			signBytes: func(t *testing.T) []byte {
+				fee := newTestFee()
+				fee.Amount = sdk.NewCoins(sdk.NewInt64Coin("other", 1))
+				return buildSignBytesWithFee(t, testChainID, fee, newMintMsg())
+			},
+		},
+		{
+			name: "GasAboveMax",
+			signBytes: func(t *testing.T) []byte {
+				fee := newTestFee()
+				fee.GasLimit = 200001
+				return buildSignBytesWithFee(t, testChainID, fee, newMintMsg())
+			},
+		},
+		{
+			name: "OtherFeePayer",
+			signBytes: func(t *testing.T) []byte {
+				fee := newTestFee()
+				fee.Payer = testGranter
+				return buildSignBytesWithFee(t, testChainID, fee, newMintMsg())
+			},
+		},
+		{
+			name: "OtherFeeGranter",
+			signBytes: func(t *testing.T) []byte {
+				fee := newTestFee()
+				fee.Granter = testTreasury
+				return buildSignBytesWithFee(t, testChainID, fee, newMintMsg())
+			},
+		},
+		{
+			name: "NoAuthInfo",
+			signBytes: func(t *testing.T) []byte {
+				signDoc := txtypes.SignDoc{}
+				require.NoError(t, signDoc.Unmarshal(buildSignBytes(t, testChainID, newMintMsg())))
+				signDoc.AuthInfoBytes = nil
+				signBytes, err := signDoc.Marshal()
+				require.NoError(t, err)
+				return signBytes
+			},
+		},
+		{
+			name: "SendAboveMaxAmountToOtherThanTreasury",
+			signBytes: func(t *testing.T) []byte {
+				return buildSignBytes(t, testChainID, newSendMsg(testGranter, "acudos", 5000))
+			},
+		},
+		{
+			name: "RefundInOtherDenom",
+			signBytes: func(t *testing.T) []byte {
+				return buildSignBytes(t, testChainID, newRefundMsg("other", 1))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Error(t, newTestPolicy().Check(tc.signBytes(t)))
+		})
+	}
+}
+
+func newTestPolicy() *signingPolicy {
+	return NewSigningPolicy(testChainID, []string{sdk.MsgTypeURL(&marketplacetypes.MsgMintNft{}), sdk.MsgTypeURL(&banktypes.MsgSend{}), sdk.MsgTypeURL(&authz.MsgExec{})},
+		sdk.NewInt(1000), "acudos", sdk.NewInt(100000), 200000, testAddress, testGranter, testTreasury)
+}
+
+func newTestFee() txtypes.Fee {
+	return txtypes.Fee{Amount: sdk.NewCoins(sdk.NewInt64Coin("acudos", 100000)), GasLimit: 200000}
+}
+
+func newMintMsg() sdk.Msg {
+	return marketplacetypes.NewMsgMintNft(testAddress, "testdenom", testAddress, "name", "uri", "data", "uid", sdk.NewCoin("acudos", sdk.NewInt(1)))
+}
+
+func newExecMsg(msgs ...sdk.Msg) sdk.Msg {
+	msgExec := authz.MsgExec{Grantee: testAddress}
+	for _, msg := range msgs {
+		anyMsg, err := codectypes.NewAnyWithValue(msg)
+		if err != nil {
+			panic(err)
+		}
+		msgExec.Msgs = append(msgExec.Msgs, anyMsg)
+	}
+
+	return &msgExec
+}
+
+func newRefundMsg(denom string, amount int64) sdk.Msg {
+	return newSendMsg(testAddress, denom, amount)
+}
+
+func newSendMsg(toAddress, denom string, amount int64) sdk.Msg {
+	return &banktypes.MsgSend{FromAddress: testAddress, ToAddress: toAddress, Amount: sdk.NewCoins(sdk.NewInt64Coin(denom, amount))}
+}
+
+func buildSignBytes(t *testing.T, chainID string, msgs ...sdk.Msg) []byte {
+	return buildSignBytesWithFee(t, chainID, newTestFee(), msgs...)
+}
+
+func buildSignBytesWithFee(t *testing.T, chainID string, fee txtypes.Fee, msgs ...sdk.Msg) []byte {
+	body := txtypes.TxBody{Memo: "memo"}
+	for _, msg := range msgs {
+		anyMsg, err := codectypes.NewAnyWithValue(msg)
+		require.NoError(t, err)
+		body.Messages = append(body.Messages, anyMsg)
+	}
+
+	bodyBytes, err := body.Marshal()
+	require.NoError(t, err)
+
+	authInfo := txtypes.AuthInfo{Fee: &fee}
+	authInfoBytes, err := authInfo.Marshal()
+	require.NoError(t, err)
+
+	signDoc := txtypes.SignDoc{BodyBytes: bodyBytes, AuthInfoBytes: authInfoBytes, ChainId: chainID, AccountNumber: 1}
+	signBytes, err := signDoc.Marshal()
+	require.NoError(t, err)
+
+	return signBytes
+}
+
+const (
+	testChainID  = "cudos-local-network"
+	testAddress  = "cudos1a326k254fukx9jlp0h3fwcr2ymjgludzum67dv"
+	testGranter  = "cudos1granter"
+	testTreasury = "cudos1treasury"
+)
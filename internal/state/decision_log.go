@@ -0,0 +1,183 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/marshal"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+)
+
+// The log of the decisions of the relayer. Every decision is appended as a single JSON line, so the file is never rewritten.
+// The records are chained by hash, the last record of the file is read on the first append, so a restarted relayer continues the chain.
+// The payments are processed by several workers, so the decisions are appended one at a time. Only one process may append to the file.
+func NewFileDecisionLog(filePath string) *fileDecisionLog {
+	return &fileDecisionLog{
+		filePath:  filePath,
+		marshaler: marshal.NewJsonMarshaler(),
+	}
+}
+
+func (l *fileDecisionLog) RecordDecision(decision model.Decision) error {
+	decisionJSON, err := l.marshaler.Marshal(decision)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.head == nil {
+		head, err := readLastDecisionRecord(l.filePath)
+		if err != nil {
+			return fmt.Errorf("reading last record of decision log (%s) failed: %s", l.filePath, err)
+		}
+		l.head = &head
+	}
+
+	record := model.DecisionRecord{Sequence: l.head.Sequence + 1, PrevHash: l.head.Hash, Decision: decisionJSON}
+	record.Hash = record.ComputeHash()
+
+	line, err := l.marshaler.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	l.head = &record
+	return nil
+}
+
+// Reading the last record of the log, an empty one if there is no log yet. The chain can not be continued after a malformed last record.
+func readLastDecisionRecord(filePath string) (model.DecisionRecord, error) {
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return model.DecisionRecord{}, nil
+	}
+	if err != nil {
+		return model.DecisionRecord{}, err
+	}
+	defer file.Close()
+
+	var lastLine []byte
+	scanner := newDecisionLogScanner(file)
+	for scanner.Scan() {
+		lastLine = append(lastLine[:0], scanner.Bytes()...)
+	}
+	if err := scanner.Err(); err != nil {
+		return model.DecisionRecord{}, err
+	}
+
+	if lastLine == nil {
+		return model.DecisionRecord{}, nil
+	}
+
+	var record model.DecisionRecord
+	if err := json.Unmarshal(lastLine, &record); err != nil || record.Hash == "" {
+		return model.DecisionRecord{}, fmt.Errorf("malformed record (%s)", string(lastLine))
+	}
+
+	return record, nil
+}
+
+// Verifying the chain of the decision log. Every record must follow the previous one by sequence and hash and its hash must match its content.
+// The verification goes on after a problem, with the chain continued from the record, so every problem is reported.
+// A malformed line is reported only, the record after it is not checked against the one before.
+// A log whose last records were removed is still a valid chain, so with a head hash kept from an earlier verification it must be found in the log.
+func VerifyDecisionLog(filePath, expectedHead string) (model.DecisionLogReport, error) {
+	report := model.DecisionLogReport{File: filePath, Problems: []model.DecisionLogProblem{}}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return report, err
+	}
+	defer file.Close()
+
+	var prev model.DecisionRecord
+	// The record after a malformed line can not be linked to the previous record
+	linked := true
+	foundHead := false
+	scanner := newDecisionLogScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		report.Records++
+
+		var record model.DecisionRecord
+		var decision model.Decision
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			report.Problems = append(report.Problems, model.DecisionLogProblem{Line: lineNumber, Kind: model.DecisionLogMalformedRecord, Detail: err.Error()})
+			linked = false
+			continue
+		}
+		if err := json.Unmarshal(record.Decision, &decision); err != nil {
+			report.Problems = append(report.Problems, model.DecisionLogProblem{Line: lineNumber, Sequence: record.Sequence, Kind: model.DecisionLogMalformedRecord, Detail: err.Error()})
+		}
+
+		if linked && record.Sequence != prev.Sequence+1 {
+			report.Problems = append(report.Problems, model.DecisionLogProblem{Line: lineNumber, Sequence: record.Sequence, Kind: model.DecisionLogSequenceGap,
+				Detail: fmt.Sprintf("expected sequence %d", prev.Sequence+1)})
+		}
+
+		if linked && record.PrevHash != prev.Hash {
+			report.Problems = append(report.Problems, model.DecisionLogProblem{Line: lineNumber, Sequence: record.Sequence, Kind: model.DecisionLogBrokenChain,
+				Detail: fmt.Sprintf("expected previous hash (%s) but got (%s)", prev.Hash, record.PrevHash)})
+		}
+
+		if hash := record.ComputeHash(); hash != record.Hash {
+			report.Problems = append(report.Problems, model.DecisionLogProblem{Line: lineNumber, Sequence: record.Sequence, Kind: model.DecisionLogHashMismatch,
+				Detail: fmt.Sprintf("expected hash (%s) but got (%s)", hash, record.Hash)})
+		}
+
+		if expectedHead != "" && record.Hash == expectedHead {
+			foundHead = true
+		}
+
+		prev = record
+		linked = true
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+
+	if expectedHead != "" && !foundHead {
+		report.Problems = append(report.Problems, model.DecisionLogProblem{Kind: model.DecisionLogMissingHead, Detail: fmt.Sprintf("head (%s) not found", expectedHead)})
+	}
+
+	report.HeadHash = prev.Hash
+	report.Valid = len(report.Problems) == 0
+	return report, nil
+}
+
+// A decision holds the NFT data of every item of a cart, so the lines are allowed to be longer than the default of the scanner.
+func newDecisionLogScanner(reader io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxDecisionLogLineSize)
+	return scanner
+}
+
+const maxDecisionLogLineSize = 16 * 1024 * 1024
+
+type fileDecisionLog struct {
+	mu        sync.Mutex
+	filePath  string
+	marshaler marshaler
+	// The last record of the log, read on the first append
+	head *model.DecisionRecord
+}
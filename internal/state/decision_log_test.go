@@ -0,0 +1,187 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldAppendChainedDecisions(t *testing.T) {
+	filePath := writeTestDecisionLog(t, 2)
+
+	lines := readTestDecisionLog(t, filePath)
+	require.Len(t, lines, 2)
+
+	var first, second model.DecisionRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	require.Equal(t, uint64(1), first.Sequence)
+	require.Empty(t, first.PrevHash)
+	require.Equal(t, first.ComputeHash(), first.Hash)
+	require.Equal(t, uint64(2), second.Sequence)
+	require.Equal(t, first.Hash, second.PrevHash)
+	require.Equal(t, second.ComputeHash(), second.Hash)
+
+	var decision model.Decision
+	require.NoError(t, json.Unmarshal(second.Decision, &decision))
+	require.Equal(t, "B", decision.Ref)
+	require.Equal(t, model.DecisionRefund, decision.Outcome)
+	require.Equal(t, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewInt(9))), decision.Refunded)
+}
+
+func TestShouldContinueChainOfExistingLog(t *testing.T) {
+	filePath := writeTestDecisionLog(t, 2)
+
+	require.NoError(t, NewFileDecisionLog(filePath).RecordDecision(model.Decision{Ref: "C", Outcome: model.DecisionSkip, Reason: model.ReasonAlreadyMinted}))
+
+	report, err := VerifyDecisionLog(filePath, "")
+	require.NoError(t, err)
+	require.True(t, report.Valid)
+	require.Equal(t, 3, report.Records)
+	require.Empty(t, report.Problems)
+}
+
+func TestShouldNotAppendAfterMalformedLastRecord(t *testing.T) {
+	filePath := writeTestDecisionLog(t, 1)
+	appendTestDecisionLog(t, filePath, "{\"sequence\":2")
+
+	err := NewFileDecisionLog(filePath).RecordDecision(model.Decision{Ref: "C"})
+	require.Error(t, err)
+	require.Len(t, readTestDecisionLog(t, filePath), 2)
+}
+
+func TestShouldDetectChangedDecision(t *testing.T) {
+	filePath := writeTestDecisionLog(t, 3)
+	lines := readTestDecisionLog(t, filePath)
+	lines[1] = strings.Replace(lines[1], "\"outcome\":\"refund\"", "\"outcome\":\"mint\"", 1)
+	rewriteTestDecisionLog(t, filePath, lines)
+
+	report, err := VerifyDecisionLog(filePath, "")
+	require.NoError(t, err)
+	require.False(t, report.Valid)
+	require.Len(t, report.Problems, 1)
+	require.Equal(t, model.DecisionLogHashMismatch, report.Problems[0].Kind)
+	require.Equal(t, 2, report.Problems[0].Line)
+}
+
+func TestShouldDetectRemovedRecord(t *testing.T) {
+	filePath := writeTestDecisionLog(t, 3)
+	lines := readTestDecisionLog(t, filePath)
+	rewriteTestDecisionLog(t, filePath, []string{lines[0], lines[2]})
+
+	report, err := VerifyDecisionLog(filePath, "")
+	require.NoError(t, err)
+	require.False(t, report.Valid)
+	require.Equal(t, []string{model.DecisionLogSequenceGap, model.DecisionLogBrokenChain}, problemKinds(report))
+	require.Equal(t, uint64(3), report.Problems[0].Sequence)
+}
+
+func TestShouldDetectRehashedRecord(t *testing.T) {
+	filePath := writeTestDecisionLog(t, 3)
+	lines := readTestDecisionLog(t, filePath)
+
+	// Changing a decision together with its hash breaks the link of the next record
+	var record model.DecisionRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	record.Decision = json.RawMessage(strings.Replace(string(record.Decision), "\"outcome\":\"refund\"", "\"outcome\":\"mint\"", 1))
+	record.Hash = record.ComputeHash()
+	line, err := json.Marshal(record)
+	require.NoError(t, err)
+	lines[1] = string(line)
+	rewriteTestDecisionLog(t, filePath, lines)
+
+	report, err := VerifyDecisionLog(filePath, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{model.DecisionLogBrokenChain}, problemKinds(report))
+	require.Equal(t, 3, report.Problems[0].Line)
+}
+
+func TestShouldDetectTruncatedLog(t *testing.T) {
+	filePath := writeTestDecisionLog(t, 3)
+	report, err := VerifyDecisionLog(filePath, "")
+	require.NoError(t, err)
+	head := report.HeadHash
+
+	lines := readTestDecisionLog(t, filePath)
+	rewriteTestDecisionLog(t, filePath, lines[:2])
+
+	report, err = VerifyDecisionLog(filePath, "")
+	require.NoError(t, err)
+	require.True(t, report.Valid)
+
+	report, err = VerifyDecisionLog(filePath, head)
+	require.NoError(t, err)
+	require.Equal(t, []string{model.DecisionLogMissingHead}, problemKinds(report))
+}
+
+func TestShouldReportMalformedRecord(t *testing.T) {
+	filePath := writeTestDecisionLog(t, 2)
+	lines := readTestDecisionLog(t, filePath)
+	rewriteTestDecisionLog(t, filePath, []string{lines[0], "not a record", lines[1]})
+
+	report, err := VerifyDecisionLog(filePath, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{model.DecisionLogMalformedRecord}, problemKinds(report))
+	require.Equal(t, 2, report.Problems[0].Line)
+	require.Equal(t, 3, report.Records)
+}
+
+func TestShouldFailToVerifyMissingLog(t *testing.T) {
+	_, err := VerifyDecisionLog(filepath.Join(t.TempDir(), "decisions.jsonl"), "")
+	require.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestShouldFailToRecordDecisionIfMarshalingFails(t *testing.T) {
+	decisionLog := NewFileDecisionLog(filepath.Join(t.TempDir(), "decisions.jsonl"))
+	decisionLog.marshaler = &failingMarshaler{}
+	require.Equal(t, errors.New("failed to marshal"), decisionLog.RecordDecision(model.Decision{}))
+}
+
+// Writing a log of the given number of decisions, alternating between mints and refunds.
+func writeTestDecisionLog(t *testing.T, count int) string {
+	filePath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	decisionLog := NewFileDecisionLog(filePath)
+
+	amount := sdk.NewCoin("acudos", sdk.NewInt(10))
+	for i := 0; i < count; i++ {
+		decision := model.Decision{Ref: string(rune('A' + i)), Amount: amount, Uids: []string{"uid"}, Outcome: model.DecisionMint, Reason: model.ReasonPaid}
+		if i%2 == 1 {
+			decision.Outcome = model.DecisionRefund
+			decision.Reason = model.ReasonNftMinted
+			decision.Refunded = sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewInt(9)))
+		}
+		require.NoError(t, decisionLog.RecordDecision(decision))
+	}
+
+	return filePath
+}
+
+func readTestDecisionLog(t *testing.T, filePath string) []string {
+	fileData, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(fileData), "\n"), "\n")
+}
+
+func rewriteTestDecisionLog(t *testing.T, filePath string, lines []string) {
+	require.NoError(t, os.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+}
+
+func appendTestDecisionLog(t *testing.T, filePath, line string) {
+	lines := readTestDecisionLog(t, filePath)
+	rewriteTestDecisionLog(t, filePath, append(lines, line))
+}
+
+func problemKinds(report model.DecisionLogReport) []string {
+	kinds := []string{}
+	for _, problem := range report.Problems {
+		kinds = append(kinds, problem.Kind)
+	}
+	return kinds
+}
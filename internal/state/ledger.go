@@ -0,0 +1,43 @@
+package state
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/marshal"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+)
+
+// The ledger of the payments rebuilt from the chain history. It is a reconstruction, so it is written as a whole,
+// one JSON line per payment, into a temporary file that replaces the former ledger only once it is complete.
+func NewFileLedger(filePath string) *fileLedger {
+	return &fileLedger{
+		filePath:  filePath,
+		marshaler: marshal.NewJsonMarshaler(),
+	}
+}
+
+func (l *fileLedger) WriteLedger(entries []model.LedgerEntry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := l.marshaler.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	tmpPath := l.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, l.filePath)
+}
+
+type fileLedger struct {
+	filePath  string
+	marshaler marshaler
+}
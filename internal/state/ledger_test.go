@@ -0,0 +1,41 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldReplaceLedger(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "ledger.jsonl")
+	require.NoError(t, os.WriteFile(filePath, []byte("former ledger\n"), 0644))
+
+	ledger := NewFileLedger(filePath)
+	require.NoError(t, ledger.WriteLedger([]model.LedgerEntry{
+		{Ref: "A", Height: 1, Amount: sdk.NewCoin("acudos", sdk.NewInt(10)), Status: model.LedgerStatusMinted, Refunded: sdk.ZeroInt()},
+		{Ref: "B", Height: 2, Amount: sdk.NewCoin("acudos", sdk.NewInt(20)), Status: model.LedgerStatusRefunded, Refunded: sdk.NewInt(19)},
+	}))
+
+	fileData, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, "{\"ref\":\"A\",\"height\":1,\"sender\":\"\",\"receiver\":\"\",\"amount\":{\"denom\":\"acudos\",\"amount\":\"10\"},\"uids\":null,\"status\":\"minted\",\"mintTxs\":null,\"mintedUids\":null,\"refundTxs\":null,\"refunded\":\"0\"}\n"+
+		"{\"ref\":\"B\",\"height\":2,\"sender\":\"\",\"receiver\":\"\",\"amount\":{\"denom\":\"acudos\",\"amount\":\"20\"},\"uids\":null,\"status\":\"refunded\",\"mintTxs\":null,\"mintedUids\":null,\"refundTxs\":null,\"refunded\":\"19\"}\n", string(fileData))
+}
+
+func TestShouldKeepLedgerIfMarshalingFails(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "ledger.jsonl")
+	require.NoError(t, os.WriteFile(filePath, []byte("former ledger\n"), 0644))
+
+	ledger := NewFileLedger(filePath)
+	ledger.marshaler = &failingMarshaler{}
+	require.Equal(t, errors.New("failed to marshal"), ledger.WriteLedger([]model.LedgerEntry{{Ref: "A"}}))
+
+	fileData, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, "former ledger\n", string(fileData))
+}
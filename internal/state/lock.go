@@ -0,0 +1,54 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Locking the file exclusively, so only one process at a time sends transactions from the working directory of the state.
+// The lock is held by the open file and released by the kernel when the process exits, so a crashed process leaves no stale lock.
+// The pid of the holder is written into the file, so the error tells which process holds the lock.
+func LockFile(filePath string) (*fileLock, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		holder, _ := os.ReadFile(filePath)
+		file.Close()
+
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("lock file (%s) is held by process (%s), stop it first", filePath, strings.TrimSpace(string(holder)))
+		}
+
+		return nil, fmt.Errorf("locking file (%s) failed: %s", filePath, err)
+	}
+
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if _, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &fileLock{file: file}, nil
+}
+
+// Releasing the lock, the file is kept so it is never removed while another process opens it.
+func (l *fileLock) Unlock() error {
+	return l.file.Close()
+}
+
+var DefaultLockFilePath = "relayer.lock"
+
+type fileLock struct {
+	file *os.File
+}
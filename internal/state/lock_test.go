@@ -0,0 +1,31 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldFailToLockFileHeldByAnotherLock(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "relayer.lock")
+
+	lock, err := LockFile(filePath)
+	require.NoError(t, err)
+
+	_, err = LockFile(filePath)
+	require.Equal(t, fmt.Errorf("lock file (%s) is held by process (%d), stop it first", filePath, os.Getpid()), err)
+
+	require.NoError(t, lock.Unlock())
+
+	lock, err = LockFile(filePath)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+}
+
+func TestShouldFailToLockFileInMissingDirectory(t *testing.T) {
+	_, err := LockFile(filepath.Join(t.TempDir(), "missing", "relayer.lock"))
+	require.Error(t, err)
+}
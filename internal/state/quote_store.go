@@ -0,0 +1,161 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/marshal"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+)
+
+// The store of the issued quotes. Every quote is appended as a single JSON line and the quotes are kept in memory,
+// so a quote issued before a restart is still honoured. The store is shared by the quote endpoint and the relayer.
+// A quote is kept for the retention after it expires, so a payment made before the expiry is still processed with it,
+// and dropped afterwards both in memory and in the file, which is rewritten on load and once most of its lines are dropped quotes.
+func NewFileQuoteStore(filePath string, retention time.Duration) (*fileQuoteStore, error) {
+	store := &fileQuoteStore{
+		filePath:  filePath,
+		marshaler: marshal.NewJsonMarshaler(),
+		retention: retention,
+		now:       time.Now,
+		quotes:    map[string]model.Quote{},
+	}
+
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (s *fileQuoteStore) SaveQuote(quote model.Quote) error {
+	line, err := s.marshaler.Marshal(quote)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	s.quotes[quote.Id] = quote
+	s.lines++
+
+	s.prune()
+	if dropped := s.lines - len(s.quotes); dropped >= minCompactedQuoteLines && dropped > len(s.quotes) {
+		// The quote is stored already, a failed rewrite is retried on the next save
+		_ = s.compact()
+	}
+
+	return nil
+}
+
+func (s *fileQuoteStore) GetQuote(id string) (model.Quote, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	quote, ok := s.quotes[id]
+	return quote, ok
+}
+
+// Loading the quotes of the file. A missing file is an empty store. The file is rewritten without the dropped quotes.
+func (s *fileQuoteStore) load() error {
+	file, err := os.Open(s.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		quote := model.Quote{}
+		if err := s.marshaler.Unmarshal(scanner.Bytes(), &quote); err != nil {
+			return fmt.Errorf("decoding quote on line %d of (%s) failed: %s", line, s.filePath, err)
+		}
+
+		s.quotes[quote.Id] = quote
+		s.lines++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	s.prune()
+	if s.lines > len(s.quotes) {
+		return s.compact()
+	}
+
+	return nil
+}
+
+// Dropping the quotes expired for longer than the retention.
+func (s *fileQuoteStore) prune() {
+	expiredBefore := s.now().Add(-s.retention).UnixMilli()
+	for id, quote := range s.quotes {
+		if quote.ExpiresAt < expiredBefore {
+			delete(s.quotes, id)
+		}
+	}
+}
+
+// Rewriting the file with the kept quotes only. The quotes are written into a temporary file that replaces the former one once it is complete.
+func (s *fileQuoteStore) compact() error {
+	var buf bytes.Buffer
+	for _, quote := range s.quotes {
+		line, err := s.marshaler.Marshal(quote)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	tmpPath := s.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, s.filePath); err != nil {
+		return err
+	}
+
+	s.lines = len(s.quotes)
+	return nil
+}
+
+// The file is not rewritten for a few dropped quotes
+const minCompactedQuoteLines = 100
+
+type fileQuoteStore struct {
+	filePath  string
+	marshaler marshaler
+	retention time.Duration
+	now       func() time.Time
+	mu        sync.RWMutex
+	quotes    map[string]model.Quote
+	// The number of lines of the file, including the ones of dropped quotes
+	lines int
+}
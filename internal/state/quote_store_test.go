@@ -0,0 +1,128 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldSaveAndLoadQuotes(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "quotes.jsonl")
+	store, err := NewFileQuoteStore(filePath, time.Hour)
+	require.NoError(t, err)
+
+	_, ok := store.GetQuote("A")
+	require.False(t, ok)
+
+	expiresAt := time.Now().Add(time.Minute).UnixMilli()
+	quoteA := model.Quote{Id: "A", Uid: "uid1", Price: sdk.NewInt(1), Fee: sdk.NewInt(2), ExpiresAt: expiresAt}
+	quoteB := model.Quote{Id: "B", Uid: "uid2", Price: sdk.NewInt(4), Fee: sdk.NewInt(5), ExpiresAt: expiresAt}
+	require.NoError(t, store.SaveQuote(quoteA))
+	require.NoError(t, store.SaveQuote(quoteB))
+
+	haveQuote, ok := store.GetQuote("A")
+	require.True(t, ok)
+	require.Equal(t, quoteA, haveQuote)
+
+	reloaded, err := NewFileQuoteStore(filePath, time.Hour)
+	require.NoError(t, err)
+
+	haveQuote, ok = reloaded.GetQuote("B")
+	require.True(t, ok)
+	require.Equal(t, quoteB, haveQuote)
+}
+
+func TestShouldDropExpiredQuotesOnLoad(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "quotes.jsonl")
+	store, err := NewFileQuoteStore(filePath, time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, store.SaveQuote(model.Quote{Id: "A", ExpiresAt: now.Add(-2 * time.Hour).UnixMilli()}))
+	require.NoError(t, store.SaveQuote(model.Quote{Id: "B", ExpiresAt: now.Add(-30 * time.Minute).UnixMilli()}))
+	require.NoError(t, appendTestQuoteLine(filePath, model.Quote{Id: "C", ExpiresAt: now.Add(-3 * time.Hour).UnixMilli()}))
+
+	reloaded, err := NewFileQuoteStore(filePath, time.Hour)
+	require.NoError(t, err)
+
+	_, ok := reloaded.GetQuote("A")
+	require.False(t, ok)
+	_, ok = reloaded.GetQuote("B")
+	require.True(t, ok)
+	require.Equal(t, 1, countTestQuoteLines(t, filePath))
+}
+
+func TestShouldDropExpiredQuotesOnSave(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "quotes.jsonl")
+	store, err := NewFileQuoteStore(filePath, time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	for i := 0; i < minCompactedQuoteLines; i++ {
+		require.NoError(t, store.SaveQuote(model.Quote{Id: fmt.Sprintf("%d", i), ExpiresAt: now.UnixMilli()}))
+	}
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, store.SaveQuote(model.Quote{Id: "A", ExpiresAt: now.UnixMilli()}))
+	_, ok := store.GetQuote("0")
+	require.False(t, ok)
+	require.Len(t, store.quotes, 1)
+	require.Equal(t, 1, countTestQuoteLines(t, filePath))
+}
+
+func TestShouldFailToLoadQuotesIfFileIsInvalid(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "quotes.jsonl")
+	require.NoError(t, os.WriteFile(filePath, []byte("invalid\n"), 0644))
+
+	_, err := NewFileQuoteStore(filePath, time.Hour)
+	require.Error(t, err)
+}
+
+func TestShouldFailToSaveQuoteIfMarshalingFails(t *testing.T) {
+	store, err := NewFileQuoteStore(filepath.Join(t.TempDir(), "quotes.jsonl"), time.Hour)
+	require.NoError(t, err)
+
+	store.marshaler = &failingMarshaler{}
+	require.Equal(t, errors.New("failed to marshal"), store.SaveQuote(model.Quote{Id: "A"}))
+
+	_, ok := store.GetQuote("A")
+	require.False(t, ok)
+}
+
+func TestShouldFailToSaveQuoteIfDirectoryDoesNotExist(t *testing.T) {
+	store, err := NewFileQuoteStore(filepath.Join(t.TempDir(), "missing", "quotes.jsonl"), time.Hour)
+	require.NoError(t, err)
+	require.Error(t, store.SaveQuote(model.Quote{Id: "A"}))
+}
+
+func appendTestQuoteLine(filePath string, quote model.Quote) error {
+	line, err := json.Marshal(quote)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+func countTestQuoteLines(t *testing.T, filePath string) int {
+	fileData, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	return strings.Count(string(fileData), "\n")
+}
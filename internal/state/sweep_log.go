@@ -0,0 +1,40 @@
+package state
+
+import (
+	"os"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/marshal"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+)
+
+// The log of the treasury sweeps. Every sweep is appended as a single JSON line, so the file is never rewritten.
+func NewFileSweepLog(filePath string) *fileSweepLog {
+	return &fileSweepLog{
+		filePath:  filePath,
+		marshaler: marshal.NewJsonMarshaler(),
+	}
+}
+
+func (l *fileSweepLog) RecordSweep(record model.SweepRecord) error {
+	line, err := l.marshaler.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+type fileSweepLog struct {
+	filePath  string
+	marshaler marshaler
+}
@@ -0,0 +1,37 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldAppendSweepRecords(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "sweeps.jsonl")
+	sweepLog := NewFileSweepLog(filePath)
+
+	sweepTime := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, sweepLog.RecordSweep(model.SweepRecord{Time: sweepTime, TxHash: "A", Amount: "1acudos"}))
+	require.NoError(t, sweepLog.RecordSweep(model.SweepRecord{Time: sweepTime, TxHash: "B", Amount: "2acudos"}))
+
+	fileData, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, "{\"time\":\"2022-10-01T12:00:00Z\",\"tx_hash\":\"A\",\"from\":\"\",\"to\":\"\",\"amount\":\"1acudos\",\"balance\":\"\",\"kept\":\"\"}\n"+
+		"{\"time\":\"2022-10-01T12:00:00Z\",\"tx_hash\":\"B\",\"from\":\"\",\"to\":\"\",\"amount\":\"2acudos\",\"balance\":\"\",\"kept\":\"\"}\n", string(fileData))
+}
+
+func TestShouldFailToRecordSweepIfMarshalingFails(t *testing.T) {
+	sweepLog := NewFileSweepLog(filepath.Join(t.TempDir(), "sweeps.jsonl"))
+	sweepLog.marshaler = &failingMarshaler{}
+	require.Equal(t, errors.New("failed to marshal"), sweepLog.RecordSweep(model.SweepRecord{}))
+}
+
+func TestShouldFailToRecordSweepIfDirectoryDoesNotExist(t *testing.T) {
+	sweepLog := NewFileSweepLog(filepath.Join(t.TempDir(), "missing", "sweeps.jsonl"))
+	require.Error(t, sweepLog.RecordSweep(model.SweepRecord{}))
+}
@@ -0,0 +1,166 @@
+package state
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/marshal"
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+)
+
+// The index of the outgoing transactions of the service. Every indexed range of blocks is appended as a single JSON line
+// and all transactions are kept in memory by the references of their payments and by the uids they mint,
+// so the idempotency checks do not have to search the chain for the blocks it covers.
+func NewFileTxIndex(filePath string) (*fileTxIndex, error) {
+	index := &fileTxIndex{
+		filePath:  filePath,
+		marshaler: marshal.NewJsonMarshaler(),
+	}
+	index.clear()
+
+	if err := index.load(); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// The blocks covered by the index. False if nothing is indexed yet.
+func (i *fileTxIndex) Coverage() (model.TxIndexRange, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.coverage, i.indexed
+}
+
+// Appending the transactions of a range of blocks. The range must start where the covered blocks end, so the index has no gaps.
+func (i *fileTxIndex) AddEntry(entry model.TxIndexEntry) error {
+	line, err := i.marshaler.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.indexed && entry.From != i.coverage.To {
+		return fmt.Errorf("tx index entry (%d-%d) does not continue the index ending at %d", entry.From, entry.To, i.coverage.To)
+	}
+
+	file, err := os.OpenFile(i.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	i.add(entry)
+	return nil
+}
+
+// Dropping all indexed blocks, e.g. when the indexed wallets change.
+func (i *fileTxIndex) Reset() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := os.Remove(i.filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	i.clear()
+	return nil
+}
+
+// The indexed transactions that reference the given payment.
+func (i *fileTxIndex) TxsByRef(ref string) []model.IndexedTx {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.byRef[ref]
+}
+
+// The indexed transactions that mint the given NFT.
+func (i *fileTxIndex) TxsByUid(uid string) []model.IndexedTx {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.byUid[uid]
+}
+
+func (i *fileTxIndex) add(entry model.TxIndexEntry) {
+	if !i.indexed {
+		i.coverage.From = entry.From
+	}
+	i.coverage.To = entry.To
+	i.coverage.Wallets = entry.Wallets
+	i.indexed = true
+
+	for _, tx := range entry.Txs {
+		for _, ref := range tx.Refs {
+			i.byRef[ref] = append(i.byRef[ref], tx)
+		}
+
+		for _, mint := range tx.Mints {
+			i.byUid[mint.Uid] = append(i.byUid[mint.Uid], tx)
+		}
+	}
+}
+
+func (i *fileTxIndex) clear() {
+	i.coverage = model.TxIndexRange{}
+	i.indexed = false
+	i.byRef = map[string][]model.IndexedTx{}
+	i.byUid = map[string][]model.IndexedTx{}
+}
+
+// Loading the entries of the file. A missing file is an empty index.
+func (i *fileTxIndex) load() error {
+	file, err := os.Open(i.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxTxIndexLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		entry := model.TxIndexEntry{}
+		if err := i.marshaler.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("decoding tx index entry on line %d of (%s) failed: %s", line, i.filePath, err)
+		}
+
+		if i.indexed && entry.From != i.coverage.To {
+			return fmt.Errorf("tx index entry on line %d of (%s) does not continue the index ending at %d", line, i.filePath, i.coverage.To)
+		}
+
+		i.add(entry)
+	}
+
+	return scanner.Err()
+}
+
+// A window of blocks may hold many outgoing transactions, so the lines are allowed to be much longer than the default of the scanner.
+const maxTxIndexLineSize = 64 * 1024 * 1024
+
+type fileTxIndex struct {
+	filePath  string
+	marshaler marshaler
+	mu        sync.RWMutex
+	coverage  model.TxIndexRange
+	indexed   bool
+	byRef     map[string][]model.IndexedTx
+	byUid     map[string][]model.IndexedTx
+}
@@ -0,0 +1,103 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldIndexAndLoadTxs(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "tx_index.jsonl")
+	index, err := NewFileTxIndex(filePath)
+	require.NoError(t, err)
+
+	_, ok := index.Coverage()
+	require.False(t, ok)
+
+	mintTx := model.IndexedTx{
+		Hash:   "MINT",
+		Height: 12,
+		Memo:   "PAYMENT1",
+		Refs:   []string{"PAYMENT1"},
+		Fees:   sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewInt(10))),
+		Mints:  []model.IndexedMint{{Uid: "uid1", Recipient: "buyer", Price: sdk.NewCoin("acudos", sdk.NewInt(100))}},
+	}
+	refundTx := model.IndexedTx{
+		Hash:    "REFUND",
+		Height:  25,
+		Memo:    "PAYMENT2",
+		Refs:    []string{"PAYMENT2"},
+		Fees:    sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewInt(10))),
+		Refunds: []model.IndexedRefund{{From: "wallet", To: "buyer", Amount: sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewInt(90)))}},
+	}
+
+	wallets := []string{"wallet"}
+	require.NoError(t, index.AddEntry(model.TxIndexEntry{TxIndexRange: model.TxIndexRange{From: 10, To: 20, Wallets: wallets}, Txs: []model.IndexedTx{mintTx}}))
+	require.NoError(t, index.AddEntry(model.TxIndexEntry{TxIndexRange: model.TxIndexRange{From: 20, To: 30, Wallets: wallets}, Txs: []model.IndexedTx{refundTx}}))
+
+	reloaded, err := NewFileTxIndex(filePath)
+	require.NoError(t, err)
+
+	coverage, ok := reloaded.Coverage()
+	require.True(t, ok)
+	require.Equal(t, model.TxIndexRange{From: 10, To: 30, Wallets: wallets}, coverage)
+	require.Equal(t, []model.IndexedTx{mintTx}, reloaded.TxsByRef("PAYMENT1"))
+	require.Equal(t, []model.IndexedTx{mintTx}, reloaded.TxsByUid("uid1"))
+	require.Equal(t, []model.IndexedTx{refundTx}, reloaded.TxsByRef("PAYMENT2"))
+	require.Empty(t, reloaded.TxsByRef("PAYMENT3"))
+}
+
+func TestShouldRejectTxIndexEntryWithGap(t *testing.T) {
+	index, err := NewFileTxIndex(filepath.Join(t.TempDir(), "tx_index.jsonl"))
+	require.NoError(t, err)
+
+	require.NoError(t, index.AddEntry(model.TxIndexEntry{TxIndexRange: model.TxIndexRange{From: 10, To: 20}}))
+	require.Error(t, index.AddEntry(model.TxIndexEntry{TxIndexRange: model.TxIndexRange{From: 21, To: 30}}))
+
+	coverage, _ := index.Coverage()
+	require.Equal(t, int64(20), coverage.To)
+}
+
+func TestShouldResetTxIndex(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "tx_index.jsonl")
+	index, err := NewFileTxIndex(filePath)
+	require.NoError(t, err)
+
+	require.NoError(t, index.AddEntry(model.TxIndexEntry{
+		TxIndexRange: model.TxIndexRange{From: 10, To: 20},
+		Txs:          []model.IndexedTx{{Hash: "REFUND", Refs: []string{"PAYMENT1"}}},
+	}))
+	require.NoError(t, index.Reset())
+
+	_, ok := index.Coverage()
+	require.False(t, ok)
+	require.Empty(t, index.TxsByRef("PAYMENT1"))
+
+	require.NoError(t, index.AddEntry(model.TxIndexEntry{TxIndexRange: model.TxIndexRange{From: 50, To: 60}}))
+	reloaded, err := NewFileTxIndex(filePath)
+	require.NoError(t, err)
+
+	coverage, ok := reloaded.Coverage()
+	require.True(t, ok)
+	require.Equal(t, int64(50), coverage.From)
+}
+
+func TestShouldFailToLoadTxIndexIfFileIsInvalid(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "tx_index.jsonl")
+	require.NoError(t, os.WriteFile(filePath, []byte("invalid\n"), 0644))
+
+	_, err := NewFileTxIndex(filePath)
+	require.Error(t, err)
+}
+
+func TestShouldFailToLoadTxIndexWithGap(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "tx_index.jsonl")
+	require.NoError(t, os.WriteFile(filePath, []byte("{\"from\":10,\"to\":20}\n{\"from\":25,\"to\":30}\n"), 0644))
+
+	_, err := NewFileTxIndex(filePath)
+	require.Error(t, err)
+}
@@ -2,6 +2,8 @@ package client
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -14,20 +16,26 @@ import (
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
 )
 
-func NewTokenisedInfraClient(url string, marshaler marshaler) *tokenisedInfraClient {
+// Creating a client of the AuraPool. If public keys are given every quote must be signed by one of them,
+// otherwise the quotes are used as they are received.
+func NewTokenisedInfraClient(url string, marshaler marshaler, publicKeys []ed25519.PublicKey) *tokenisedInfraClient {
 	return &tokenisedInfraClient{
-		url:       url,
-		client:    &http.Client{Timeout: clientTimeout},
-		marshaler: marshaler,
+		url:        url,
+		client:     &http.Client{Timeout: clientTimeout},
+		marshaler:  marshaler,
+		publicKeys: publicKeys,
 	}
 }
 
-func (tic *tokenisedInfraClient) GetNFTData(ctx context.Context, cfg config.Config, uid, recipientCudosAddress string, paidAmount sdk.Coin) (model.NFTData, error) {
-	log.Info().Msgf("making request to %s", fmt.Sprintf("%s%s/%s/%s/%s", tic.url, getNFTDataUri, uid, recipientCudosAddress, paidAmount.Amount))
+// Getting the NFT data of the uid for the recipient. The time of the payment is passed as unix millis,
+// so the AuraPool evaluates the validity of the price at the time of the payment rather than at the time of the request.
+func (tic *tokenisedInfraClient) GetNFTData(ctx context.Context, cfg config.Config, uid, recipientCudosAddress string, paidAmount sdk.Coin, paidAt time.Time) (model.NFTData, error) {
+	url := fmt.Sprintf("%s%s/%s/%s/%s?paidAt=%d", tic.url, getNFTDataUri, uid, recipientCudosAddress, paidAmount.Amount, paidAt.UnixMilli())
+	log.Info().Msgf("making request to %s", url)
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
-		fmt.Sprintf("%s%s/%s/%s/%s", tic.url, getNFTDataUri, uid, recipientCudosAddress, paidAmount.Amount),
+		url,
 		nil,
 	)
 
@@ -46,7 +54,51 @@ func (tic *tokenisedInfraClient) GetNFTData(ctx context.Context, cfg config.Conf
 		return model.NFTData{}, nil
 	}
 
-	return tic.parseBody(res)
+	nft, err := tic.parseBody(res)
+	if err != nil {
+		return model.NFTData{}, err
+	}
+
+	if len(tic.publicKeys) == 0 {
+		return nft, nil
+	}
+
+	// The expiry of a valid quote is checked by the relayer, like the one of an unsigned quote
+	if err := tic.verifyQuote(uid, recipientCudosAddress, nft); err != nil {
+		return model.NFTData{}, err
+	}
+
+	return nft, nil
+}
+
+// Verifying that the quote is of the requested NFT and recipient and is signed by one of the configured keys.
+// A quote failing the verification is returned as an untrusted quote error, so the relayer refunds the payment instead of retrying it.
+func (tic *tokenisedInfraClient) verifyQuote(uid, recipientCudosAddress string, nft model.NFTData) error {
+	if nft.Id != uid {
+		return &model.UntrustedQuoteError{Uid: uid, Detail: fmt.Sprintf("quote is of nft (%s) instead of (%s)", nft.Id, uid)}
+	}
+
+	if nft.Signature == "" {
+		return &model.UntrustedQuoteError{Uid: uid, Detail: fmt.Sprintf("quote of nft (%s) is not signed", uid)}
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(nft.Signature)
+	if err != nil {
+		return &model.UntrustedQuoteError{Uid: uid, Detail: fmt.Sprintf("decoding signature of nft (%s) quote failed: %s", uid, err)}
+	}
+
+	signBytes, err := nft.SignBytes(recipientCudosAddress)
+	if err != nil {
+		return fmt.Errorf("encoding sign bytes of nft (%s) quote failed: %s", uid, err)
+	}
+
+	for _, publicKey := range tic.publicKeys {
+		if ed25519.Verify(publicKey, signBytes, signature) {
+			return nil
+		}
+	}
+
+	return &model.UntrustedQuoteError{Uid: uid, Detail: fmt.Sprintf("quote of nft (%s) has invalid signature", uid)}
 }
 
 func (tic *tokenisedInfraClient) parseBody(res *http.Response) (model.NFTData, error) {
@@ -78,9 +130,10 @@ type mintTx struct {
 }
 
 type tokenisedInfraClient struct {
-	url       string
-	client    *http.Client
-	marshaler marshaler
+	url        string
+	client     *http.Client
+	marshaler  marshaler
+	publicKeys []ed25519.PublicKey
 }
 
 const (
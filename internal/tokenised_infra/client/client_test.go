@@ -2,12 +2,18 @@ package client
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/config"
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/marshal"
@@ -18,14 +24,14 @@ import (
 )
 
 func TestShouldFailGetNFTDataWithInvalidUrl(t *testing.T) {
-	client := NewTokenisedInfraClient(badUrl, marshal.NewJsonMarshaler())
-	_, err := client.GetNFTData(context.Background(), config.Config{}, "testuid", "address", sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)))
+	client := NewTokenisedInfraClient(badUrl, marshal.NewJsonMarshaler(), nil)
+	_, err := client.GetNFTData(context.Background(), config.Config{}, "testuid", "address", sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), time.Now())
 	require.Error(t, err)
 }
 
 func TestShouldFailGetNFTDataWithNotRunningService(t *testing.T) {
-	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler())
-	_, err := client.GetNFTData(context.Background(), config.Config{}, "testuid", "address", sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)))
+	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler(), nil)
+	_, err := client.GetNFTData(context.Background(), config.Config{}, "testuid", "address", sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), time.Now())
 	require.Error(t, err)
 }
 
@@ -38,8 +44,8 @@ func TestShouldFailGetNFTDataIfInvalidJSONResponse(t *testing.T) {
 	go ws.Start()
 	defer ws.server.Shutdown(context.Background())
 
-	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler())
-	_, err = client.GetNFTData(context.Background(), config.Config{}, "testuid", "address", sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)))
+	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler(), nil)
+	_, err = client.GetNFTData(context.Background(), config.Config{}, "testuid", "address", sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), time.Now())
 	require.Error(t, err)
 	require.True(t, strings.Contains(err.Error(), "invalid character"))
 }
@@ -53,14 +59,14 @@ func TestGetNFTDataShouldReturnEmptyDataWithNoErrWhenNotUIDNotFound(t *testing.T
 	go ws.Start()
 	defer ws.server.Shutdown(context.Background())
 
-	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler())
-	data, err := client.GetNFTData(context.Background(), config.Config{}, "notfounduid", "address", sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)))
+	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler(), nil)
+	data, err := client.GetNFTData(context.Background(), config.Config{}, "notfounduid", "address", sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), time.Now())
 	require.NoError(t, err)
 	require.Equal(t, model.NFTData{}, data)
 }
 
 func TestShouldFailParseBodyWithBodyError(t *testing.T) {
-	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler())
+	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler(), nil)
 	readCloser := mockReadCloser{}
 	readCloser.On("Read", mock.AnythingOfType("[]uint8")).Return(0, errors.New("error reading"))
 	readCloser.On("Close").Return(errors.New("error closing"))
@@ -71,18 +77,18 @@ func TestShouldFailParseBodyWithBodyError(t *testing.T) {
 }
 
 // func TestShouldFailMarkMintedNFTWithInvalidUrl(t *testing.T) {
-// 	client := NewTokenisedInfraClient(badUrl, marshal.NewJsonMarshaler())
+// 	client := NewTokenisedInfraClient(badUrl, marshal.NewJsonMarshaler(), nil)
 // 	require.Error(t, client.MarkMintedNFT(context.Background(), "txhash", "testuid"))
 // }
 
 // func TestShouldFailMarkMintedNFTWithNotRunningService(t *testing.T) {
-// 	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler())
+// 	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler(), nil)
 // 	require.Error(t, client.MarkMintedNFT(context.Background(), "txhash", "testuid"))
 // }
 
 // func TestShouldFailMarkMintedNFTIfFailsToMarshal(t *testing.T) {
-// 	client := NewTokenisedInfraClient(localServiceUrl, &mockMarshaler{})
-// 	require.Equal(t, failedMarshal, client.MarkMintedNFT(context.Background(), "txhash", "testuid", "address", sdk.NewCoin("acudos", sdk.NewIntFromUint64(300))))
+// 	client := NewTokenisedInfraClient(localServiceUrl, &mockMarshaler{}, nil)
+// 	require.Equal(t, failedMarshal, client.MarkMintedNFT(context.Background(), "txhash", "testuid", "address", sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), time.Now()))
 // }
 
 // func (mm *mockMarshaler) Marshal(v any) ([]byte, error) {
@@ -99,7 +105,7 @@ func TestShouldFailParseBodyWithBodyError(t *testing.T) {
 // }
 
 func TestShouldParseBody(t *testing.T) {
-	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler())
+	client := NewTokenisedInfraClient(localServiceUrl, marshal.NewJsonMarshaler(), nil)
 	bz, err := client.marshaler.Marshal(&model.NFTData{Id: "1"})
 	require.NoError(t, err)
 
@@ -110,6 +116,190 @@ func TestShouldParseBody(t *testing.T) {
 	require.EqualValues(t, model.NFTData{Id: "1", Price: sdk.ZeroInt()}, nftData)
 }
 
+func TestShouldAcceptSignedQuote(t *testing.T) {
+	nft := newSignedQuote(t, quoteKey, quoteRecipient)
+	client := newQuoteClient(t, nft)
+
+	data, err := client.GetNFTData(context.Background(), config.Config{}, quoteUid, quoteRecipient, sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), time.Now())
+	require.NoError(t, err)
+	require.Equal(t, nft, data)
+}
+
+func TestShouldAcceptUnsignedQuoteWithoutPublicKeys(t *testing.T) {
+	nft := newSignedQuote(t, quoteKey, quoteRecipient)
+	nft.Signature = ""
+	server := httptest.NewServer(newQuoteHandler(t, nft))
+	defer server.Close()
+
+	client := NewTokenisedInfraClient(server.URL, marshal.NewJsonMarshaler(), nil)
+	data, err := client.GetNFTData(context.Background(), config.Config{}, quoteUid, quoteRecipient, sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), time.Now())
+	require.NoError(t, err)
+	require.Equal(t, nft, data)
+}
+
+func TestShouldReturnExpiredSignedQuote(t *testing.T) {
+	nft := newQuote()
+	nft.PriceValidUntil = time.Now().Add(-time.Minute).UnixMilli()
+	signQuote(t, &nft, quoteKey, quoteRecipient)
+	client := newQuoteClient(t, nft)
+
+	data, err := client.GetNFTData(context.Background(), config.Config{}, quoteUid, quoteRecipient, sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), time.Now())
+	require.NoError(t, err)
+	require.Equal(t, nft, data)
+}
+
+func TestShouldAcceptSignedQuoteValidAtPaymentTime(t *testing.T) {
+	nft := newQuote()
+	nft.PriceValidUntil = time.Now().Add(-time.Hour).UnixMilli()
+	signQuote(t, &nft, quoteKey, quoteRecipient)
+	client := newQuoteClient(t, nft)
+
+	paidAt := time.UnixMilli(nft.PriceValidUntil).Add(-time.Minute)
+	data, err := client.GetNFTData(context.Background(), config.Config{}, quoteUid, quoteRecipient, sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), paidAt)
+	require.NoError(t, err)
+	require.Equal(t, nft, data)
+}
+
+func TestShouldPassPaymentTimeToAuraPool(t *testing.T) {
+	var requestUrl string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestUrl = r.URL.String()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewTokenisedInfraClient(server.URL, marshal.NewJsonMarshaler(), nil)
+	_, err := client.GetNFTData(context.Background(), config.Config{}, quoteUid, quoteRecipient, sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), time.UnixMilli(1664625600000))
+	require.NoError(t, err)
+	require.Equal(t, "/api/v1/nft/on-demand-minting-nft/quoteuid/cudos1recipient/300?paidAt=1664625600000", requestUrl)
+}
+
+func TestShouldRejectInvalidQuote(t *testing.T) {
+	otherKey := ed25519.NewKeyFromSeed([]byte("another-aura-pool-test-key-seed!"))
+
+	tests := []struct {
+		name      string
+		uid       string
+		recipient string
+		quote     func(t *testing.T) model.NFTData
+	}{
+		{
+			name:      "Unsigned",
+			uid:       quoteUid,
+			recipient: quoteRecipient,
+			quote: func(t *testing.T) model.NFTData {
+				return newQuote()
+			},
+		},
+		{
+			name:      "SignatureNotBase64",
+			uid:       quoteUid,
+			recipient: quoteRecipient,
+			quote: func(t *testing.T) model.NFTData {
+				nft := newQuote()
+				nft.Signature = "not base64"
+				return nft
+			},
+		},
+		{
+			name:      "SignedByUnknownKey",
+			uid:       quoteUid,
+			recipient: quoteRecipient,
+			quote: func(t *testing.T) model.NFTData {
+				return newSignedQuote(t, otherKey, quoteRecipient)
+			},
+		},
+		{
+			name:      "TamperedPrice",
+			uid:       quoteUid,
+			recipient: quoteRecipient,
+			quote: func(t *testing.T) model.NFTData {
+				nft := newSignedQuote(t, quoteKey, quoteRecipient)
+				nft.Price = sdk.NewIntFromUint64(1)
+				return nft
+			},
+		},
+		{
+			name:      "OtherRecipient",
+			uid:       quoteUid,
+			recipient: "cudos1other",
+			quote: func(t *testing.T) model.NFTData {
+				return newSignedQuote(t, quoteKey, quoteRecipient)
+			},
+		},
+		{
+			name:      "OtherUid",
+			uid:       "otheruid",
+			recipient: quoteRecipient,
+			quote: func(t *testing.T) model.NFTData {
+				return newSignedQuote(t, quoteKey, quoteRecipient)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newQuoteClient(t, tc.quote(t))
+			_, err := client.GetNFTData(context.Background(), config.Config{}, tc.uid, tc.recipient, sdk.NewCoin("acudos", sdk.NewIntFromUint64(300)), time.Now())
+			var untrustedErr *model.UntrustedQuoteError
+			require.True(t, errors.As(err, &untrustedErr))
+			require.Equal(t, tc.uid, untrustedErr.Uid)
+		})
+	}
+}
+
+func TestSignBytesShouldBeCanonical(t *testing.T) {
+	nft := newQuote()
+	signBytes, err := nft.SignBytes(quoteRecipient)
+	require.NoError(t, err)
+	require.Equal(t, `{"data":"<data>","denomId":"testdenom","id":"quoteuid","name":"test nft","price":"1000","priceValidUntil":`+
+		strconv.FormatInt(nft.PriceValidUntil, 10)+`,"recipient":"cudos1recipient","status":"queued","uri":"test uri"}`, string(signBytes))
+}
+
+func newQuote() model.NFTData {
+	return model.NFTData{
+		Id:              quoteUid,
+		Price:           sdk.NewIntFromUint64(1000),
+		Name:            "test nft",
+		Uri:             "test uri",
+		Data:            "<data>",
+		DenomID:         "testdenom",
+		Status:          model.QueuedNFTStatus,
+		PriceValidUntil: time.Now().Add(time.Hour).UnixMilli(),
+	}
+}
+
+func newSignedQuote(t *testing.T, privKey ed25519.PrivateKey, recipient string) model.NFTData {
+	nft := newQuote()
+	signQuote(t, &nft, privKey, recipient)
+	return nft
+}
+
+func signQuote(t *testing.T, nft *model.NFTData, privKey ed25519.PrivateKey, recipient string) {
+	signBytes, err := nft.SignBytes(recipient)
+	require.NoError(t, err)
+	nft.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privKey, signBytes))
+}
+
+func newQuoteClient(t *testing.T, nft model.NFTData) *tokenisedInfraClient {
+	server := httptest.NewServer(newQuoteHandler(t, nft))
+	t.Cleanup(server.Close)
+
+	return NewTokenisedInfraClient(server.URL, marshal.NewJsonMarshaler(), []ed25519.PublicKey{quoteKey.Public().(ed25519.PublicKey)})
+}
+
+func newQuoteHandler(t *testing.T, nft model.NFTData) http.HandlerFunc {
+	bz, err := json.Marshal(nft)
+	require.NoError(t, err)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(bz)
+	}
+}
+
+var quoteKey = ed25519.NewKeyFromSeed([]byte("aura-pool-test-quote-key-seed-32"))
+
 type webServer struct {
 	server   http.Server
 	listener net.Listener
@@ -153,4 +343,6 @@ func (mrc *mockReadCloser) Close() error {
 const (
 	badUrl          = ":badurl"
 	localServiceUrl = "http://127.0.0.1:1314"
+	quoteUid        = "quoteuid"
+	quoteRecipient  = "cudos1recipient"
 )
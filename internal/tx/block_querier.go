@@ -0,0 +1,53 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+func NewBlockQuerier(node blockFetcher) *blockQuerier {
+	return &blockQuerier{node: node}
+}
+
+// Querying the time of the block at the given height. Only the header of the block is fetched.
+func (bq *blockQuerier) BlockTime(ctx context.Context, height int64) (time.Time, error) {
+	result, err := bq.node.BlockchainInfo(ctx, height, height)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fetching block (%d) failed: %s", height, err)
+	}
+
+	for _, blockMeta := range result.BlockMetas {
+		if blockMeta.Header.Height == height {
+			return blockMeta.Header.Time, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("block (%d) not found", height)
+}
+
+// Querying the status of the node, i.e. whether it is still syncing and which block is the latest one it knows of.
+func (bq *blockQuerier) NodeStatus(ctx context.Context) (model.NodeStatus, error) {
+	result, err := bq.node.Status(ctx)
+	if err != nil {
+		return model.NodeStatus{}, fmt.Errorf("fetching node status failed: %s", err)
+	}
+
+	return model.NodeStatus{
+		CatchingUp:      result.SyncInfo.CatchingUp,
+		LatestHeight:    result.SyncInfo.LatestBlockHeight,
+		LatestBlockTime: result.SyncInfo.LatestBlockTime,
+	}, nil
+}
+
+type blockQuerier struct {
+	node blockFetcher
+}
+
+type blockFetcher interface {
+	BlockchainInfo(ctx context.Context, minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, error)
+	Status(ctx context.Context) (*ctypes.ResultStatus, error)
+}
@@ -0,0 +1,73 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/stretchr/testify/require"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func TestShouldQueryBlockTime(t *testing.T) {
+	blockTime := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	blockQuerier := NewBlockQuerier(&mockBlockFetcher{blockMetas: []*tmtypes.BlockMeta{
+		{Header: tmtypes.Header{Height: 10, Time: blockTime}},
+	}})
+
+	haveTime, err := blockQuerier.BlockTime(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, blockTime, haveTime)
+}
+
+func TestShouldFailIfBlockNotFound(t *testing.T) {
+	blockQuerier := NewBlockQuerier(&mockBlockFetcher{blockMetas: []*tmtypes.BlockMeta{}})
+	_, err := blockQuerier.BlockTime(context.Background(), 10)
+	require.Equal(t, errors.New("block (10) not found"), err)
+}
+
+func TestShouldFailIfBlockchainInfoFails(t *testing.T) {
+	blockQuerier := NewBlockQuerier(&mockBlockFetcher{err: errors.New("failed blockchain info request")})
+	_, err := blockQuerier.BlockTime(context.Background(), 10)
+	require.Equal(t, errors.New("fetching block (10) failed: failed blockchain info request"), err)
+}
+
+func TestShouldQueryNodeStatus(t *testing.T) {
+	blockTime := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	blockQuerier := NewBlockQuerier(&mockBlockFetcher{syncInfo: ctypes.SyncInfo{LatestBlockHeight: 10, LatestBlockTime: blockTime, CatchingUp: true}})
+
+	status, err := blockQuerier.NodeStatus(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, model.NodeStatus{CatchingUp: true, LatestHeight: 10, LatestBlockTime: blockTime}, status)
+}
+
+func TestShouldFailIfNodeStatusFails(t *testing.T) {
+	blockQuerier := NewBlockQuerier(&mockBlockFetcher{err: errors.New("failed status request")})
+	_, err := blockQuerier.NodeStatus(context.Background())
+	require.Equal(t, errors.New("fetching node status failed: failed status request"), err)
+}
+
+func (m *mockBlockFetcher) BlockchainInfo(ctx context.Context, minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &ctypes.ResultBlockchainInfo{BlockMetas: m.blockMetas}, nil
+}
+
+func (m *mockBlockFetcher) Status(ctx context.Context) (*ctypes.ResultStatus, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &ctypes.ResultStatus{SyncInfo: m.syncInfo}, nil
+}
+
+type mockBlockFetcher struct {
+	blockMetas []*tmtypes.BlockMeta
+	syncInfo   ctypes.SyncInfo
+	err        error
+}
@@ -0,0 +1,295 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// The block scanner is a source of transactions for nodes without a tx indexer. It walks the blocks and their results
+// and keeps the transactions of the tracked addresses only, i.e. the payments to the wallets and the mints and refunds sent by them.
+// The kept transactions of the settled blocks are dropped by Prune, so the memory does not grow with the chain.
+func NewBlockScanner(node blockScanFetcher, addresses []string) *blockScanner {
+	trackedAddresses := map[string]bool{}
+	for _, address := range addresses {
+		trackedAddresses[address] = true
+	}
+
+	return &blockScanner{node: node, addresses: trackedAddresses}
+}
+
+// Answering a tx_search query from the scanned transactions. Only the queries of the relayer are supported, these are conjunctions of
+// conditions on tx.height and of <event type>.<attribute>='<value>' conditions. A condition on an event is met if any event of the transaction has the attribute,
+// like it is in tx_search.
+// The blocks from the lower height bound of the query up to its upper bound, or the latest block without one, are scanned first,
+// each block is scanned only once.
+func (bs *blockScanner) Query(ctx context.Context, query string) (*ctypes.ResultTxSearch, error) {
+	txQuery, err := parseTxQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("block scan query (%s) failed: %s", query, err)
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if err := bs.scan(ctx, txQuery.minHeight, txQuery.maxHeight); err != nil {
+		return nil, fmt.Errorf("block scan query (%s) failed: %s", query, err)
+	}
+
+	results := &ctypes.ResultTxSearch{Txs: []*ctypes.ResultTx{}}
+	for _, resultTx := range bs.txs {
+		if txQuery.matches(resultTx) {
+			results.Txs = append(results.Txs, resultTx)
+		}
+	}
+	results.TotalCount = len(results.Txs)
+
+	return results, nil
+}
+
+// Dropping the transactions up to the given height, e.g. the height of the state once a tick settled the payments up to it.
+// A later query below the height scans the blocks again.
+func (bs *blockScanner) Prune(height int64) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.scannedTo == 0 || height < bs.scannedFrom {
+		return
+	}
+
+	if height >= bs.scannedTo {
+		bs.scannedFrom = 0
+		bs.scannedTo = 0
+		bs.txs = nil
+		return
+	}
+
+	kept := []*ctypes.ResultTx{}
+	for _, resultTx := range bs.txs {
+		if resultTx.Height > height {
+			kept = append(kept, resultTx)
+		}
+	}
+
+	bs.txs = kept
+	bs.scannedFrom = height + 1
+}
+
+// Extending the scanned range to cover the blocks from the given height up to the max height, or the latest block if it is negative or above it.
+// The scanned range is kept contiguous, so a range below it is scanned up to its start.
+func (bs *blockScanner) scan(ctx context.Context, minHeight, maxHeight int64) error {
+	status, err := bs.node.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching node status failed: %s", err)
+	}
+	latestHeight := status.SyncInfo.LatestBlockHeight
+	if maxHeight >= 0 && maxHeight < latestHeight {
+		latestHeight = maxHeight
+	}
+
+	if bs.scannedTo == 0 {
+		bs.scannedFrom = minHeight
+		bs.scannedTo = minHeight - 1
+	}
+
+	if minHeight < bs.scannedFrom {
+		if err := bs.scanBlocks(ctx, minHeight, bs.scannedFrom-1); err != nil {
+			return err
+		}
+		bs.scannedFrom = minHeight
+	}
+
+	if latestHeight > bs.scannedTo {
+		if err := bs.scanBlocks(ctx, bs.scannedTo+1, latestHeight); err != nil {
+			return err
+		}
+		bs.scannedTo = latestHeight
+	}
+
+	sort.SliceStable(bs.txs, func(i, j int) bool {
+		if bs.txs[i].Height != bs.txs[j].Height {
+			return bs.txs[i].Height < bs.txs[j].Height
+		}
+		return bs.txs[i].Index < bs.txs[j].Index
+	})
+
+	return nil
+}
+
+// Scanning the blocks in the given range. Nothing of the range is kept unless all of its blocks are scanned,
+// so a failed scan is repeated by the next query.
+func (bs *blockScanner) scanBlocks(ctx context.Context, fromHeight, toHeight int64) error {
+	txs := []*ctypes.ResultTx{}
+	for height := fromHeight; height <= toHeight; height++ {
+		blockTxs, err := bs.scanBlock(ctx, height)
+		if err != nil {
+			return err
+		}
+		txs = append(txs, blockTxs...)
+	}
+
+	bs.txs = append(bs.txs, txs...)
+	return nil
+}
+
+func (bs *blockScanner) scanBlock(ctx context.Context, height int64) ([]*ctypes.ResultTx, error) {
+	block, err := bs.node.Block(ctx, &height)
+	if err != nil {
+		return nil, fmt.Errorf("fetching block (%d) failed: %s", height, err)
+	}
+
+	blockResults, err := bs.node.BlockResults(ctx, &height)
+	if err != nil {
+		return nil, fmt.Errorf("fetching results of block (%d) failed: %s", height, err)
+	}
+
+	if len(blockResults.TxsResults) != len(block.Block.Txs) {
+		return nil, fmt.Errorf("block (%d) has %d txs but %d results", height, len(block.Block.Txs), len(blockResults.TxsResults))
+	}
+
+	txs := []*ctypes.ResultTx{}
+	for i, tx := range block.Block.Txs {
+		txResult := blockResults.TxsResults[i]
+		if !bs.isTracked(txResult.Events) {
+			continue
+		}
+
+		txs = append(txs, &ctypes.ResultTx{
+			Hash:     tx.Hash(),
+			Height:   height,
+			Index:    uint32(i),
+			TxResult: *txResult,
+			Tx:       tx,
+		})
+	}
+
+	return txs, nil
+}
+
+// Checking whether any event of a transaction refers to a tracked address.
+func (bs *blockScanner) isTracked(events []abcitypes.Event) bool {
+	for _, event := range events {
+		for _, attribute := range event.Attributes {
+			if bs.addresses[string(attribute.Value)] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Parsing a query of conditions joined by AND.
+func parseTxQuery(query string) (txQuery, error) {
+	parsed := txQuery{minHeight: 1, maxHeight: -1, attributes: map[string]string{}}
+
+	for _, condition := range strings.Split(query, " AND ") {
+		condition = strings.TrimSpace(condition)
+
+		if strings.HasPrefix(condition, txHeightKey) {
+			if err := parsed.parseHeightCondition(strings.TrimPrefix(condition, txHeightKey)); err != nil {
+				return txQuery{}, err
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(condition, "=")
+		if !ok || len(value) < 2 || !strings.HasPrefix(value, "'") || !strings.HasSuffix(value, "'") {
+			return txQuery{}, fmt.Errorf("unsupported condition (%s)", condition)
+		}
+		parsed.attributes[key] = value[1 : len(value)-1]
+	}
+
+	return parsed, nil
+}
+
+func (q *txQuery) parseHeightCondition(condition string) error {
+	for _, operator := range []string{">=", "<=", ">", "<", "="} {
+		if !strings.HasPrefix(condition, operator) {
+			continue
+		}
+
+		height, err := strconv.ParseInt(strings.TrimPrefix(condition, operator), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid height condition (%s%s): %s", txHeightKey, condition, err)
+		}
+
+		switch operator {
+		case ">=":
+			q.minHeight = height
+		case ">":
+			q.minHeight = height + 1
+		case "<=":
+			q.maxHeight = height
+		case "<":
+			q.maxHeight = height - 1
+		case "=":
+			q.minHeight = height
+			q.maxHeight = height
+		}
+
+		if q.minHeight < 1 {
+			q.minHeight = 1
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported height condition (%s%s)", txHeightKey, condition)
+}
+
+func (q *txQuery) matches(resultTx *ctypes.ResultTx) bool {
+	if resultTx.Height < q.minHeight || (q.maxHeight >= 0 && resultTx.Height > q.maxHeight) {
+		return false
+	}
+
+	for key, value := range q.attributes {
+		if !hasEventAttribute(resultTx.TxResult.Events, key, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasEventAttribute(events []abcitypes.Event, key, value string) bool {
+	for _, event := range events {
+		for _, attribute := range event.Attributes {
+			if event.Type+"."+string(attribute.Key) == key && string(attribute.Value) == value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+const txHeightKey = "tx.height"
+
+type txQuery struct {
+	minHeight int64
+	// Negative if the query has no upper bound
+	maxHeight  int64
+	attributes map[string]string
+}
+
+type blockScanner struct {
+	node      blockScanFetcher
+	addresses map[string]bool
+	// The scanned range of heights and the tracked transactions in it ordered by height and index
+	scannedFrom int64
+	scannedTo   int64
+	txs         []*ctypes.ResultTx
+	mu          sync.Mutex
+}
+
+type blockScanFetcher interface {
+	Status(ctx context.Context) (*ctypes.ResultStatus, error)
+	Block(ctx context.Context, height *int64) (*ctypes.ResultBlock, error)
+	BlockResults(ctx context.Context, height *int64) (*ctypes.ResultBlockResults, error)
+}
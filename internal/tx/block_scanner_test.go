@@ -0,0 +1,266 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func TestShouldFindTransfersToTrackedAddress(t *testing.T) {
+	fetcher := newMockBlockScanFetcher(map[int64][]mockScannedTx{
+		1: {{tx: "payment1", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+		2: {{tx: "other", events: []abcitypes.Event{transferEvent("buyer", "someone")}}},
+		3: {
+			{tx: "unrelated", events: []abcitypes.Event{transferEvent("a", "b")}},
+			{tx: "payment2", events: []abcitypes.Event{transferEvent("buyer", "wallet")}},
+		},
+	})
+	blockScanner := NewBlockScanner(fetcher, []string{"wallet"})
+
+	results, err := blockScanner.Query(context.Background(), "tx.height>0 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Equal(t, 2, results.TotalCount)
+	require.Equal(t, tmtypes.Tx("payment1"), results.Txs[0].Tx)
+	require.Equal(t, int64(1), results.Txs[0].Height)
+	require.Equal(t, tmtypes.Tx("payment1").Hash(), []byte(results.Txs[0].Hash))
+	require.Equal(t, tmtypes.Tx("payment2"), results.Txs[1].Tx)
+	require.Equal(t, uint32(1), results.Txs[1].Index)
+}
+
+func TestShouldMatchHeightBounds(t *testing.T) {
+	fetcher := newMockBlockScanFetcher(map[int64][]mockScannedTx{
+		1: {{tx: "payment1", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+		2: {{tx: "payment2", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+		3: {{tx: "payment3", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+	})
+	blockScanner := NewBlockScanner(fetcher, []string{"wallet"})
+
+	results, err := blockScanner.Query(context.Background(), "tx.height>1 AND tx.height<=2 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 1)
+	require.Equal(t, tmtypes.Tx("payment2"), results.Txs[0].Tx)
+
+	results, err = blockScanner.Query(context.Background(), "tx.height>=2 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 2)
+}
+
+func TestShouldMatchConditionsOnDifferentEvents(t *testing.T) {
+	fetcher := newMockBlockScanFetcher(map[int64][]mockScannedTx{
+		1: {{tx: "refund", events: []abcitypes.Event{transferEvent("wallet", "feecollector"), transferEvent("payer", "buyer")}}},
+	})
+	blockScanner := NewBlockScanner(fetcher, []string{"wallet"})
+
+	results, err := blockScanner.Query(context.Background(), "tx.height>=1 AND transfer.sender='wallet' AND transfer.recipient='buyer'")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 1)
+
+	results, err = blockScanner.Query(context.Background(), "tx.height>=1 AND transfer.sender='wallet' AND transfer.recipient='other'")
+	require.NoError(t, err)
+	require.Empty(t, results.Txs)
+}
+
+func TestShouldKeepOnlyMintsOfTrackedAddresses(t *testing.T) {
+	fetcher := newMockBlockScanFetcher(map[int64][]mockScannedTx{
+		1: {{tx: "mint", events: []abcitypes.Event{mintEvent("nftuid1", "creator")}}},
+		2: {{tx: "ownmint", events: []abcitypes.Event{mintEvent("nftuid1", "wallet")}}},
+	})
+	blockScanner := NewBlockScanner(fetcher, []string{"wallet"})
+
+	results, err := blockScanner.Query(context.Background(), "tx.height>=0 AND marketplace_mint_nft.uid='nftuid1'")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 1)
+	require.Equal(t, tmtypes.Tx("ownmint"), results.Txs[0].Tx)
+}
+
+func TestShouldScanUpToMaxHeightOfQuery(t *testing.T) {
+	fetcher := newMockBlockScanFetcher(map[int64][]mockScannedTx{
+		1: {{tx: "payment1", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+		2: {{tx: "payment2", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+		3: {{tx: "payment3", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+	})
+	blockScanner := NewBlockScanner(fetcher, []string{"wallet"})
+
+	results, err := blockScanner.Query(context.Background(), "tx.height>0 AND tx.height<=2 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 2)
+	require.Equal(t, []int64{1, 2}, fetcher.fetchedHeights)
+
+	_, err = blockScanner.Query(context.Background(), "tx.height>0 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2, 3}, fetcher.fetchedHeights)
+}
+
+func TestShouldPruneSettledTxs(t *testing.T) {
+	fetcher := newMockBlockScanFetcher(map[int64][]mockScannedTx{
+		1: {{tx: "payment1", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+		2: {{tx: "payment2", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+		3: {{tx: "payment3", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+	})
+	blockScanner := NewBlockScanner(fetcher, []string{"wallet"})
+
+	_, err := blockScanner.Query(context.Background(), "tx.height>0 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+
+	blockScanner.Prune(2)
+	require.Len(t, blockScanner.txs, 1)
+	require.Equal(t, tmtypes.Tx("payment3"), blockScanner.txs[0].Tx)
+
+	results, err := blockScanner.Query(context.Background(), "tx.height>2 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 1)
+	require.Equal(t, []int64{1, 2, 3}, fetcher.fetchedHeights)
+
+	// The pruned blocks are scanned again by a query below the settled height
+	results, err = blockScanner.Query(context.Background(), "tx.height>1 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 2)
+	require.Equal(t, []int64{1, 2, 3, 2}, fetcher.fetchedHeights)
+
+	blockScanner.Prune(3)
+	require.Empty(t, blockScanner.txs)
+
+	fetcher.latestHeight = 4
+	fetcher.blocks[4] = []mockScannedTx{{tx: "payment4", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}}
+	results, err = blockScanner.Query(context.Background(), "tx.height>3 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 1)
+	require.Equal(t, []int64{1, 2, 3, 2, 4}, fetcher.fetchedHeights)
+}
+
+func TestShouldScanEachBlockOnce(t *testing.T) {
+	fetcher := newMockBlockScanFetcher(map[int64][]mockScannedTx{
+		1: {{tx: "payment1", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+		2: {{tx: "payment2", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+		3: {{tx: "payment3", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+	})
+	blockScanner := NewBlockScanner(fetcher, []string{"wallet"})
+
+	_, err := blockScanner.Query(context.Background(), "tx.height>1 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Equal(t, []int64{2, 3}, fetcher.fetchedHeights)
+
+	fetcher.latestHeight = 4
+	fetcher.blocks[4] = []mockScannedTx{{tx: "payment4", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}}
+
+	results, err := blockScanner.Query(context.Background(), "tx.height>=1 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Equal(t, []int64{2, 3, 1, 4}, fetcher.fetchedHeights)
+	require.Len(t, results.Txs, 4)
+	require.Equal(t, tmtypes.Tx("payment1"), results.Txs[0].Tx)
+	require.Equal(t, tmtypes.Tx("payment4"), results.Txs[3].Tx)
+}
+
+func TestShouldRescanBlocksIfScanFails(t *testing.T) {
+	fetcher := newMockBlockScanFetcher(map[int64][]mockScannedTx{
+		1: {{tx: "payment1", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+		2: {{tx: "payment2", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+	})
+	fetcher.failHeight = 2
+	blockScanner := NewBlockScanner(fetcher, []string{"wallet"})
+
+	_, err := blockScanner.Query(context.Background(), "tx.height>0 AND transfer.recipient='wallet'")
+	require.Equal(t, errors.New("block scan query (tx.height>0 AND transfer.recipient='wallet') failed: fetching block (2) failed: failed block request"), err)
+
+	fetcher.failHeight = 0
+	results, err := blockScanner.Query(context.Background(), "tx.height>0 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 2)
+}
+
+func TestShouldFailIfQueryIsNotSupported(t *testing.T) {
+	blockScanner := NewBlockScanner(newMockBlockScanFetcher(map[int64][]mockScannedTx{}), []string{"wallet"})
+
+	_, err := blockScanner.Query(context.Background(), "tx.height>0 OR transfer.recipient='wallet'")
+	require.Error(t, err)
+
+	_, err = blockScanner.Query(context.Background(), "tx.height!0 AND transfer.recipient='wallet'")
+	require.Error(t, err)
+
+	_, err = blockScanner.Query(context.Background(), "tx.height>0 AND transfer.recipient=wallet")
+	require.Error(t, err)
+}
+
+func TestShouldFailIfBlockResultsDoNotMatchBlock(t *testing.T) {
+	fetcher := newMockBlockScanFetcher(map[int64][]mockScannedTx{
+		1: {{tx: "payment1", events: []abcitypes.Event{transferEvent("buyer", "wallet")}}},
+	})
+	fetcher.missingResults = true
+	blockScanner := NewBlockScanner(fetcher, []string{"wallet"})
+
+	_, err := blockScanner.Query(context.Background(), "tx.height>0 AND transfer.recipient='wallet'")
+	require.Equal(t, errors.New("block scan query (tx.height>0 AND transfer.recipient='wallet') failed: block (1) has 1 txs but 0 results"), err)
+}
+
+func transferEvent(sender, recipient string) abcitypes.Event {
+	return abcitypes.Event{Type: "transfer", Attributes: []abcitypes.EventAttribute{
+		{Key: []byte("recipient"), Value: []byte(recipient)},
+		{Key: []byte("sender"), Value: []byte(sender)},
+		{Key: []byte("amount"), Value: []byte("100acudos")},
+	}}
+}
+
+func mintEvent(uid, creator string) abcitypes.Event {
+	return abcitypes.Event{Type: "marketplace_mint_nft", Attributes: []abcitypes.EventAttribute{
+		{Key: []byte("uid"), Value: []byte(uid)},
+		{Key: []byte("creator"), Value: []byte(creator)},
+	}}
+}
+
+func newMockBlockScanFetcher(blocks map[int64][]mockScannedTx) *mockBlockScanFetcher {
+	latestHeight := int64(0)
+	for height := range blocks {
+		if height > latestHeight {
+			latestHeight = height
+		}
+	}
+
+	return &mockBlockScanFetcher{blocks: blocks, latestHeight: latestHeight}
+}
+
+func (m *mockBlockScanFetcher) Status(ctx context.Context) (*ctypes.ResultStatus, error) {
+	return &ctypes.ResultStatus{SyncInfo: ctypes.SyncInfo{LatestBlockHeight: m.latestHeight}}, nil
+}
+
+func (m *mockBlockScanFetcher) Block(ctx context.Context, height *int64) (*ctypes.ResultBlock, error) {
+	if *height == m.failHeight {
+		return nil, errors.New("failed block request")
+	}
+	m.fetchedHeights = append(m.fetchedHeights, *height)
+
+	txs := tmtypes.Txs{}
+	for _, scannedTx := range m.blocks[*height] {
+		txs = append(txs, tmtypes.Tx(scannedTx.tx))
+	}
+
+	return &ctypes.ResultBlock{Block: &tmtypes.Block{Data: tmtypes.Data{Txs: txs}}}, nil
+}
+
+func (m *mockBlockScanFetcher) BlockResults(ctx context.Context, height *int64) (*ctypes.ResultBlockResults, error) {
+	txsResults := []*abcitypes.ResponseDeliverTx{}
+	if !m.missingResults {
+		for _, scannedTx := range m.blocks[*height] {
+			txsResults = append(txsResults, &abcitypes.ResponseDeliverTx{Events: scannedTx.events})
+		}
+	}
+
+	return &ctypes.ResultBlockResults{Height: *height, TxsResults: txsResults}, nil
+}
+
+type mockBlockScanFetcher struct {
+	blocks         map[int64][]mockScannedTx
+	latestHeight   int64
+	failHeight     int64
+	missingResults bool
+	fetchedHeights []int64
+}
+
+type mockScannedTx struct {
+	tx     string
+	events []abcitypes.Event
+}
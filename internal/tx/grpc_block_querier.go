@@ -0,0 +1,64 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	"google.golang.org/grpc"
+)
+
+// The gRPC block querier queries the blocks and the status of the node by the Cosmos SDK tendermint service,
+// so the relayer can run against a node exposing gRPC only.
+func NewGRPCBlockQuerier(client tmServiceClient) *grpcBlockQuerier {
+	return &grpcBlockQuerier{client: client}
+}
+
+// Querying the time of the block at the given height.
+func (gq *grpcBlockQuerier) BlockTime(ctx context.Context, height int64) (time.Time, error) {
+	res, err := gq.client.GetBlockByHeight(ctx, &tmservice.GetBlockByHeightRequest{Height: height})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fetching block (%d) failed: %s", height, err)
+	}
+
+	if res.Block == nil {
+		return time.Time{}, fmt.Errorf("block (%d) not found", height)
+	}
+
+	return res.Block.Header.Time, nil
+}
+
+// Querying whether the node is syncing and its latest block.
+func (gq *grpcBlockQuerier) NodeStatus(ctx context.Context) (model.NodeStatus, error) {
+	syncing, err := gq.client.GetSyncing(ctx, &tmservice.GetSyncingRequest{})
+	if err != nil {
+		return model.NodeStatus{}, fmt.Errorf("fetching node status failed: %s", err)
+	}
+
+	latest, err := gq.client.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	if err != nil {
+		return model.NodeStatus{}, fmt.Errorf("fetching latest block failed: %s", err)
+	}
+
+	if latest.Block == nil {
+		return model.NodeStatus{}, fmt.Errorf("latest block not found")
+	}
+
+	return model.NodeStatus{
+		CatchingUp:      syncing.Syncing,
+		LatestHeight:    latest.Block.Header.Height,
+		LatestBlockTime: latest.Block.Header.Time,
+	}, nil
+}
+
+type grpcBlockQuerier struct {
+	client tmServiceClient
+}
+
+type tmServiceClient interface {
+	GetSyncing(ctx context.Context, in *tmservice.GetSyncingRequest, opts ...grpc.CallOption) (*tmservice.GetSyncingResponse, error)
+	GetLatestBlock(ctx context.Context, in *tmservice.GetLatestBlockRequest, opts ...grpc.CallOption) (*tmservice.GetLatestBlockResponse, error)
+	GetBlockByHeight(ctx context.Context, in *tmservice.GetBlockByHeightRequest, opts ...grpc.CallOption) (*tmservice.GetBlockByHeightResponse, error)
+}
@@ -0,0 +1,60 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	"github.com/stretchr/testify/require"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"google.golang.org/grpc"
+)
+
+func TestShouldQueryBlockTimeByGRPC(t *testing.T) {
+	blockTime := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	blockQuerier := NewGRPCBlockQuerier(&mockTmServiceClient{block: &tmproto.Block{Header: tmproto.Header{Height: 10, Time: blockTime}}})
+
+	haveTime, err := blockQuerier.BlockTime(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, blockTime, haveTime)
+}
+
+func TestShouldFailIfGRPCBlockNotFound(t *testing.T) {
+	_, err := NewGRPCBlockQuerier(&mockTmServiceClient{}).BlockTime(context.Background(), 10)
+	require.Equal(t, errors.New("block (10) not found"), err)
+}
+
+func TestShouldQueryNodeStatusByGRPC(t *testing.T) {
+	blockTime := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	blockQuerier := NewGRPCBlockQuerier(&mockTmServiceClient{syncing: true, block: &tmproto.Block{Header: tmproto.Header{Height: 10, Time: blockTime}}})
+
+	status, err := blockQuerier.NodeStatus(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, model.NodeStatus{CatchingUp: true, LatestHeight: 10, LatestBlockTime: blockTime}, status)
+}
+
+func TestShouldFailIfGRPCNodeStatusFails(t *testing.T) {
+	_, err := NewGRPCBlockQuerier(&mockTmServiceClient{err: errors.New("failed request")}).NodeStatus(context.Background())
+	require.Equal(t, errors.New("fetching node status failed: failed request"), err)
+}
+
+func (m *mockTmServiceClient) GetSyncing(ctx context.Context, in *tmservice.GetSyncingRequest, opts ...grpc.CallOption) (*tmservice.GetSyncingResponse, error) {
+	return &tmservice.GetSyncingResponse{Syncing: m.syncing}, m.err
+}
+
+func (m *mockTmServiceClient) GetLatestBlock(ctx context.Context, in *tmservice.GetLatestBlockRequest, opts ...grpc.CallOption) (*tmservice.GetLatestBlockResponse, error) {
+	return &tmservice.GetLatestBlockResponse{Block: m.block}, m.err
+}
+
+func (m *mockTmServiceClient) GetBlockByHeight(ctx context.Context, in *tmservice.GetBlockByHeightRequest, opts ...grpc.CallOption) (*tmservice.GetBlockByHeightResponse, error) {
+	return &tmservice.GetBlockByHeightResponse{Block: m.block}, m.err
+}
+
+type mockTmServiceClient struct {
+	syncing bool
+	block   *tmproto.Block
+	err     error
+}
@@ -0,0 +1,148 @@
+package tx
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkquery "github.com/cosmos/cosmos-sdk/types/query"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"google.golang.org/grpc"
+)
+
+// The gRPC tx querier searches the transactions by the GetTxsEvent of the Cosmos SDK tx service,
+// so the relayer can run against a node exposing gRPC only.
+// The pages of a search are retried the given number of times each.
+func NewGRPCTxQuerier(client txEventsClient, pageRetries int) *grpcTxQuerier {
+	return &grpcTxQuerier{client: client, pageRetries: pageRetries}
+}
+
+// Searching the transactions matching a tx_search query page by page and mapping them to the results of tx_search.
+// Every condition of the query is passed as a separate event, the node joins them by AND. A transaction is returned once even if the results shift between the pages.
+func (gq *grpcTxQuerier) Query(ctx context.Context, query string) (*ctypes.ResultTxSearch, error) {
+	events, err := grpcEvents(query)
+	if err != nil {
+		return nil, fmt.Errorf("tx events query (%s) failed: %s", query, err)
+	}
+
+	allResults := &ctypes.ResultTxSearch{Txs: []*ctypes.ResultTx{}}
+	seen := map[string]bool{}
+	for offset := uint64(0); ; offset += uint64(itemsPerPage) {
+		var res *txtypes.GetTxsEventResponse
+		err := retryPage(ctx, gq.pageRetries, func() (err error) {
+			res, err = gq.client.GetTxsEvent(ctx, &txtypes.GetTxsEventRequest{
+				Events:     events,
+				Pagination: &sdkquery.PageRequest{Offset: offset, Limit: uint64(itemsPerPage)},
+				OrderBy:    txtypes.OrderBy_ORDER_BY_ASC,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("tx events query (%s) failed: %s", query, err)
+		}
+
+		if len(res.Txs) != len(res.TxResponses) {
+			return nil, fmt.Errorf("tx events query (%s) returned %d txs but %d responses", query, len(res.Txs), len(res.TxResponses))
+		}
+
+		for i, txResponse := range res.TxResponses {
+			if seen[txResponse.TxHash] {
+				continue
+			}
+			seen[txResponse.TxHash] = true
+
+			resultTx, err := toResultTx(res.Txs[i], txResponse)
+			if err != nil {
+				return nil, fmt.Errorf("tx events query (%s) failed: %s", query, err)
+			}
+			allResults.Txs = append(allResults.Txs, resultTx)
+		}
+
+		total := uint64(len(allResults.Txs))
+		if res.Pagination != nil {
+			total = res.Pagination.Total
+		}
+
+		if len(res.TxResponses) < itemsPerPage || uint64(len(allResults.Txs)) >= total {
+			break
+		}
+	}
+
+	allResults.TotalCount = len(allResults.Txs)
+	return allResults, nil
+}
+
+// Splitting a tx_search query into the events of GetTxsEvent. Every event must contain exactly one '=',
+// so the strict height bounds are turned into inclusive ones.
+func grpcEvents(query string) ([]string, error) {
+	events := []string{}
+	for _, condition := range strings.Split(query, " AND ") {
+		condition = strings.TrimSpace(condition)
+
+		for _, bound := range []struct {
+			operator  string
+			inclusive string
+			delta     int64
+		}{{">", ">=", 1}, {"<", "<=", -1}} {
+			prefix := txHeightKey + bound.operator
+			if !strings.HasPrefix(condition, prefix) || strings.HasPrefix(condition, prefix+"=") {
+				continue
+			}
+
+			height, err := strconv.ParseInt(strings.TrimPrefix(condition, prefix), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid height condition (%s): %s", condition, err)
+			}
+			condition = fmt.Sprintf("%s%s%d", txHeightKey, bound.inclusive, height+bound.delta)
+		}
+
+		if strings.Count(condition, "=") != 1 {
+			return nil, fmt.Errorf("unsupported condition (%s)", condition)
+		}
+		events = append(events, condition)
+	}
+
+	return events, nil
+}
+
+// Mapping a transaction of GetTxsEvent to the result of tx_search. The index of the transaction in its block is not returned by GetTxsEvent and is left empty.
+func toResultTx(tx *txtypes.Tx, txResponse *sdk.TxResponse) (*ctypes.ResultTx, error) {
+	hash, err := hex.DecodeString(txResponse.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hash of tx (%s): %s", txResponse.TxHash, err)
+	}
+
+	txBytes, err := tx.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("encoding tx (%s) failed: %s", txResponse.TxHash, err)
+	}
+
+	return &ctypes.ResultTx{
+		Hash:   hash,
+		Height: txResponse.Height,
+		TxResult: abcitypes.ResponseDeliverTx{
+			Code:      txResponse.Code,
+			Log:       txResponse.RawLog,
+			Info:      txResponse.Info,
+			GasWanted: txResponse.GasWanted,
+			GasUsed:   txResponse.GasUsed,
+			Events:    txResponse.Events,
+			Codespace: txResponse.Codespace,
+		},
+		Tx: txBytes,
+	}, nil
+}
+
+type grpcTxQuerier struct {
+	client      txEventsClient
+	pageRetries int
+}
+
+type txEventsClient interface {
+	GetTxsEvent(ctx context.Context, in *txtypes.GetTxsEventRequest, opts ...grpc.CallOption) (*txtypes.GetTxsEventResponse, error)
+}
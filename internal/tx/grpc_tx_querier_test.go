@@ -0,0 +1,107 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	encodingconfig "github.com/CudoVentures/cudos-ondemand-minting-service/internal/encoding_config"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkquery "github.com/cosmos/cosmos-sdk/types/query"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/stretchr/testify/require"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	"google.golang.org/grpc"
+)
+
+func TestShouldQueryTxsEventPageByPage(t *testing.T) {
+	defer func(perPage int) { itemsPerPage = perPage }(itemsPerPage)
+	itemsPerPage = 2
+
+	client := &mockTxEventsClient{txs: []mockEventTx{
+		{hash: "AA01", height: 1, memo: "memo1"},
+		{hash: "AA02", height: 2, memo: "memo2"},
+		{hash: "AA03", height: 3, memo: "memo3"},
+	}}
+
+	results, err := NewGRPCTxQuerier(client, 0).Query(context.Background(), "tx.height>0 AND transfer.recipient='wallet'")
+	require.NoError(t, err)
+	require.Equal(t, 3, results.TotalCount)
+	require.Len(t, results.Txs, 3)
+	require.Equal(t, []uint64{0, 2}, client.offsets)
+	require.Equal(t, []string{"tx.height>=1", "transfer.recipient='wallet'"}, client.events)
+	require.Equal(t, txtypes.OrderBy_ORDER_BY_ASC, client.orderBy)
+
+	require.Equal(t, "AA03", results.Txs[2].Hash.String())
+	require.Equal(t, int64(3), results.Txs[2].Height)
+	require.Equal(t, "transfer", results.Txs[2].TxResult.Events[0].Type)
+}
+
+func TestShouldMapTxsToDecodableTxBytes(t *testing.T) {
+	client := &mockTxEventsClient{txs: []mockEventTx{{hash: "AA01", height: 1, memo: "{\"uuid\":\"nftuid1\"}"}}}
+
+	results, err := NewGRPCTxQuerier(client, 0).Query(context.Background(), "tx.height>=1 AND marketplace_mint_nft.uid='nftuid1'")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 1)
+
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+	decodedTx, err := NewTxCoder(&encodingConfig).Decode(results.Txs[0].Tx)
+	require.NoError(t, err)
+	require.Equal(t, "{\"uuid\":\"nftuid1\"}", decodedTx.(sdk.TxWithMemo).GetMemo())
+}
+
+func TestShouldTurnStrictHeightBoundsIntoInclusiveEvents(t *testing.T) {
+	events, err := grpcEvents("tx.height>5 AND tx.height<=10 AND tx.height<20 AND tx.height>=3 AND transfer.sender='wallet'")
+	require.NoError(t, err)
+	require.Equal(t, []string{"tx.height>=6", "tx.height<=10", "tx.height<=19", "tx.height>=3", "transfer.sender='wallet'"}, events)
+}
+
+func TestShouldFailIfGRPCQueryIsNotSupported(t *testing.T) {
+	_, err := NewGRPCTxQuerier(&mockTxEventsClient{}, 0).Query(context.Background(), "tx.height>x AND transfer.sender='wallet'")
+	require.Error(t, err)
+
+	_, err = NewGRPCTxQuerier(&mockTxEventsClient{}, 0).Query(context.Background(), "transfer.sender='wallet' OR transfer.recipient='wallet'")
+	require.Error(t, err)
+}
+
+func TestShouldFailIfGetTxsEventFails(t *testing.T) {
+	_, err := NewGRPCTxQuerier(&mockTxEventsClient{err: errors.New("failed request")}, 0).Query(context.Background(), "tx.height>=1 AND transfer.sender='wallet'")
+	require.Equal(t, errors.New("tx events query (tx.height>=1 AND transfer.sender='wallet') failed: failed request"), err)
+}
+
+func (m *mockTxEventsClient) GetTxsEvent(ctx context.Context, in *txtypes.GetTxsEventRequest, opts ...grpc.CallOption) (*txtypes.GetTxsEventResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	m.events = in.Events
+	m.orderBy = in.OrderBy
+	m.offsets = append(m.offsets, in.Pagination.Offset)
+
+	res := &txtypes.GetTxsEventResponse{Pagination: &sdkquery.PageResponse{Total: uint64(len(m.txs))}}
+	for i := in.Pagination.Offset; i < in.Pagination.Offset+in.Pagination.Limit && i < uint64(len(m.txs)); i++ {
+		eventTx := m.txs[i]
+		res.Txs = append(res.Txs, &txtypes.Tx{Body: &txtypes.TxBody{Memo: eventTx.memo}, AuthInfo: &txtypes.AuthInfo{}})
+		res.TxResponses = append(res.TxResponses, &sdk.TxResponse{
+			TxHash: eventTx.hash,
+			Height: eventTx.height,
+			Events: []abcitypes.Event{transferEvent("buyer", "wallet")},
+		})
+	}
+
+	return res, nil
+}
+
+type mockTxEventsClient struct {
+	txs     []mockEventTx
+	err     error
+	events  []string
+	orderBy txtypes.OrderBy
+	offsets []uint64
+}
+
+type mockEventTx struct {
+	hash   string
+	height int64
+	memo   string
+}
@@ -0,0 +1,79 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+)
+
+// The remote signer sends the sign bytes to a signing daemon, so the private key is never held by the relayer.
+// The requests are authenticated by a bearer token and every returned signature is verified against the public key of the wallet.
+func NewRemoteSigner(url, authToken string, pubKey cryptotypes.PubKey, client *http.Client) *remoteSigner {
+	return &remoteSigner{
+		url:       url,
+		authToken: authToken,
+		pubKey:    pubKey,
+		client:    client,
+	}
+}
+
+func (rs *remoteSigner) PubKey() cryptotypes.PubKey {
+	return rs.pubKey
+}
+
+func (rs *remoteSigner) Sign(msg []byte) ([]byte, error) {
+	reqBody, err := json.Marshal(model.SignRequest{SignBytes: msg})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s%s", rs.url, SignUri), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", rs.authToken))
+
+	res, err := rs.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("signing request failed: %s", err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signing daemon refused to sign with status %d: %s", res.StatusCode, string(resBody))
+	}
+
+	signResponse := model.SignResponse{}
+	if err := json.Unmarshal(resBody, &signResponse); err != nil {
+		return nil, fmt.Errorf("unmarshaling signing response failed: %s", err)
+	}
+
+	if !rs.pubKey.VerifySignature(msg, signResponse.Signature) {
+		return nil, errors.New("signature returned by the signing daemon does not match the wallet public key")
+	}
+
+	return signResponse.Signature, nil
+}
+
+type remoteSigner struct {
+	url       string
+	authToken string
+	pubKey    cryptotypes.PubKey
+	client    *http.Client
+}
+
+// The path of the sign endpoint of the signing daemon.
+const SignUri = "/sign"
@@ -0,0 +1,68 @@
+package tx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteSignerShouldReturnVerifiedSignature(t *testing.T) {
+	privKey := secp256k1.GenPrivKey()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, SignUri, r.URL.Path)
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		signRequest := model.SignRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&signRequest))
+
+		signature, err := privKey.Sign(signRequest.SignBytes)
+		require.NoError(t, err)
+		require.NoError(t, json.NewEncoder(w).Encode(model.SignResponse{Signature: signature}))
+	}))
+	defer server.Close()
+
+	signer := NewRemoteSigner(server.URL, "token", privKey.PubKey(), server.Client())
+	require.Equal(t, privKey.PubKey(), signer.PubKey())
+
+	signature, err := signer.Sign([]byte("sign bytes"))
+	require.NoError(t, err)
+	require.True(t, privKey.PubKey().VerifySignature([]byte("sign bytes"), signature))
+}
+
+func TestRemoteSignerShouldFailIfSignatureOfOtherKey(t *testing.T) {
+	otherPrivKey := secp256k1.GenPrivKey()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signRequest := model.SignRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&signRequest))
+
+		signature, err := otherPrivKey.Sign(signRequest.SignBytes)
+		require.NoError(t, err)
+		require.NoError(t, json.NewEncoder(w).Encode(model.SignResponse{Signature: signature}))
+	}))
+	defer server.Close()
+
+	signer := NewRemoteSigner(server.URL, "token", secp256k1.GenPrivKey().PubKey(), server.Client())
+
+	_, err := signer.Sign([]byte("sign bytes"))
+	require.Error(t, err)
+}
+
+func TestRemoteSignerShouldFailIfRefused(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("message type is not allowed"))
+	}))
+	defer server.Close()
+
+	signer := NewRemoteSigner(server.URL, "token", secp256k1.GenPrivKey().PubKey(), server.Client())
+
+	_, err := signer.Sign([]byte("sign bytes"))
+	require.EqualError(t, err, "signing daemon refused to sign with status 403: message type is not allowed")
+}
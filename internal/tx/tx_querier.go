@@ -3,60 +3,83 @@ package tx
 import (
 	"context"
 	"fmt"
+	"time"
 
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 )
 
-func NewTxQuerier(node txSearcher) *txQuerier {
-	return &txQuerier{node: node}
+// The pages of a search are retried the given number of times each, so a single failed page does not fail the whole search.
+func NewTxQuerier(node txSearcher, pageRetries int) *txQuerier {
+	return &txQuerier{node: node, pageRetries: pageRetries}
 }
 
+// Searching the transactions matching the query page by page in ascending order.
+// A transaction is returned once even if the results shift between the pages, e.g. because new transactions arrived during the search.
+// The search stops at the first page that is not full, so the query should be bounded by a height range.
 func (tq *txQuerier) Query(ctx context.Context, query string) (*ctypes.ResultTxSearch, error) {
-	var allResults *ctypes.ResultTxSearch = nil
+	allResults := &ctypes.ResultTxSearch{Txs: []*ctypes.ResultTx{}}
+	seen := map[string]bool{}
 
 	for page := 1; ; page += 1 {
-		// fmt.Printf("Fetching page %d\n", page)
-		results, err := tq.node.TxSearch(ctx, query, true, &page, &itemsPerPage, "asc")
+		results, err := tq.searchPage(ctx, query, page)
 		if err != nil {
-			return nil, fmt.Errorf("tx search (%s) failed: %s", query, err)
-			// if allResults == nil {
-			// 	return nil, fmt.Errorf("tx search (%s) failed: %s", query, err)
-			// } else {
-			// 	fmt.Println(err)
-			// 	continue
-			// }
+			return nil, err
 		}
 
-		if allResults == nil {
-			allResults = results
-		} else {
-			allResults.Txs = append(allResults.Txs, results.Txs...)
-			allResults.TotalCount = results.TotalCount
+		for _, result := range results.Txs {
+			if seen[result.Hash.String()] {
+				continue
+			}
+			seen[result.Hash.String()] = true
+			allResults.Txs = append(allResults.Txs, result)
 		}
 
-		from := (page - 1) * itemsPerPage
-		to := page*itemsPerPage - 1
-		// fmt.Printf("[%d; %d] -> %d\n", from, to, allResults.TotalCount)
-
-		// there are no events so we can break it directly
-		if allResults.TotalCount == 0 {
-			break
-		}
-		// the "+1" in from and to are because from,to are 0th based, while TotalCount is 1 based
-		if from+1 <= allResults.TotalCount && allResults.TotalCount <= to+1 {
+		if len(results.Txs) < itemsPerPage || len(allResults.Txs) >= results.TotalCount {
 			break
 		}
 	}
 
+	allResults.TotalCount = len(allResults.Txs)
 	return allResults, nil
 }
 
+func (tq *txQuerier) searchPage(ctx context.Context, query string, page int) (*ctypes.ResultTxSearch, error) {
+	var results *ctypes.ResultTxSearch
+	err := retryPage(ctx, tq.pageRetries, func() (err error) {
+		results, err = tq.node.TxSearch(ctx, query, true, &page, &itemsPerPage, "asc")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("tx search (%s) failed: %s", query, err)
+	}
+
+	return results, nil
+}
+
+// Fetching a page, retrying it the given number of times if it fails.
+func retryPage(ctx context.Context, retries int, fetch func() error) error {
+	for retry := 0; ; retry++ {
+		err := fetch()
+		if err == nil || retry >= retries || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(pageRetryInterval):
+		case <-ctx.Done():
+		}
+	}
+}
+
 // const txSearchTimeout = 10 * time.Second
 
 var itemsPerPage = 100
 
+var pageRetryInterval = time.Second
+
 type txQuerier struct {
-	node txSearcher
+	node        txSearcher
+	pageRetries int
 }
 
 type txSearcher interface {
@@ -4,22 +4,97 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
 )
 
 func TestShouldFailIfNodeTxSearchFails(t *testing.T) {
-	txQuerier := NewTxQuerier(&mockTxSearcher{})
+	txQuerier := NewTxQuerier(&mockTxSearcher{failures: -1}, 0)
 	_, err := txQuerier.Query(context.Background(), "some query")
 	require.Equal(t, errors.New("tx search (some query) failed: failed tx search request"), err)
 }
 
+func TestShouldSearchAllPages(t *testing.T) {
+	defer func(perPage int) { itemsPerPage = perPage }(itemsPerPage)
+	itemsPerPage = 2
+
+	txSearcher := &mockTxSearcher{txs: []tmtypes.Tx{tmtypes.Tx("tx1"), tmtypes.Tx("tx2"), tmtypes.Tx("tx3"), tmtypes.Tx("tx4")}}
+	results, err := NewTxQuerier(txSearcher, 0).Query(context.Background(), "some query")
+	require.NoError(t, err)
+	require.Equal(t, 4, results.TotalCount)
+	require.Len(t, results.Txs, 4)
+	require.Equal(t, []int{1, 2}, txSearcher.pages)
+}
+
+func TestShouldReturnShiftedTxsOnce(t *testing.T) {
+	defer func(perPage int) { itemsPerPage = perPage }(itemsPerPage)
+	itemsPerPage = 2
+
+	// A transaction arriving before the second page shifts tx2 into it
+	txSearcher := &mockTxSearcher{
+		txs:         []tmtypes.Tx{tmtypes.Tx("tx1"), tmtypes.Tx("tx2"), tmtypes.Tx("tx3")},
+		insertAfter: 1,
+		insertTx:    tmtypes.Tx("tx0"),
+	}
+	results, err := NewTxQuerier(txSearcher, 0).Query(context.Background(), "some query")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 3)
+	require.Equal(t, tmtypes.Tx("tx1"), results.Txs[0].Tx)
+	require.Equal(t, tmtypes.Tx("tx2"), results.Txs[1].Tx)
+	require.Equal(t, tmtypes.Tx("tx3"), results.Txs[2].Tx)
+}
+
+func TestShouldRetryFailedPage(t *testing.T) {
+	defer func(interval time.Duration) { pageRetryInterval = interval }(pageRetryInterval)
+	pageRetryInterval = time.Millisecond
+
+	txSearcher := &mockTxSearcher{txs: []tmtypes.Tx{tmtypes.Tx("tx1")}, failures: 2}
+	results, err := NewTxQuerier(txSearcher, 2).Query(context.Background(), "some query")
+	require.NoError(t, err)
+	require.Len(t, results.Txs, 1)
+	require.Equal(t, []int{1, 1, 1}, txSearcher.pages)
+}
+
+func TestShouldFailIfPageFailsAfterRetries(t *testing.T) {
+	defer func(interval time.Duration) { pageRetryInterval = interval }(pageRetryInterval)
+	pageRetryInterval = time.Millisecond
+
+	txSearcher := &mockTxSearcher{txs: []tmtypes.Tx{tmtypes.Tx("tx1")}, failures: 3}
+	_, err := NewTxQuerier(txSearcher, 2).Query(context.Background(), "some query")
+	require.Equal(t, errors.New("tx search (some query) failed: failed tx search request"), err)
+	require.Len(t, txSearcher.pages, 3)
+}
+
+// Serving the transactions in pages. The given number of requests fail first, all of them if negative.
+// If set, the insert transaction is put at the front of the results after the given number of pages.
 func (mts *mockTxSearcher) TxSearch(ctx context.Context, query string, prove bool, page, perPage *int, orderBy string) (*ctypes.ResultTxSearch, error) {
-	return nil, failedTxSearchRequest
+	mts.pages = append(mts.pages, *page)
+	if mts.failures < 0 || len(mts.pages) <= mts.failures {
+		return nil, failedTxSearchRequest
+	}
+
+	txs := mts.txs
+	if mts.insertTx != nil && *page > mts.insertAfter {
+		txs = append([]tmtypes.Tx{mts.insertTx}, txs...)
+	}
+
+	results := &ctypes.ResultTxSearch{Txs: []*ctypes.ResultTx{}, TotalCount: len(txs)}
+	for i := (*page - 1) * *perPage; i < *page**perPage && i < len(txs); i++ {
+		results.Txs = append(results.Txs, &ctypes.ResultTx{Hash: txs[i].Hash(), Tx: txs[i]})
+	}
+
+	return results, nil
 }
 
 type mockTxSearcher struct {
+	txs         []tmtypes.Tx
+	failures    int
+	insertAfter int
+	insertTx    tmtypes.Tx
+	pages       []int
 }
 
 var failedTxSearchRequest = errors.New("failed tx search request")
@@ -8,7 +8,7 @@ import (
 
 	"github.com/CudoVentures/cudos-ondemand-minting-service/internal/model"
 	client "github.com/cosmos/cosmos-sdk/client"
-	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	"github.com/cosmos/cosmos-sdk/simapp/params"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
@@ -19,15 +19,17 @@ import (
 	"google.golang.org/grpc"
 )
 
+// The fee granter is optional. If set it pays the fees of all transactions instead of the signing wallet.
 func NewTxSender(txClient txClient, accInfoClient accountInfoClient, encodingConfig *params.EncodingConfig,
-	privKey *secp256k1.PrivKey, chainID, paymentDenom string, gasPrice uint64, gasAdjustment float64, signer signer) *txSender {
+	pubKey cryptotypes.PubKey, chainID, paymentDenom string, feeGranter sdk.AccAddress, gasPrice uint64, gasAdjustment float64, signer signer) *txSender {
 	return &txSender{
 		txClient:       txClient,
 		accInfoClient:  accInfoClient,
 		encodingConfig: encodingConfig,
-		privKey:        privKey,
+		pubKey:         pubKey,
 		chainID:        chainID,
 		paymentDenom:   paymentDenom,
+		feeGranter:     feeGranter,
 		gasPrice:       gasPrice,
 		gasAdjustment:  gasAdjustment,
 		signer:         signer,
@@ -85,7 +87,7 @@ func (ts *txSender) EstimateGas(ctx context.Context, msgs []sdk.Msg, memo string
 }
 
 func (ts *txSender) buildTx(ctx context.Context, msgs []sdk.Msg, memo string, gasResult model.GasResult) ([]byte, error) {
-	accAddr := sdk.AccAddress(ts.privKey.PubKey().Address())
+	accAddr := sdk.AccAddress(ts.pubKey.Address())
 
 	accInfo, err := ts.accInfoClient.QueryInfo(ctx, accAddr.String())
 	if err != nil {
@@ -108,7 +110,7 @@ func (ts *txSender) genTx(msgs []sdk.Msg, memo string, feeAmt sdk.Coins, gas, ac
 	// signer infos.
 	sigs := []signing.SignatureV2{
 		{
-			PubKey: ts.privKey.PubKey(),
+			PubKey: ts.pubKey,
 			Data: &signing.SingleSignatureData{
 				SignMode: signMode,
 			},
@@ -127,6 +129,10 @@ func (ts *txSender) genTx(msgs []sdk.Msg, memo string, feeAmt sdk.Coins, gas, ac
 	tx.SetMemo(memo)
 	tx.SetFeeAmount(feeAmt)
 	tx.SetGasLimit(gas)
+	// The granter is part of the signed auth info, so it is set for the simulation as well and the gas is estimated with the allowance in use
+	if ts.feeGranter != nil {
+		tx.SetFeeGranter(ts.feeGranter)
+	}
 
 	// 2nd round: once all signer infos are set, every signer can sign.
 	signerData := authsign.SignerData{
@@ -175,9 +181,10 @@ type txSender struct {
 	txClient       txClient
 	accInfoClient  accountInfoClient
 	encodingConfig *params.EncodingConfig
-	privKey        *secp256k1.PrivKey
+	pubKey         cryptotypes.PubKey
 	chainID        string
 	paymentDenom   string
+	feeGranter     sdk.AccAddress
 	gasPrice       uint64
 	gasAdjustment  float64
 	signer         signer
@@ -35,7 +35,7 @@ func TestShouldFailSendTxIfBroadcastFails(t *testing.T) {
 	require.NoError(t, err)
 	encodingConfig := encodingconfig.MakeEncodingConfig()
 
-	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, NewTxSigner(&encodingConfig, privKey))
+	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, NewTxSigner(&encodingConfig, privKey))
 
 	_, err = txSender.SendTx(context.Background(), []types.Msg{}, "", model.GasResult{})
 	require.Equal(t, broadcastFailed, err)
@@ -52,7 +52,7 @@ func TestShouldFailSendTxIfBrodcastReturnsNilTxResponse(t *testing.T) {
 	require.NoError(t, err)
 	encodingConfig := encodingconfig.MakeEncodingConfig()
 
-	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, NewTxSigner(&encodingConfig, privKey))
+	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, NewTxSigner(&encodingConfig, privKey))
 
 	_, err = txSender.SendTx(context.Background(), []types.Msg{}, "", model.GasResult{})
 	require.Equal(t, errors.New("broadcasting of tx failed: "), err)
@@ -72,7 +72,7 @@ func TestShouldFailSendTxIfBrodcastReturnsTxResponseWithNonZeroCode(t *testing.T
 	require.NoError(t, err)
 	encodingConfig := encodingconfig.MakeEncodingConfig()
 
-	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, NewTxSigner(&encodingConfig, privKey))
+	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, NewTxSigner(&encodingConfig, privKey))
 
 	_, err = txSender.SendTx(context.Background(), []types.Msg{}, "", model.GasResult{})
 	require.Equal(t, fmt.Errorf("broadcasting of tx failed: %+v", &response), err)
@@ -87,7 +87,7 @@ func TestShouldFailSendTxIfBuildingTxFails(t *testing.T) {
 	require.NoError(t, err)
 	encodingConfig := encodingconfig.MakeEncodingConfig()
 
-	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, NewTxSigner(&encodingConfig, privKey))
+	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, NewTxSigner(&encodingConfig, privKey))
 
 	_, err = txSender.SendTx(context.Background(), []types.Msg{}, "", model.GasResult{})
 	require.Equal(t, failedQueryInfo, err)
@@ -105,7 +105,7 @@ func TestShouldFailEstimateGasIfSimulateFails(t *testing.T) {
 	require.NoError(t, err)
 	encodingConfig := encodingconfig.MakeEncodingConfig()
 
-	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, NewTxSigner(&encodingConfig, privKey))
+	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, NewTxSigner(&encodingConfig, privKey))
 
 	_, err = txSender.EstimateGas(context.Background(), []types.Msg{}, "")
 	require.Equal(t, failedSimulate, err)
@@ -122,7 +122,7 @@ func TestShouldFailEstimateGasIfSimulateReturnsNilGasInfo(t *testing.T) {
 	require.NoError(t, err)
 	encodingConfig := encodingconfig.MakeEncodingConfig()
 
-	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, NewTxSigner(&encodingConfig, privKey))
+	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, NewTxSigner(&encodingConfig, privKey))
 
 	_, err = txSender.EstimateGas(context.Background(), []types.Msg{}, "")
 	require.Equal(t, errors.New("simulation result with no gas info"), err)
@@ -137,7 +137,7 @@ func TestShouldFailEstimateGasIfBuildingTxFail(t *testing.T) {
 	require.NoError(t, err)
 	encodingConfig := encodingconfig.MakeEncodingConfig()
 
-	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, NewTxSigner(&encodingConfig, privKey))
+	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, NewTxSigner(&encodingConfig, privKey))
 
 	_, err = txSender.EstimateGas(context.Background(), []types.Msg{}, "")
 	require.Equal(t, failedQueryInfo, err)
@@ -155,7 +155,7 @@ func TestShouldFailBuildTxIfGenTxFails(t *testing.T) {
 	mTxSigner := mockTxSigner{}
 	mTxSigner.On("SetMsgs", mock.Anything, mock.Anything).Return(failedSetMsgs)
 
-	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, &mTxSigner)
+	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, &mTxSigner)
 
 	_, err = txSender.buildTx(context.Background(), []types.Msg{}, "", model.GasResult{})
 	require.Equal(t, failedSetMsgs, err)
@@ -174,7 +174,7 @@ func TestShouldGenTxShouldFailIfSetSignaturesFails(t *testing.T) {
 	mTxSigner.On("SetMsgs", mock.Anything, mock.Anything).Return(nil)
 	mTxSigner.On("SetSignatures", mock.Anything, mock.Anything).Return(failedSetSignatures)
 
-	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, &mTxSigner)
+	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, &mTxSigner)
 
 	addr, err := sdk.AccAddressFromBech32("cosmos1a326k254fukx9jlp0h3fwcr2ymjgludza2npne")
 	require.NoError(t, err)
@@ -199,7 +199,7 @@ func TestShouldGenTxShouldFailIfGetSignBytesFails(t *testing.T) {
 	mTxSigner.On("SetSignatures", mock.Anything, mock.Anything).Return(nil)
 	mTxSigner.On("GetSignBytes", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, failedGetSignBytes)
 
-	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, &mTxSigner)
+	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, &mTxSigner)
 
 	addr, err := sdk.AccAddressFromBech32("cosmos1a326k254fukx9jlp0h3fwcr2ymjgludza2npne")
 	require.NoError(t, err)
@@ -225,7 +225,7 @@ func TestShouldGenTxShouldFailIfSignFails(t *testing.T) {
 	mTxSigner.On("GetSignBytes", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
 	mTxSigner.On("Sign", mock.Anything).Return([]byte{}, failedSign)
 
-	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, &mTxSigner)
+	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, &mTxSigner)
 
 	addr, err := sdk.AccAddressFromBech32("cosmos1a326k254fukx9jlp0h3fwcr2ymjgludza2npne")
 	require.NoError(t, err)
@@ -252,7 +252,7 @@ func TestShouldGenTxShouldFailIfSecondSetSignaturesFails(t *testing.T) {
 	mTxSigner.On("GetSignBytes", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
 	mTxSigner.On("Sign", mock.Anything).Return([]byte{}, nil)
 
-	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey, "cudos-local-network", "acudos", 1, 1.3, &mTxSigner)
+	txSender := NewTxSender(&mockTxClient{}, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", nil, 1, 1.3, &mTxSigner)
 
 	addr, err := sdk.AccAddressFromBech32("cosmos1a326k254fukx9jlp0h3fwcr2ymjgludza2npne")
 	require.NoError(t, err)
@@ -263,6 +263,36 @@ func TestShouldGenTxShouldFailIfSecondSetSignaturesFails(t *testing.T) {
 	require.Equal(t, failedSetSignatures, err)
 }
 
+func TestShouldSetFeeGranterInSimulatedTx(t *testing.T) {
+	privKey, err := key.PrivKeyFromMnemonic(walletMnemonic)
+	require.NoError(t, err)
+	encodingConfig := encodingconfig.MakeEncodingConfig()
+
+	granter, err := sdk.AccAddressFromBech32("cosmos1a326k254fukx9jlp0h3fwcr2ymjgludza2npne")
+	require.NoError(t, err)
+
+	mTxClient := mockTxClient{}
+	mTxClient.On("Simulate", mock.Anything, mock.MatchedBy(func(req *txtypes.SimulateRequest) bool {
+		decodedTx, err := encodingConfig.TxConfig.TxDecoder()(req.TxBytes)
+		if err != nil {
+			return false
+		}
+
+		feeTx, ok := decodedTx.(sdk.FeeTx)
+		return ok && feeTx.FeeGranter().Equals(granter)
+	}), mock.Anything).Return(&txtypes.SimulateResponse{GasInfo: &sdk.GasInfo{GasUsed: 100}}, nil)
+
+	mAccInfoClient := mockAccountInfoClient{}
+	mAccInfoClient.On("QueryInfo", mock.Anything, mock.Anything).Return(model.AccountInfo{}, nil)
+
+	txSender := NewTxSender(&mTxClient, &mAccInfoClient, &encodingConfig, privKey.PubKey(), "cudos-local-network", "acudos", granter, 1, 1.3, NewTxSigner(&encodingConfig, privKey))
+
+	bankSendMsg := banktypes.NewMsgSend(granter, granter, sdk.NewCoins(sdk.NewCoin("acudos", sdk.NewIntFromUint64(8000000000000000000))))
+	gasResult, err := txSender.EstimateGas(context.Background(), []types.Msg{bankSendMsg}, "")
+	require.NoError(t, err)
+	require.Equal(t, uint64(130), gasResult.GasLimit)
+}
+
 type mockTxSigner struct {
 	mock.Mock
 }
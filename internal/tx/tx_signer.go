@@ -2,7 +2,6 @@ package tx
 
 import (
 	client "github.com/cosmos/cosmos-sdk/client"
-	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
 	"github.com/cosmos/cosmos-sdk/simapp/params"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/types/tx/signing"
@@ -10,10 +9,11 @@ import (
 	auth "github.com/cosmos/cosmos-sdk/x/auth/signing"
 )
 
-func NewTxSigner(encodingConfig *params.EncodingConfig, privKey *secp256k1.PrivKey) *txSigner {
+// The key can be held in memory or by a remote signing daemon, see NewRemoteSigner.
+func NewTxSigner(encodingConfig *params.EncodingConfig, key keySigner) *txSigner {
 	return &txSigner{
 		encodingConfig: encodingConfig,
-		privKey:        privKey,
+		key:            key,
 	}
 }
 
@@ -30,10 +30,14 @@ func (ts *txSigner) GetSignBytes(mode signing.SignMode, data auth.SignerData, tx
 }
 
 func (ts *txSigner) Sign(msg []byte) ([]byte, error) {
-	return ts.privKey.Sign(msg)
+	return ts.key.Sign(msg)
+}
+
+type keySigner interface {
+	Sign(msg []byte) ([]byte, error)
 }
 
 type txSigner struct {
 	encodingConfig *params.EncodingConfig
-	privKey        *secp256k1.PrivKey
+	key            keySigner
 }